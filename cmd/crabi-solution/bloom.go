@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/alamrios/crabi-solution/internal/infra/breached"
+)
+
+// buildBloomFilterCommand subcommand name that builds the breached
+// passwords bloom filter from a wordlist
+const buildBloomFilterCommand = "build-bloom-filter"
+
+// buildBloomFilter reads a plain wordlist, one password per line, and
+// writes the bloom filter loaded with PASSWORD_BREACHED_BLOOM_FILE
+func buildBloomFilter(args []string) error {
+	flags := flag.NewFlagSet(buildBloomFilterCommand, flag.ContinueOnError)
+	input := flags.String("wordlist", "", "plain wordlist, one password per line")
+	output := flags.String("out", "", "bloom filter file to write")
+	falsePositiveRate := flags.Float64("fp-rate", 0.001, "false positive rate")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if *input == "" || *output == "" {
+		return fmt.Errorf("-wordlist and -out flags are required")
+	}
+
+	wordlist, err := os.Open(*input)
+	if err != nil {
+		return err
+	}
+	defer wordlist.Close()
+
+	passwords, err := countPasswords(wordlist)
+	if err != nil {
+		return err
+	}
+
+	filter, err := breached.NewBloomFilter(passwords, *falsePositiveRate)
+	if err != nil {
+		return err
+	}
+
+	_, err = wordlist.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	err = eachPassword(wordlist, filter.Add)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(file)
+	_, err = filter.WriteTo(writer)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+
+	log.Printf("wrote bloom filter with %d passwords to %s", passwords, *output)
+
+	return nil
+}
+
+// countPasswords returns the number of passwords in given wordlist
+func countPasswords(r io.Reader) (int, error) {
+	count := 0
+	err := eachPassword(r, func(string) {
+		count++
+	})
+
+	return count, err
+}
+
+// eachPassword calls fn with every non empty line of given wordlist
+func eachPassword(r io.Reader, fn func(password string)) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		password := strings.TrimRight(scanner.Text(), "\r")
+		if password != "" {
+			fn(password)
+		}
+	}
+
+	return scanner.Err()
+}
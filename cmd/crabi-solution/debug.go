@@ -0,0 +1,19 @@
+package main
+
+import (
+	"expvar"
+	"log"
+	"net/http"
+)
+
+// serveDebug serves runtime metrics at /debug/vars on given address, kept
+// apart from the public API since expvar also exposes the command line and
+// memory stats
+func serveDebug(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+
+	log.Printf("Starting debug server on %s...", addr)
+	err := http.ListenAndServe(addr, mux)
+	log.Printf("debug server stopped: %v", err)
+}
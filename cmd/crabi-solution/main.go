@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"expvar"
 	"fmt"
 	"log"
 	"net/http"
@@ -10,15 +11,29 @@ import (
 	"github.com/gorilla/mux"
 
 	"github.com/alamrios/crabi-solution/config"
+	"github.com/alamrios/crabi-solution/internal/app/auth"
+	pldApp "github.com/alamrios/crabi-solution/internal/app/pld"
 	"github.com/alamrios/crabi-solution/internal/app/user"
+	"github.com/alamrios/crabi-solution/internal/infra/breached"
 	chttp "github.com/alamrios/crabi-solution/internal/infra/http"
 	"github.com/alamrios/crabi-solution/internal/infra/http/pld"
 	userRouter "github.com/alamrios/crabi-solution/internal/infra/http/users"
+	"github.com/alamrios/crabi-solution/internal/infra/notifier"
 	"github.com/alamrios/crabi-solution/internal/infra/repository/mongo"
+	authRepo "github.com/alamrios/crabi-solution/internal/infra/repository/mongo/auth"
+	pldRepo "github.com/alamrios/crabi-solution/internal/infra/repository/mongo/pld"
 	userRepo "github.com/alamrios/crabi-solution/internal/infra/repository/mongo/user"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == buildBloomFilterCommand {
+		err := buildBloomFilter(os.Args[2:])
+		if err != nil {
+			log.Fatalf("failed to build bloom filter: %v", err)
+		}
+		return
+	}
+
 	ctx := context.Background()
 
 	cfg, err := config.New(ctx)
@@ -32,27 +47,277 @@ func main() {
 		log.Fatalf("failed to setup mongoDB client: %v", err)
 	}
 
-	httpClient, err := chttp.NewClient()
+	httpClient, err := chttp.NewClient(&cfg.PLD.Client)
 	if err != nil {
 		log.Fatalf("failed to setup http client: %v", err)
 	}
 
-	pldService, err := pld.NewService(&cfg.PLD, httpClient)
+	screeningRepo, err := pldRepo.NewScreeningRepository(mongoDB)
+	if err != nil {
+		log.Fatalf("failed to setup pld screening repo: %v", err)
+	}
+
+	err = screeningRepo.EnsureIndexes(ctx)
+	if err != nil {
+		log.Fatalf("failed to create pld screening indexes: %v", err)
+	}
+
+	screeningService, err := pldApp.NewAuditService(screeningRepo)
+	if err != nil {
+		log.Fatalf("failed to setup pld screening service: %v", err)
+	}
+
+	pldClient, err := pld.NewService(&cfg.PLD, httpClient, screeningRepo)
 	if err != nil {
 		log.Fatalf("failed to setup pld client: %v", err)
 	}
 
-	userRepo, err := userRepo.New(mongoDB)
+	pldService, err := pldApp.NewBreaker(pldClient, pldApp.BreakerPolicy{
+		WindowSize:       cfg.PLD.Breaker.WindowSize,
+		MinRequests:      cfg.PLD.Breaker.MinRequests,
+		FailureThreshold: cfg.PLD.Breaker.FailureThreshold,
+		OpenDuration:     cfg.PLD.Breaker.OpenDuration,
+		HalfOpenRequests: cfg.PLD.Breaker.HalfOpenRequests,
+		OpenPolicy:       pldApp.OpenPolicy(cfg.PLD.Breaker.OpenPolicy),
+	})
+	if err != nil {
+		log.Fatalf("failed to setup pld circuit breaker: %v", err)
+	}
+	expvar.Publish("pld_circuit_breaker", expvar.Func(func() interface{} {
+		return pldService.Stats()
+	}))
+
+	userRepository, err := userRepo.New(mongoDB)
 	if err != nil {
 		log.Fatalf("failed to setup user repo: %v", err)
 	}
 
-	userService, err := user.NewService(pldService, userRepo)
+	// users are migrated before serving requests and before the unique email
+	// index exists, since ids are migrated by copying documents. Ids go last,
+	// the other migrations read the object id of legacy users
+	migrated, err := userRepository.MigrateStatus(ctx)
+	if err != nil {
+		log.Fatalf("failed to migrate users status: %v", err)
+	}
+	if migrated > 0 {
+		log.Printf("migrated %d users to active status", migrated)
+	}
+
+	migrated, err = userRepository.MigrateRole(ctx)
+	if err != nil {
+		log.Fatalf("failed to migrate users role: %v", err)
+	}
+	if migrated > 0 {
+		log.Printf("migrated %d users to customer role", migrated)
+	}
+
+	migrated, err = userRepository.MigrateCreatedAt(ctx)
+	if err != nil {
+		log.Fatalf("failed to migrate users creation date: %v", err)
+	}
+	if migrated > 0 {
+		log.Printf("migrated %d users creation date", migrated)
+	}
+
+	migrated, err = userRepository.MigrateEmailDomain(ctx)
+	if err != nil {
+		log.Fatalf("failed to migrate users email domain: %v", err)
+	}
+	if migrated > 0 {
+		log.Printf("migrated %d users email domain", migrated)
+	}
+
+	migrated, err = userRepository.MigrateIDs(ctx)
+	if err != nil {
+		log.Fatalf("failed to migrate users id: %v", err)
+	}
+	if migrated > 0 {
+		log.Printf("migrated %d users to opaque ids", migrated)
+	}
+
+	err = userRepository.EnsureIndexes(ctx)
+	if err != nil {
+		log.Fatalf("failed to create user indexes: %v", err)
+	}
+
+	passwordHasher, err := newPasswordHasher(&cfg.Password)
+	if err != nil {
+		log.Fatalf("failed to setup password hasher: %v", err)
+	}
+
+	lockoutPolicy := user.LockoutPolicy{
+		MaxAttempts: cfg.Lockout.MaxAttempts,
+		Window:      cfg.Lockout.Window,
+		Duration:    cfg.Lockout.Duration,
+		BaseDelay:   cfg.Lockout.BaseDelay,
+		MaxDelay:    cfg.Lockout.MaxDelay,
+	}
+
+	passwordPolicy := user.PasswordPolicy{
+		MinLength:          cfg.Password.Policy.MinLength,
+		MaxLength:          cfg.Password.Policy.MaxLength,
+		RequireUppercase:   cfg.Password.Policy.RequireUppercase,
+		RequireLowercase:   cfg.Password.Policy.RequireLowercase,
+		RequireDigit:       cfg.Password.Policy.RequireDigit,
+		RequireSymbol:      cfg.Password.Policy.RequireSymbol,
+		RejectPersonalInfo: cfg.Password.Policy.RejectPersonalInfo,
+		HistorySize:        cfg.Password.Policy.HistorySize,
+	}
+
+	passwordPolicy.Breached, err = newBreachedPasswords(&cfg.Password.Policy)
+	if err != nil {
+		log.Fatalf("failed to load breached passwords: %v", err)
+	}
+
+	accountNotifier, err := newNotifier(&cfg.Notifier)
+	if err != nil {
+		log.Fatalf("failed to setup notifier: %v", err)
+	}
+
+	emailVerificationRepo, err := userRepo.NewEmailVerificationRepository(mongoDB)
+	if err != nil {
+		log.Fatalf("failed to setup email verification repo: %v", err)
+	}
+
+	err = emailVerificationRepo.EnsureIndexes(ctx)
+	if err != nil {
+		log.Fatalf("failed to create email verification indexes: %v", err)
+	}
+
+	emailVerificationService, err := user.NewEmailVerificationService(
+		userRepository,
+		emailVerificationRepo,
+		accountNotifier,
+		cfg.EmailVerification.TokenTTL,
+	)
+	if err != nil {
+		log.Fatalf("failed to setup email verification service: %v", err)
+	}
+
+	refreshTokenRepo, err := authRepo.NewRefreshTokenRepository(mongoDB)
+	if err != nil {
+		log.Fatalf("failed to setup refresh token repo: %v", err)
+	}
+
+	err = refreshTokenRepo.EnsureIndexes(ctx)
+	if err != nil {
+		log.Fatalf("failed to create refresh token indexes: %v", err)
+	}
+
+	revokedTokenRepo, err := authRepo.NewRevokedTokenRepository(mongoDB)
+	if err != nil {
+		log.Fatalf("failed to setup revoked token repo: %v", err)
+	}
+
+	err = revokedTokenRepo.EnsureIndexes(ctx)
+	if err != nil {
+		log.Fatalf("failed to create revoked token indexes: %v", err)
+	}
+
+	sessionRepo, err := authRepo.NewSessionRepository(mongoDB)
+	if err != nil {
+		log.Fatalf("failed to setup session repo: %v", err)
+	}
+
+	err = sessionRepo.EnsureIndexes(ctx)
+	if err != nil {
+		log.Fatalf("failed to create session indexes: %v", err)
+	}
+
+	authService, err := auth.NewService(refreshTokenRepo, revokedTokenRepo, sessionRepo, cfg.JWT.RefreshTokenTTL)
+	if err != nil {
+		log.Fatalf("failed to setup auth service: %v", err)
+	}
+
+	userService, err := user.NewService(
+		pldService,
+		userRepository,
+		passwordHasher,
+		lockoutPolicy,
+		passwordPolicy,
+		emailVerificationService,
+		authService,
+	)
 	if err != nil {
 		log.Fatalf("failed to setup user service: %v", err)
 	}
 
-	usersRouter, err := userRouter.New(userService, &cfg.JWT)
+	for _, email := range cfg.RBAC.AdminEmails {
+		err = userService.AssignRole(ctx, email, user.RoleAdmin)
+		if err != nil {
+			log.Printf("failed to assign admin role to %s: %v", email, err)
+		}
+	}
+
+	if cfg.UserDeletion.PurgeInterval > 0 {
+		go purgeDeletedUsers(ctx, userService, cfg.UserDeletion.PurgeInterval, cfg.UserDeletion.Retention)
+	}
+
+	if cfg.PLD.ScreeningInterval > 0 {
+		go screenPendingUsers(ctx, userService, cfg.PLD.ScreeningInterval)
+	}
+
+	mfaService, err := user.NewMFAService(userRepository, lockoutPolicy, cfg.MFA.Issuer)
+	if err != nil {
+		log.Fatalf("failed to setup mfa service: %v", err)
+	}
+
+	passwordResetRepo, err := userRepo.NewPasswordResetRepository(mongoDB)
+	if err != nil {
+		log.Fatalf("failed to setup password reset repo: %v", err)
+	}
+
+	err = passwordResetRepo.EnsureIndexes(ctx)
+	if err != nil {
+		log.Fatalf("failed to create password reset indexes: %v", err)
+	}
+
+	passwordResetService, err := user.NewPasswordResetService(
+		userService,
+		passwordResetRepo,
+		accountNotifier,
+		cfg.Password.ResetTokenTTL,
+	)
+	if err != nil {
+		log.Fatalf("failed to setup password reset service: %v", err)
+	}
+
+	apiKeyRepo, err := authRepo.NewAPIKeyRepository(mongoDB)
+	if err != nil {
+		log.Fatalf("failed to setup api key repo: %v", err)
+	}
+
+	err = apiKeyRepo.EnsureIndexes(ctx)
+	if err != nil {
+		log.Fatalf("failed to create api key indexes: %v", err)
+	}
+
+	apiKeyService, err := auth.NewAPIKeyService(apiKeyRepo)
+	if err != nil {
+		log.Fatalf("failed to setup api key service: %v", err)
+	}
+
+	clientRepo, err := authRepo.NewClientRepository(mongoDB)
+	if err != nil {
+		log.Fatalf("failed to setup oauth client repo: %v", err)
+	}
+
+	clientService, err := auth.NewClientService(clientRepo)
+	if err != nil {
+		log.Fatalf("failed to setup oauth client service: %v", err)
+	}
+
+	usersRouter, err := userRouter.New(
+		userService,
+		authService,
+		passwordResetService,
+		emailVerificationService,
+		mfaService,
+		apiKeyService,
+		clientService,
+		screeningService,
+		&cfg.JWT,
+	)
 	if err != nil {
 		log.Fatalf("failed to setup user router: %v", err)
 	}
@@ -60,7 +325,63 @@ func main() {
 	router := mux.NewRouter()
 	usersRouter.AppendRoutes(router)
 
+	go serveDebug(cfg.Debug.Addr)
+
 	log.Print("Starting crabi-solution server...")
 	err = http.ListenAndServe(":8080", router)
 	log.Fatal(err)
 }
+
+// newPasswordHasher returns configured password hasher, able to verify
+// hashes from every supported algorithm so they can be upgraded on login
+func newPasswordHasher(cfg *config.Password) (user.PasswordHasher, error) {
+	argon2id, err := user.NewArgon2idHasher(user.Argon2idParams{
+		Memory:      uint32(cfg.Argon2Memory),
+		Iterations:  uint32(cfg.Argon2Iterations),
+		Parallelism: uint8(cfg.Argon2Parallelism),
+		SaltLength:  user.DefaultArgon2idParams.SaltLength,
+		KeyLength:   user.DefaultArgon2idParams.KeyLength,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	bcrypt, err := user.NewBcryptHasher(cfg.BcryptCost)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Hasher == "bcrypt" {
+		return user.NewCompositeHasher(bcrypt, argon2id, user.LegacySHA256Hasher{})
+	}
+
+	return user.NewCompositeHasher(argon2id, bcrypt, user.LegacySHA256Hasher{})
+}
+
+// newNotifier returns configured account messages notifier, the log notifier
+// is only allowed in dev mode since it writes tokens to the log
+func newNotifier(cfg *config.Notifier) (user.Notifier, error) {
+	if cfg.Type != "log" {
+		return notifier.NewSMTPNotifier(cfg)
+	}
+
+	if !cfg.DevMode {
+		return nil, fmt.Errorf("log notifier needs dev mode enabled")
+	}
+
+	log.Printf("log notifier enabled, account links will be written to the log")
+	return notifier.NewLogNotifier(cfg.PasswordResetURL, cfg.EmailVerificationURL)
+}
+
+// newBreachedPasswords returns configured list of compromised passwords,
+// nil when screening is disabled
+func newBreachedPasswords(cfg *config.PasswordPolicy) (user.BreachedPasswords, error) {
+	switch {
+	case cfg.BreachedBloomFile != "":
+		return breached.LoadBloomFilter(cfg.BreachedBloomFile)
+	case cfg.BreachedPrefixDir != "":
+		return breached.NewPrefixDirectory(cfg.BreachedPrefixDir)
+	default:
+		return nil, nil
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/alamrios/crabi-solution/internal/app/user"
+)
+
+// purgeDeletedUsers permanently removes users soft deleted longer than given
+// retention on startup and then every interval, until ctx is done
+func purgeDeletedUsers(ctx context.Context, userService *user.Service, interval, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := userService.PurgeDeletedUsers(ctx, retention)
+		if err != nil {
+			log.Printf("failed to purge deleted users: %v", err)
+		} else if purged > 0 {
+			log.Printf("purged %d deleted users", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/alamrios/crabi-solution/internal/app/user"
+)
+
+// screenPendingUsers screens users registered while PLD service was
+// unavailable on startup and then every interval, until ctx is done
+func screenPendingUsers(ctx context.Context, userService *user.Service, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		screened, err := userService.ScreenPendingUsers(ctx)
+		if err != nil {
+			log.Printf("failed to screen pending users: %v", err)
+		}
+		if screened > 0 {
+			log.Printf("screened %d pending users", screened)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
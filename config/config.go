@@ -4,13 +4,24 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Config struct for crabi-solution
 type Config struct {
-	Mongo Mongo
-	PLD   PLD
-	JWT   JWT
+	Mongo             Mongo
+	PLD               PLD
+	JWT               JWT
+	Password          Password
+	Lockout           Lockout
+	Notifier          Notifier
+	EmailVerification EmailVerification
+	MFA               MFA
+	RBAC              RBAC
+	UserDeletion      UserDeletion
+	Debug             Debug
 }
 
 // Mongo struct for mongodb connection
@@ -28,11 +39,164 @@ type PLD struct {
 	Host     string
 	Port     string
 	URI      string
+	// Provider name of the PLD provider recorded with every screening
+	Provider string
+	Client   HTTPClient
+	Retry    Retry
+	Breaker  Breaker
+	// ScreeningInterval time between checks of users registered while PLD
+	// service was unavailable, zero disables them
+	ScreeningInterval time.Duration
+}
+
+// Breaker struct for the circuit breaker of an external service
+type Breaker struct {
+	// WindowSize latest calls the failure rate is computed from
+	WindowSize int
+	// MinRequests calls in the window needed before the circuit can open
+	MinRequests int
+	// FailureThreshold percentage of failed calls that opens the circuit
+	FailureThreshold int
+	// OpenDuration time calls are rejected before probing the service again
+	OpenDuration time.Duration
+	// HalfOpenRequests successful probes needed to close the circuit
+	HalfOpenRequests int
+	// OpenPolicy reject or pending_screening, handling of registrations
+	// while the circuit is open
+	OpenPolicy string
+}
+
+// Retry struct for retries of failed outgoing requests
+type Retry struct {
+	// MaxAttempts requests sent at most, including the first one
+	MaxAttempts int
+	// BaseDelay upper bound of the wait before the first retry, doubled on
+	// every retry
+	BaseDelay time.Duration
+	// MaxDelay upper bound of the wait before any retry
+	MaxDelay time.Duration
+}
+
+// HTTPClient struct for outgoing http requests
+type HTTPClient struct {
+	// ConnectTimeout time to establish a connection
+	ConnectTimeout time.Duration
+	// ReadTimeout time to wait for response headers once the request is sent
+	ReadTimeout time.Duration
+	// Timeout time for the whole request, including reading the response body
+	Timeout time.Duration
+	// MaxIdleConnsPerHost idle connections kept open for reuse per host
+	MaxIdleConnsPerHost int
 }
 
 // JWT struct for jwt authentication
 type JWT struct {
-	SecretKey string
+	SigningMethod string
+	SecretKey     string
+	KeyID         string
+	// LegacyKeyID kid of tokens signed with SecretKey before switching to
+	// RS256 or ES256, they are accepted while SecretKey is set. Empty for
+	// tokens signed without kid
+	LegacyKeyID     string
+	PrivateKeyFile  string
+	PublicKeyFiles  map[string]string
+	Issuer          string
+	Audience        string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	Leeway          time.Duration
+	// MFAChallengeTTL lifetime of tokens exchanged for access tokens after MFA
+	MFAChallengeTTL time.Duration
+	// LegacyTokenHeader accepts and returns tokens in the custom Token header
+	LegacyTokenHeader bool
+}
+
+// Password struct for password hashing
+type Password struct {
+	Hasher            string
+	BcryptCost        int
+	Argon2Memory      int
+	Argon2Iterations  int
+	Argon2Parallelism int
+	ResetTokenTTL     time.Duration
+	Policy            PasswordPolicy
+}
+
+// PasswordPolicy struct for password strength rules
+type PasswordPolicy struct {
+	MinLength          int
+	MaxLength          int
+	RequireUppercase   bool
+	RequireLowercase   bool
+	RequireDigit       bool
+	RequireSymbol      bool
+	RejectPersonalInfo bool
+	HistorySize        int
+	// BreachedBloomFile bloom filter of compromised passwords
+	BreachedBloomFile string
+	// BreachedPrefixDir directory of SHA-1 prefix range files of compromised passwords
+	BreachedPrefixDir string
+}
+
+// Lockout struct for failed login attempts thresholds
+type Lockout struct {
+	MaxAttempts int
+	Window      time.Duration
+	Duration    time.Duration
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// Debug struct for the internal listener serving runtime metrics
+type Debug struct {
+	// Addr address of the listener, it should not be reachable from the
+	// public network
+	Addr string
+}
+
+// Notifier struct for account messages delivery
+type Notifier struct {
+	// Type smtp or log, the log notifier writes links with tokens to the
+	// service log
+	Type string
+	// DevMode allows the log notifier, never enable it in production
+	DevMode              bool
+	PasswordResetURL     string
+	EmailVerificationURL string
+	SMTP                 SMTP
+}
+
+// SMTP struct for email delivery through an SMTP server
+type SMTP struct {
+	Host     string
+	Port     string
+	User     string
+	Password string
+	From     string
+}
+
+// EmailVerification struct for new users email confirmation
+type EmailVerification struct {
+	TokenTTL time.Duration
+}
+
+// MFA struct for TOTP two-factor authentication
+type MFA struct {
+	Issuer string
+}
+
+// RBAC struct for role-based access control
+type RBAC struct {
+	// AdminEmails users given admin role on startup
+	AdminEmails []string
+}
+
+// UserDeletion struct for soft deleted users retention
+type UserDeletion struct {
+	// Retention time soft deleted users are kept before being purged
+	Retention time.Duration
+	// PurgeInterval time between purges, zero disables them
+	PurgeInterval time.Duration
 }
 
 // New returns config instance with values
@@ -66,6 +230,7 @@ func New(ctx context.Context) (*Config, error) {
 		Host:     os.Getenv("PLD_HOST"),
 		Port:     os.Getenv("PLD_PORT"),
 		URI:      os.Getenv("PLD_URI"),
+		Provider: getEnv("PLD_PROVIDER", "crabi-pld"),
 	}
 
 	if pld.Protocol == "" {
@@ -81,17 +246,404 @@ func New(ctx context.Context) (*Config, error) {
 		return nil, fmt.Errorf("PLD_URI env var needed")
 	}
 
+	var err error
+	pld.Client.ConnectTimeout, err = getEnvDuration("PLD_CONNECT_TIMEOUT", 2*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	pld.Client.ReadTimeout, err = getEnvDuration("PLD_READ_TIMEOUT", 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	pld.Client.Timeout, err = getEnvDuration("PLD_TIMEOUT", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	pld.Client.MaxIdleConnsPerHost, err = getEnvInt("PLD_MAX_IDLE_CONNS_PER_HOST", 10)
+	if err != nil {
+		return nil, err
+	}
+
+	if pld.Client.ConnectTimeout <= 0 {
+		return nil, fmt.Errorf("PLD_CONNECT_TIMEOUT should be greater than zero")
+	}
+	if pld.Client.ReadTimeout <= 0 {
+		return nil, fmt.Errorf("PLD_READ_TIMEOUT should be greater than zero")
+	}
+	if pld.Client.Timeout <= 0 {
+		return nil, fmt.Errorf("PLD_TIMEOUT should be greater than zero")
+	}
+	if pld.Client.MaxIdleConnsPerHost <= 0 {
+		return nil, fmt.Errorf("PLD_MAX_IDLE_CONNS_PER_HOST should be greater than zero")
+	}
+
+	pld.Retry.MaxAttempts, err = getEnvInt("PLD_RETRY_MAX_ATTEMPTS", 3)
+	if err != nil {
+		return nil, err
+	}
+	pld.Retry.BaseDelay, err = getEnvDuration("PLD_RETRY_BASE_DELAY", 100*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+	pld.Retry.MaxDelay, err = getEnvDuration("PLD_RETRY_MAX_DELAY", 2*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	if pld.Retry.MaxAttempts <= 0 {
+		return nil, fmt.Errorf("PLD_RETRY_MAX_ATTEMPTS should be greater than zero")
+	}
+	if pld.Retry.BaseDelay <= 0 {
+		return nil, fmt.Errorf("PLD_RETRY_BASE_DELAY should be greater than zero")
+	}
+	if pld.Retry.MaxDelay < pld.Retry.BaseDelay {
+		return nil, fmt.Errorf("PLD_RETRY_MAX_DELAY should not be less than PLD_RETRY_BASE_DELAY")
+	}
+
+	pld.Breaker.OpenPolicy = getEnv("PLD_CIRCUIT_OPEN_POLICY", "reject")
+	pld.Breaker.WindowSize, err = getEnvInt("PLD_BREAKER_WINDOW_SIZE", 20)
+	if err != nil {
+		return nil, err
+	}
+	pld.Breaker.MinRequests, err = getEnvInt("PLD_BREAKER_MIN_REQUESTS", 10)
+	if err != nil {
+		return nil, err
+	}
+	pld.Breaker.FailureThreshold, err = getEnvInt("PLD_BREAKER_FAILURE_THRESHOLD", 50)
+	if err != nil {
+		return nil, err
+	}
+	pld.Breaker.OpenDuration, err = getEnvDuration("PLD_BREAKER_OPEN_DURATION", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	pld.Breaker.HalfOpenRequests, err = getEnvInt("PLD_BREAKER_HALF_OPEN_REQUESTS", 3)
+	if err != nil {
+		return nil, err
+	}
+	pld.ScreeningInterval, err = getEnvDuration("PLD_SCREENING_INTERVAL", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	if pld.Breaker.OpenPolicy != "reject" && pld.Breaker.OpenPolicy != "pending_screening" {
+		return nil, fmt.Errorf("PLD_CIRCUIT_OPEN_POLICY should be reject or pending_screening")
+	}
+	if pld.ScreeningInterval < 0 {
+		return nil, fmt.Errorf("PLD_SCREENING_INTERVAL should not be negative")
+	}
+
 	jwt := JWT{
-		SecretKey: os.Getenv("JWT_SECRET_KEY"),
+		SigningMethod:  getEnv("JWT_SIGNING_METHOD", "HS256"),
+		SecretKey:      os.Getenv("JWT_SECRET_KEY"),
+		KeyID:          os.Getenv("JWT_KEY_ID"),
+		LegacyKeyID:    os.Getenv("JWT_LEGACY_KEY_ID"),
+		PrivateKeyFile: os.Getenv("JWT_PRIVATE_KEY_FILE"),
+		Issuer:         getEnv("JWT_ISSUER", "crabi-solution"),
+		Audience:       getEnv("JWT_AUDIENCE", "crabi-solution"),
+	}
+
+	switch jwt.SigningMethod {
+	case "HS256":
+		if jwt.SecretKey == "" {
+			return nil, fmt.Errorf("JWT_SECRET_KEY env var needed")
+		}
+	case "RS256", "ES256":
+		if jwt.PrivateKeyFile == "" {
+			return nil, fmt.Errorf("JWT_PRIVATE_KEY_FILE env var needed")
+		}
+		if jwt.KeyID == "" {
+			return nil, fmt.Errorf("JWT_KEY_ID env var needed")
+		}
+		if jwt.SecretKey != "" && jwt.LegacyKeyID == jwt.KeyID {
+			return nil, fmt.Errorf("JWT_LEGACY_KEY_ID should differ from JWT_KEY_ID")
+		}
+	default:
+		return nil, fmt.Errorf("JWT_SIGNING_METHOD should be HS256, RS256 or ES256")
+	}
+
+	jwt.PublicKeyFiles, err = getEnvMap("JWT_PUBLIC_KEY_FILES")
+	if err != nil {
+		return nil, err
+	}
+	jwt.AccessTokenTTL, err = getEnvDuration("JWT_ACCESS_TOKEN_TTL", time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	jwt.RefreshTokenTTL, err = getEnvDuration("JWT_REFRESH_TOKEN_TTL", 30*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	jwt.Leeway, err = getEnvDuration("JWT_LEEWAY", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	jwt.MFAChallengeTTL, err = getEnvDuration("JWT_MFA_CHALLENGE_TTL", 5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	jwt.LegacyTokenHeader, err = getEnvBool("JWT_LEGACY_TOKEN_HEADER", true)
+	if err != nil {
+		return nil, err
+	}
+
+	password := Password{
+		Hasher: getEnv("PASSWORD_HASHER", "argon2id"),
+	}
+
+	password.BcryptCost, err = getEnvInt("PASSWORD_BCRYPT_COST", 12)
+	if err != nil {
+		return nil, err
+	}
+	password.Argon2Memory, err = getEnvInt("PASSWORD_ARGON2_MEMORY", 64*1024)
+	if err != nil {
+		return nil, err
+	}
+	password.Argon2Iterations, err = getEnvInt("PASSWORD_ARGON2_ITERATIONS", 3)
+	if err != nil {
+		return nil, err
+	}
+	password.Argon2Parallelism, err = getEnvInt("PASSWORD_ARGON2_PARALLELISM", 2)
+	if err != nil {
+		return nil, err
+	}
+
+	password.ResetTokenTTL, err = getEnvDuration("PASSWORD_RESET_TOKEN_TTL", 30*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	if password.Hasher != "argon2id" && password.Hasher != "bcrypt" {
+		return nil, fmt.Errorf("PASSWORD_HASHER should be argon2id or bcrypt")
+	}
+
+	password.Policy.MinLength, err = getEnvInt("PASSWORD_MIN_LENGTH", 8)
+	if err != nil {
+		return nil, err
+	}
+	password.Policy.MaxLength, err = getEnvInt("PASSWORD_MAX_LENGTH", 72)
+	if err != nil {
+		return nil, err
+	}
+	password.Policy.RequireUppercase, err = getEnvBool("PASSWORD_REQUIRE_UPPERCASE", false)
+	if err != nil {
+		return nil, err
+	}
+	password.Policy.RequireLowercase, err = getEnvBool("PASSWORD_REQUIRE_LOWERCASE", false)
+	if err != nil {
+		return nil, err
+	}
+	password.Policy.RequireDigit, err = getEnvBool("PASSWORD_REQUIRE_DIGIT", false)
+	if err != nil {
+		return nil, err
+	}
+	password.Policy.RequireSymbol, err = getEnvBool("PASSWORD_REQUIRE_SYMBOL", false)
+	if err != nil {
+		return nil, err
+	}
+	password.Policy.RejectPersonalInfo, err = getEnvBool("PASSWORD_REJECT_PERSONAL_INFO", true)
+	if err != nil {
+		return nil, err
+	}
+	password.Policy.HistorySize, err = getEnvInt("PASSWORD_HISTORY_SIZE", 5)
+	if err != nil {
+		return nil, err
+	}
+	password.Policy.BreachedBloomFile = os.Getenv("PASSWORD_BREACHED_BLOOM_FILE")
+	password.Policy.BreachedPrefixDir = os.Getenv("PASSWORD_BREACHED_PREFIX_DIR")
+
+	if password.Policy.BreachedBloomFile != "" && password.Policy.BreachedPrefixDir != "" {
+		return nil, fmt.Errorf("only one of PASSWORD_BREACHED_BLOOM_FILE or PASSWORD_BREACHED_PREFIX_DIR should be set")
+	}
+
+	var lockout Lockout
+	lockout.MaxAttempts, err = getEnvInt("LOGIN_MAX_ATTEMPTS", 5)
+	if err != nil {
+		return nil, err
+	}
+	lockout.Window, err = getEnvDuration("LOGIN_ATTEMPTS_WINDOW", 15*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	lockout.Duration, err = getEnvDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	lockout.BaseDelay, err = getEnvDuration("LOGIN_BACKOFF_BASE_DELAY", time.Second)
+	if err != nil {
+		return nil, err
+	}
+	lockout.MaxDelay, err = getEnvDuration("LOGIN_BACKOFF_MAX_DELAY", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	notifier := Notifier{
+		Type:                 getEnv("NOTIFIER_TYPE", "smtp"),
+		PasswordResetURL:     getEnv("NOTIFIER_PASSWORD_RESET_URL", "http://localhost:8080/reset-password"),
+		EmailVerificationURL: getEnv("NOTIFIER_EMAIL_VERIFICATION_URL", "http://localhost:8080/api/v1/users/verify"),
+		SMTP: SMTP{
+			Host:     os.Getenv("NOTIFIER_SMTP_HOST"),
+			Port:     getEnv("NOTIFIER_SMTP_PORT", "587"),
+			User:     os.Getenv("NOTIFIER_SMTP_USER"),
+			Password: os.Getenv("NOTIFIER_SMTP_PASSWORD"),
+			From:     os.Getenv("NOTIFIER_SMTP_FROM"),
+		},
+	}
+	notifier.DevMode, err = getEnvBool("NOTIFIER_DEV_MODE", false)
+	if err != nil {
+		return nil, err
+	}
+
+	switch notifier.Type {
+	case "smtp":
+		if notifier.SMTP.Host == "" {
+			return nil, fmt.Errorf("NOTIFIER_SMTP_HOST env var needed")
+		}
+		if notifier.SMTP.From == "" {
+			return nil, fmt.Errorf("NOTIFIER_SMTP_FROM env var needed")
+		}
+	case "log":
+		if !notifier.DevMode {
+			return nil, fmt.Errorf("NOTIFIER_TYPE log writes account tokens to the log, it needs NOTIFIER_DEV_MODE enabled")
+		}
+	default:
+		return nil, fmt.Errorf("NOTIFIER_TYPE should be smtp or log")
+	}
+
+	var emailVerification EmailVerification
+	emailVerification.TokenTTL, err = getEnvDuration("EMAIL_VERIFICATION_TOKEN_TTL", 24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	mfa := MFA{
+		Issuer: getEnv("MFA_ISSUER", "crabi-solution"),
+	}
+
+	rbac := RBAC{
+		AdminEmails: getEnvList("RBAC_ADMIN_EMAILS"),
+	}
+
+	var userDeletion UserDeletion
+	userDeletion.Retention, err = getEnvDuration("USER_DELETION_RETENTION", 30*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	userDeletion.PurgeInterval, err = getEnvDuration("USER_PURGE_INTERVAL", time.Hour)
+	if err != nil {
+		return nil, err
 	}
 
-	if jwt.SecretKey == "" {
-		return nil, fmt.Errorf("JWT_SECRET_KEY env var needed")
+	if userDeletion.Retention <= 0 {
+		return nil, fmt.Errorf("USER_DELETION_RETENTION should be greater than zero")
+	}
+	if userDeletion.PurgeInterval < 0 {
+		return nil, fmt.Errorf("USER_PURGE_INTERVAL should not be negative")
 	}
 
 	return &Config{
-		Mongo: mongo,
-		PLD:   pld,
-		JWT:   jwt,
+		Mongo:             mongo,
+		PLD:               pld,
+		JWT:               jwt,
+		Password:          password,
+		Lockout:           lockout,
+		Notifier:          notifier,
+		EmailVerification: emailVerification,
+		MFA:               mfa,
+		RBAC:              rbac,
+		UserDeletion:      userDeletion,
+		Debug: Debug{
+			Addr: getEnv("DEBUG_ADDR", "127.0.0.1:6060"),
+		},
 	}, nil
 }
+
+// getEnv returns env var value or fallback if not set
+func getEnv(key, fallback string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	return value
+}
+
+// getEnvInt returns env var value as int or fallback if not set
+func getEnvInt(key string, fallback int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s env var should be an integer", key)
+	}
+
+	return number, nil
+}
+
+// getEnvDuration returns env var value as duration or fallback if not set
+func getEnvDuration(key string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s env var should be a duration", key)
+	}
+
+	return duration, nil
+}
+
+// getEnvMap returns env var value with format key1=value1,key2=value2 as map
+func getEnvMap(key string) (map[string]string, error) {
+	values := map[string]string{}
+
+	value := os.Getenv(key)
+	if value == "" {
+		return values, nil
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("%s env var should have format key1=value1,key2=value2", key)
+		}
+		values[parts[0]] = parts[1]
+	}
+
+	return values, nil
+}
+
+// getEnvList returns env var value with format value1,value2 as slice
+func getEnvList(key string) []string {
+	values := []string{}
+
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		value = strings.TrimSpace(value)
+		if value != "" {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
+
+// getEnvBool returns env var value as bool or fallback if not set
+func getEnvBool(key string, fallback bool) (bool, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+
+	flag, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s env var should be a boolean", key)
+	}
+
+	return flag, nil
+}
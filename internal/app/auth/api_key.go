@@ -0,0 +1,182 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/alamrios/crabi-solution/internal/app/opaque"
+)
+
+// APIKeyPrefix marks API keys so they can be told apart from access tokens
+const APIKeyPrefix = "crb_"
+
+// apiKeyIDLength hex characters of the public key identifier
+const apiKeyIDLength = 16
+
+// ErrInvalidAPIKey returned when API key is malformed, unknown, expired or revoked
+var ErrInvalidAPIKey = fmt.Errorf("invalid api key")
+
+// APIKey struct for machine-to-machine credentials, only the hash of the
+// secret key is stored
+type APIKey struct {
+	ID         string     `bson:"_id"`
+	Hash       string     `bson:"hash"`
+	Name       string     `bson:"name"`
+	Owner      string     `bson:"owner"`
+	Scopes     []string   `bson:"scopes"`
+	CreatedBy  string     `bson:"created_by"`
+	CreatedAt  time.Time  `bson:"created_at"`
+	ExpiresAt  *time.Time `bson:"expires_at,omitempty"`
+	LastUsedAt *time.Time `bson:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `bson:"revoked_at,omitempty"`
+}
+
+// APIKeyService struct for API keys management and authentication
+type APIKeyService struct {
+	apiKeyRepo APIKeyRepository
+}
+
+// NewAPIKeyService returns an instance of API key service
+func NewAPIKeyService(apiKeyRepo APIKeyRepository) (*APIKeyService, error) {
+	if apiKeyRepo == nil {
+		return nil, fmt.Errorf("api key repo is nil")
+	}
+
+	return &APIKeyService{
+		apiKeyRepo: apiKeyRepo,
+	}, nil
+}
+
+// CreateAPIKey stores a new API key and returns it along with the secret
+// key, which is not retrievable afterwards
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, apiKey APIKey) (*APIKey, string, error) {
+	if apiKey.Name == "" {
+		return nil, "", fmt.Errorf("api key name should not be empty")
+	}
+	if apiKey.Owner == "" {
+		return nil, "", fmt.Errorf("api key owner should not be empty")
+	}
+	if len(apiKey.Scopes) == 0 {
+		return nil, "", fmt.Errorf("api key scopes should not be empty")
+	}
+
+	now := time.Now().UTC()
+
+	if apiKey.ExpiresAt != nil && !now.Before(*apiKey.ExpiresAt) {
+		return nil, "", fmt.Errorf("api key expiration should be in the future")
+	}
+
+	id, err := newPublicID()
+	if err != nil {
+		return nil, "", err
+	}
+
+	secret, err := opaque.NewToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+
+	key := APIKeyPrefix + id + "_" + secret
+
+	apiKey.ID = id
+	apiKey.Hash = opaque.Hash(key)
+	apiKey.CreatedAt = now
+	apiKey.LastUsedAt = nil
+	apiKey.RevokedAt = nil
+
+	err = s.apiKeyRepo.SaveAPIKey(ctx, apiKey)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &apiKey, key, nil
+}
+
+// ListAPIKeys returns every API key, including expired and revoked ones
+func (s *APIKeyService) ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	return s.apiKeyRepo.ListAPIKeys(ctx)
+}
+
+// RevokeAPIKey disables API key with given id
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, id string) error {
+	if id == "" {
+		return fmt.Errorf("api key id should not be empty")
+	}
+
+	apiKey, err := s.apiKeyRepo.GetAPIKey(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if apiKey == nil {
+		return fmt.Errorf("api key not exists")
+	}
+
+	if apiKey.RevokedAt != nil {
+		return nil
+	}
+
+	return s.apiKeyRepo.RevokeAPIKey(ctx, id, time.Now().UTC())
+}
+
+// AuthenticateAPIKey returns the active API key matching given secret key
+// and records its use
+func (s *APIKeyService) AuthenticateAPIKey(ctx context.Context, key string) (*APIKey, error) {
+	id, ok := parseAPIKeyID(key)
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+
+	apiKey, err := s.apiKeyRepo.GetAPIKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if apiKey == nil || subtle.ConstantTimeCompare([]byte(apiKey.Hash), []byte(opaque.Hash(key))) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now().UTC()
+
+	if apiKey.RevokedAt != nil || (apiKey.ExpiresAt != nil && !now.Before(*apiKey.ExpiresAt)) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	err = s.apiKeyRepo.UpdateAPIKeyLastUsed(ctx, id, now)
+	if err != nil {
+		log.Printf("failed to record use of api key %s: %v", id, err)
+	}
+	apiKey.LastUsedAt = &now
+
+	return apiKey, nil
+}
+
+// parseAPIKeyID returns public identifier embedded in given secret key
+func parseAPIKeyID(key string) (string, bool) {
+	if !strings.HasPrefix(key, APIKeyPrefix) {
+		return "", false
+	}
+
+	rest := strings.TrimPrefix(key, APIKeyPrefix)
+	if len(rest) <= apiKeyIDLength+1 || rest[apiKeyIDLength] != '_' {
+		return "", false
+	}
+
+	return rest[:apiKeyIDLength], true
+}
+
+// newPublicID returns a random public identifier for API keys and clients
+func newPublicID() (string, error) {
+	b := make([]byte, apiKeyIDLength/2)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
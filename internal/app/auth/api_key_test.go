@@ -0,0 +1,256 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/alamrios/crabi-solution/internal/app/auth"
+	tmock "github.com/alamrios/crabi-solution/test/mock"
+)
+
+func TestNewAPIKeyService(t *testing.T) {
+	got, err := auth.NewAPIKeyService(nil)
+	assert.EqualError(t, err, "api key repo is nil")
+	assert.Nil(t, got)
+
+	got, err = auth.NewAPIKeyService(&tmock.APIKeyRepository{})
+	assert.NoError(t, err)
+	assert.NotNil(t, got)
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	valid := auth.APIKey{
+		Name:      "back-office",
+		Owner:     "back-office-service",
+		Scopes:    []string{"users:create"},
+		CreatedBy: "joaquin@guzman.com",
+		ExpiresAt: &future,
+	}
+
+	testCases := map[string]struct {
+		apiKey        auth.APIKey
+		repoCalls     []tmock.Call
+		expectedError error
+	}{
+		"success": {
+			apiKey: valid,
+			repoCalls: []tmock.Call{
+				{
+					FunctionName: "SaveAPIKey",
+					Params: []interface{}{
+						mock.MatchedBy(func(apiKey auth.APIKey) bool {
+							return len(apiKey.ID) == 16 && apiKey.Hash != "" &&
+								apiKey.Owner == valid.Owner && !apiKey.CreatedAt.IsZero()
+						}),
+					},
+					Returns: []interface{}{nil},
+				},
+			},
+		},
+		"empty name should return error": {
+			apiKey:        auth.APIKey{Owner: "back-office-service", Scopes: []string{"users:create"}},
+			expectedError: fmt.Errorf("api key name should not be empty"),
+		},
+		"empty owner should return error": {
+			apiKey:        auth.APIKey{Name: "back-office", Scopes: []string{"users:create"}},
+			expectedError: fmt.Errorf("api key owner should not be empty"),
+		},
+		"empty scopes should return error": {
+			apiKey:        auth.APIKey{Name: "back-office", Owner: "back-office-service"},
+			expectedError: fmt.Errorf("api key scopes should not be empty"),
+		},
+		"past expiration should return error": {
+			apiKey: auth.APIKey{
+				Name:      "back-office",
+				Owner:     "back-office-service",
+				Scopes:    []string{"users:create"},
+				ExpiresAt: &past,
+			},
+			expectedError: fmt.Errorf("api key expiration should be in the future"),
+		},
+	}
+
+	for name, tc := range testCases {
+		ctx := context.Background()
+		repo := tmock.NewAPIKeyRepository().AddCall(t, tc.repoCalls)
+
+		service, err := auth.NewAPIKeyService(repo)
+		assert.NoError(t, err)
+
+		apiKey := tc.apiKey
+		expectedError := tc.expectedError
+
+		t.Run(name, func(t *testing.T) {
+			got, key, err := service.CreateAPIKey(ctx, apiKey)
+			if expectedError == nil {
+				assert.NoError(t, err)
+				assert.True(t, strings.HasPrefix(key, auth.APIKeyPrefix+got.ID+"_"))
+
+				sum := sha256.Sum256([]byte(key))
+				assert.Equal(t, hex.EncodeToString(sum[:]), got.Hash)
+			} else {
+				assert.Equal(t, expectedError, err)
+				assert.Nil(t, got)
+				assert.Empty(t, key)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	id := "3f9a1c2b7d4e8f60"
+	key := auth.APIKeyPrefix + id + "_k3S0zN9rT6xQ"
+	sum := sha256.Sum256([]byte(key))
+	hash := hex.EncodeToString(sum[:])
+	past := time.Now().Add(-time.Hour)
+
+	active := &auth.APIKey{ID: id, Hash: hash, Scopes: []string{"users:create"}}
+	expired := &auth.APIKey{ID: id, Hash: hash, ExpiresAt: &past}
+	revoked := &auth.APIKey{ID: id, Hash: hash, RevokedAt: &past}
+
+	getCall := func(apiKey *auth.APIKey) tmock.Call {
+		return tmock.Call{
+			FunctionName: "GetAPIKey",
+			Params:       []interface{}{id},
+			Returns:      []interface{}{apiKey, nil},
+		}
+	}
+
+	testCases := map[string]struct {
+		key           string
+		repoCalls     []tmock.Call
+		expectedError error
+	}{
+		"success should record last use": {
+			key: key,
+			repoCalls: []tmock.Call{
+				getCall(active),
+				{
+					FunctionName: "UpdateAPIKeyLastUsed",
+					Params:       []interface{}{id, mock.AnythingOfType("time.Time")},
+					Returns:      []interface{}{nil},
+				},
+			},
+		},
+		"wrong secret should return error": {
+			key:           auth.APIKeyPrefix + id + "_other",
+			repoCalls:     []tmock.Call{getCall(active)},
+			expectedError: auth.ErrInvalidAPIKey,
+		},
+		"unknown key should return error": {
+			key:           key,
+			repoCalls:     []tmock.Call{getCall(nil)},
+			expectedError: auth.ErrInvalidAPIKey,
+		},
+		"expired key should return error": {
+			key:           key,
+			repoCalls:     []tmock.Call{getCall(expired)},
+			expectedError: auth.ErrInvalidAPIKey,
+		},
+		"revoked key should return error": {
+			key:           key,
+			repoCalls:     []tmock.Call{getCall(revoked)},
+			expectedError: auth.ErrInvalidAPIKey,
+		},
+		"malformed key should return error": {
+			key:           "crb_short",
+			expectedError: auth.ErrInvalidAPIKey,
+		},
+	}
+
+	for name, tc := range testCases {
+		ctx := context.Background()
+		repo := tmock.NewAPIKeyRepository().AddCall(t, tc.repoCalls)
+
+		service, err := auth.NewAPIKeyService(repo)
+		assert.NoError(t, err)
+
+		key := tc.key
+		expectedError := tc.expectedError
+
+		t.Run(name, func(t *testing.T) {
+			got, err := service.AuthenticateAPIKey(ctx, key)
+			if expectedError == nil {
+				assert.NoError(t, err)
+				assert.Equal(t, id, got.ID)
+				assert.NotNil(t, got.LastUsedAt)
+			} else {
+				assert.Equal(t, expectedError, err)
+				assert.Nil(t, got)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	id := "3f9a1c2b7d4e8f60"
+	past := time.Now().Add(-time.Hour)
+
+	testCases := map[string]struct {
+		repoCalls     []tmock.Call
+		expectedError error
+	}{
+		"success": {
+			repoCalls: []tmock.Call{
+				{
+					FunctionName: "GetAPIKey",
+					Params:       []interface{}{id},
+					Returns:      []interface{}{&auth.APIKey{ID: id}, nil},
+				},
+				{
+					FunctionName: "RevokeAPIKey",
+					Params:       []interface{}{id, mock.AnythingOfType("time.Time")},
+					Returns:      []interface{}{nil},
+				},
+			},
+		},
+		"already revoked key should not return error": {
+			repoCalls: []tmock.Call{
+				{
+					FunctionName: "GetAPIKey",
+					Params:       []interface{}{id},
+					Returns:      []interface{}{&auth.APIKey{ID: id, RevokedAt: &past}, nil},
+				},
+			},
+		},
+		"unknown key should return error": {
+			repoCalls: []tmock.Call{
+				{
+					FunctionName: "GetAPIKey",
+					Params:       []interface{}{id},
+					Returns:      []interface{}{(*auth.APIKey)(nil), nil},
+				},
+			},
+			expectedError: fmt.Errorf("api key not exists"),
+		},
+	}
+
+	for name, tc := range testCases {
+		ctx := context.Background()
+		repo := tmock.NewAPIKeyRepository().AddCall(t, tc.repoCalls)
+
+		service, err := auth.NewAPIKeyService(repo)
+		assert.NoError(t, err)
+
+		expectedError := tc.expectedError
+
+		t.Run(name, func(t *testing.T) {
+			err := service.RevokeAPIKey(ctx, id)
+			assert.Equal(t, expectedError, err)
+			repo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"time"
+
+	"github.com/alamrios/crabi-solution/internal/app/opaque"
+)
+
+var (
+	// ErrInvalidClient returned when client is unknown, disabled or its secret does not match
+	ErrInvalidClient = fmt.Errorf("invalid client")
+	// ErrInvalidScope returned when requested scope is not allowed for the client
+	ErrInvalidScope = fmt.Errorf("invalid scope")
+)
+
+// Client struct for OAuth2 clients using the client credentials grant,
+// only the hash of the secret is stored
+type Client struct {
+	ID         string     `bson:"_id"`
+	SecretHash string     `bson:"secret_hash"`
+	Name       string     `bson:"name"`
+	Scopes     []string   `bson:"scopes"`
+	CreatedBy  string     `bson:"created_by"`
+	CreatedAt  time.Time  `bson:"created_at"`
+	DisabledAt *time.Time `bson:"disabled_at,omitempty"`
+}
+
+// ClientService struct for OAuth2 clients registration and authentication
+type ClientService struct {
+	clientRepo ClientRepository
+}
+
+// NewClientService returns an instance of OAuth2 client service
+func NewClientService(clientRepo ClientRepository) (*ClientService, error) {
+	if clientRepo == nil {
+		return nil, fmt.Errorf("client repo is nil")
+	}
+
+	return &ClientService{
+		clientRepo: clientRepo,
+	}, nil
+}
+
+// CreateClient registers a new client and returns it along with its secret,
+// which is not retrievable afterwards
+func (s *ClientService) CreateClient(ctx context.Context, client Client) (*Client, string, error) {
+	if client.Name == "" {
+		return nil, "", fmt.Errorf("client name should not be empty")
+	}
+	if len(client.Scopes) == 0 {
+		return nil, "", fmt.Errorf("client scopes should not be empty")
+	}
+
+	id, err := newPublicID()
+	if err != nil {
+		return nil, "", err
+	}
+
+	secret, err := opaque.NewToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+
+	client.ID = id
+	client.SecretHash = opaque.Hash(secret)
+	client.CreatedAt = time.Now().UTC()
+	client.DisabledAt = nil
+
+	err = s.clientRepo.SaveClient(ctx, client)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &client, secret, nil
+}
+
+// DisableClient stops client with given id from getting new tokens
+func (s *ClientService) DisableClient(ctx context.Context, id string) error {
+	client, err := s.GetClient(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if client == nil {
+		return fmt.Errorf("client not exists")
+	}
+
+	if client.DisabledAt != nil {
+		return nil
+	}
+
+	return s.clientRepo.DisableClient(ctx, id, time.Now().UTC())
+}
+
+// GetClient returns client with given id, nil if it does not exist
+func (s *ClientService) GetClient(ctx context.Context, id string) (*Client, error) {
+	if id == "" {
+		return nil, fmt.Errorf("client id should not be empty")
+	}
+
+	return s.clientRepo.GetClient(ctx, id)
+}
+
+// AuthenticateClient checks client credentials and returns granted scopes,
+// all the client's scopes when none are requested
+func (s *ClientService) AuthenticateClient(ctx context.Context, id, secret string, scopes []string) (*Client, []string, error) {
+	if id == "" || secret == "" {
+		return nil, nil, ErrInvalidClient
+	}
+
+	client, err := s.clientRepo.GetClient(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if client == nil || client.DisabledAt != nil ||
+		subtle.ConstantTimeCompare([]byte(client.SecretHash), []byte(opaque.Hash(secret))) != 1 {
+		return nil, nil, ErrInvalidClient
+	}
+
+	if len(scopes) == 0 {
+		return client, client.Scopes, nil
+	}
+
+	for _, scope := range scopes {
+		if !contains(client.Scopes, scope) {
+			return nil, nil, ErrInvalidScope
+		}
+	}
+
+	return client, scopes, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
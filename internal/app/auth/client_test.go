@@ -0,0 +1,163 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/alamrios/crabi-solution/internal/app/auth"
+	tmock "github.com/alamrios/crabi-solution/test/mock"
+)
+
+func TestCreateClient(t *testing.T) {
+	ctx := context.Background()
+
+	repo := tmock.NewClientRepository().AddCall(t, []tmock.Call{
+		{
+			FunctionName: "SaveClient",
+			Params: []interface{}{
+				mock.MatchedBy(func(client auth.Client) bool {
+					return client.ID != "" && client.SecretHash != "" && client.Name == "partner"
+				}),
+			},
+			Returns: []interface{}{nil},
+		},
+	})
+
+	service, err := auth.NewClientService(repo)
+	assert.NoError(t, err)
+
+	client, secret, err := service.CreateClient(ctx, auth.Client{Name: "partner", Scopes: []string{"users:read"}})
+	assert.NoError(t, err)
+
+	sum := sha256.Sum256([]byte(secret))
+	assert.Equal(t, hex.EncodeToString(sum[:]), client.SecretHash)
+	repo.AssertExpectations(t)
+
+	_, _, err = service.CreateClient(ctx, auth.Client{Name: "partner"})
+	assert.EqualError(t, err, "client scopes should not be empty")
+}
+
+func TestAuthenticateClient(t *testing.T) {
+	id := "3f9a1c2b7d4e8f60"
+	secret := "k3S0zN9rT6xQ"
+	sum := sha256.Sum256([]byte(secret))
+	disabledAt := time.Now().Add(-time.Hour)
+
+	active := &auth.Client{
+		ID:         id,
+		SecretHash: hex.EncodeToString(sum[:]),
+		Scopes:     []string{"users:create", "users:read"},
+	}
+	disabled := &auth.Client{
+		ID:         id,
+		SecretHash: hex.EncodeToString(sum[:]),
+		Scopes:     []string{"users:create"},
+		DisabledAt: &disabledAt,
+	}
+
+	testCases := map[string]struct {
+		secret         string
+		scopes         []string
+		client         *auth.Client
+		expectedScopes []string
+		expectedError  error
+	}{
+		"no requested scope should grant every client scope": {
+			secret:         secret,
+			client:         active,
+			expectedScopes: []string{"users:create", "users:read"},
+		},
+		"requested scope should be granted": {
+			secret:         secret,
+			scopes:         []string{"users:read"},
+			client:         active,
+			expectedScopes: []string{"users:read"},
+		},
+		"scope not allowed should return error": {
+			secret:        secret,
+			scopes:        []string{"users:unlock"},
+			client:        active,
+			expectedError: auth.ErrInvalidScope,
+		},
+		"wrong secret should return error": {
+			secret:        "other",
+			client:        active,
+			expectedError: auth.ErrInvalidClient,
+		},
+		"disabled client should return error": {
+			secret:        secret,
+			client:        disabled,
+			expectedError: auth.ErrInvalidClient,
+		},
+		"unknown client should return error": {
+			secret:        secret,
+			expectedError: auth.ErrInvalidClient,
+		},
+	}
+
+	for name, tc := range testCases {
+		ctx := context.Background()
+		repo := tmock.NewClientRepository().AddCall(t, []tmock.Call{
+			{
+				FunctionName: "GetClient",
+				Params:       []interface{}{id},
+				Returns:      []interface{}{tc.client, nil},
+			},
+		})
+
+		service, err := auth.NewClientService(repo)
+		assert.NoError(t, err)
+
+		secret := tc.secret
+		scopes := tc.scopes
+		expectedScopes := tc.expectedScopes
+		expectedError := tc.expectedError
+
+		t.Run(name, func(t *testing.T) {
+			client, granted, err := service.AuthenticateClient(ctx, id, secret, scopes)
+			if expectedError == nil {
+				assert.NoError(t, err)
+				assert.Equal(t, id, client.ID)
+				assert.Equal(t, expectedScopes, granted)
+			} else {
+				assert.Equal(t, expectedError, err)
+				assert.Nil(t, client)
+			}
+		})
+	}
+}
+
+func TestDisableClient(t *testing.T) {
+	ctx := context.Background()
+	id := "3f9a1c2b7d4e8f60"
+
+	repo := tmock.NewClientRepository().AddCall(t, []tmock.Call{
+		{
+			FunctionName: "GetClient",
+			Params:       []interface{}{id},
+			Returns:      []interface{}{&auth.Client{ID: id}, nil},
+		},
+		{
+			FunctionName: "DisableClient",
+			Params:       []interface{}{id, mock.AnythingOfType("time.Time")},
+			Returns:      []interface{}{nil},
+		},
+	})
+
+	service, err := auth.NewClientService(repo)
+	assert.NoError(t, err)
+
+	err = service.DisableClient(ctx, id)
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+
+	_, err = service.GetClient(ctx, "")
+	assert.Equal(t, fmt.Errorf("client id should not be empty"), err)
+}
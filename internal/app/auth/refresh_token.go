@@ -0,0 +1,16 @@
+package auth
+
+import (
+	"time"
+)
+
+// RefreshToken struct, only the hash of the opaque token is stored
+type RefreshToken struct {
+	Hash      string     `bson:"_id"`
+	FamilyID  string     `bson:"family_id"`
+	Email     string     `bson:"email"`
+	CreatedAt time.Time  `bson:"created_at"`
+	ExpiresAt time.Time  `bson:"expires_at"`
+	UsedAt    *time.Time `bson:"used_at,omitempty"`
+	RevokedAt *time.Time `bson:"revoked_at,omitempty"`
+}
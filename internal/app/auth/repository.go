@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"context"
+	"time"
+)
+
+// RefreshTokenRepository contract for refresh tokens repository
+type RefreshTokenRepository interface {
+	SaveRefreshToken(ctx context.Context, token RefreshToken) error
+	GetRefreshToken(ctx context.Context, hash string) (*RefreshToken, error)
+	// MarkRefreshTokenUsed flags token as used, returns false if it was already used
+	MarkRefreshTokenUsed(ctx context.Context, hash string, usedAt time.Time) (bool, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string, revokedAt time.Time) error
+	RevokeRefreshTokensByEmail(ctx context.Context, email string, revokedAt time.Time) error
+}
+
+// RevokedTokenRepository contract for revoked access tokens repository
+type RevokedTokenRepository interface {
+	SaveRevokedToken(ctx context.Context, token RevokedToken) error
+	// InsertRevokedToken saves given token, returns false when a token with
+	// the same jti was already revoked
+	InsertRevokedToken(ctx context.Context, token RevokedToken) (bool, error)
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+// SessionRepository contract for login sessions repository
+type SessionRepository interface {
+	SaveSession(ctx context.Context, session Session) error
+	GetSession(ctx context.Context, id string) (*Session, error)
+	ListSessions(ctx context.Context, email string) ([]Session, error)
+	UpdateSessionLastSeen(ctx context.Context, id string, lastSeenAt time.Time) error
+	ExtendSession(ctx context.Context, id string, lastSeenAt, expiresAt time.Time) error
+	DeleteSession(ctx context.Context, id string) error
+	DeleteSessionsByEmail(ctx context.Context, email string) error
+}
+
+// APIKeyRepository contract for API keys repository
+type APIKeyRepository interface {
+	SaveAPIKey(ctx context.Context, apiKey APIKey) error
+	GetAPIKey(ctx context.Context, id string) (*APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]APIKey, error)
+	RevokeAPIKey(ctx context.Context, id string, revokedAt time.Time) error
+	UpdateAPIKeyLastUsed(ctx context.Context, id string, usedAt time.Time) error
+}
+
+// ClientRepository contract for OAuth2 clients repository
+type ClientRepository interface {
+	SaveClient(ctx context.Context, client Client) error
+	GetClient(ctx context.Context, id string) (*Client, error)
+	DisableClient(ctx context.Context, id string, disabledAt time.Time) error
+}
@@ -0,0 +1,13 @@
+package auth
+
+import (
+	"time"
+)
+
+// RevokedToken struct, kept until the access token would have expired
+type RevokedToken struct {
+	JTI       string    `bson:"_id"`
+	Email     string    `bson:"email"`
+	RevokedAt time.Time `bson:"revoked_at"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
@@ -0,0 +1,201 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/alamrios/crabi-solution/internal/app/opaque"
+)
+
+// ErrInvalidRefreshToken returned when refresh token is unknown, expired or revoked
+var ErrInvalidRefreshToken = fmt.Errorf("invalid refresh token")
+
+// ErrRefreshTokenReused returned when an already rotated refresh token is replayed
+var ErrRefreshTokenReused = fmt.Errorf("refresh token reuse detected")
+
+// Service struct for auth service
+type Service struct {
+	refreshTokenRepo RefreshTokenRepository
+	revokedTokenRepo RevokedTokenRepository
+	sessionRepo      SessionRepository
+	refreshTokenTTL  time.Duration
+}
+
+// NewService returns an instance of auth service
+func NewService(
+	refreshTokenRepo RefreshTokenRepository,
+	revokedTokenRepo RevokedTokenRepository,
+	sessionRepo SessionRepository,
+	refreshTokenTTL time.Duration,
+) (*Service, error) {
+	if refreshTokenRepo == nil {
+		return nil, fmt.Errorf("refresh token repo is nil")
+	}
+
+	if revokedTokenRepo == nil {
+		return nil, fmt.Errorf("revoked token repo is nil")
+	}
+
+	if sessionRepo == nil {
+		return nil, fmt.Errorf("session repo is nil")
+	}
+
+	if refreshTokenTTL <= 0 {
+		return nil, fmt.Errorf("refresh token ttl should be greater than zero")
+	}
+
+	return &Service{
+		refreshTokenRepo: refreshTokenRepo,
+		revokedTokenRepo: revokedTokenRepo,
+		sessionRepo:      sessionRepo,
+		refreshTokenTTL:  refreshTokenTTL,
+	}, nil
+}
+
+// RevokeRefreshToken revokes the family of given refresh token, unknown
+// tokens are ignored so logout is idempotent
+func (s *Service) RevokeRefreshToken(ctx context.Context, token string) error {
+	if token == "" {
+		return fmt.Errorf("refresh token should not be empty")
+	}
+
+	current, err := s.refreshTokenRepo.GetRefreshToken(ctx, opaque.Hash(token))
+	if err != nil {
+		return err
+	}
+
+	if current == nil {
+		return nil
+	}
+
+	return s.refreshTokenRepo.RevokeRefreshTokenFamily(ctx, current.FamilyID, time.Now().UTC())
+}
+
+// RevokeRefreshTokens revokes every refresh token issued to given email
+func (s *Service) RevokeRefreshTokens(ctx context.Context, email string) error {
+	if email == "" {
+		return fmt.Errorf("user's email should not be empty")
+	}
+
+	return s.refreshTokenRepo.RevokeRefreshTokensByEmail(ctx, email, time.Now().UTC())
+}
+
+// RevokeToken denies access token with given jti until it expires
+func (s *Service) RevokeToken(ctx context.Context, jti, email string, expiresAt time.Time) error {
+	if jti == "" {
+		return fmt.Errorf("token id should not be empty")
+	}
+
+	return s.revokedTokenRepo.SaveRevokedToken(ctx, RevokedToken{
+		JTI:       jti,
+		Email:     email,
+		RevokedAt: time.Now().UTC(),
+		ExpiresAt: expiresAt.UTC(),
+	})
+}
+
+// ConsumeToken revokes single use token with given jti until it expires,
+// returns false when it was already consumed so concurrent uses of the same
+// token succeed once
+func (s *Service) ConsumeToken(ctx context.Context, jti, email string, expiresAt time.Time) (bool, error) {
+	if jti == "" {
+		return false, fmt.Errorf("token id should not be empty")
+	}
+
+	return s.revokedTokenRepo.InsertRevokedToken(ctx, RevokedToken{
+		JTI:       jti,
+		Email:     email,
+		RevokedAt: time.Now().UTC(),
+		ExpiresAt: expiresAt.UTC(),
+	})
+}
+
+// IsTokenRevoked reports whether access token with given jti was revoked
+func (s *Service) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	if jti == "" {
+		return false, fmt.Errorf("token id should not be empty")
+	}
+
+	return s.revokedTokenRepo.IsTokenRevoked(ctx, jti)
+}
+
+// rotateRefreshToken marks given refresh token used and issues the next one
+// of its family, returns the rotated token and the new opaque token
+func (s *Service) rotateRefreshToken(ctx context.Context, token string) (*RefreshToken, string, error) {
+	if token == "" {
+		return nil, "", fmt.Errorf("refresh token should not be empty")
+	}
+
+	hash := opaque.Hash(token)
+
+	current, err := s.refreshTokenRepo.GetRefreshToken(ctx, hash)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if current == nil || current.RevokedAt != nil {
+		return nil, "", ErrInvalidRefreshToken
+	}
+
+	now := time.Now().UTC()
+
+	if current.UsedAt != nil {
+		s.revokeFamily(ctx, current, now)
+		return nil, "", ErrRefreshTokenReused
+	}
+
+	if !now.Before(current.ExpiresAt) {
+		return nil, "", ErrInvalidRefreshToken
+	}
+
+	marked, err := s.refreshTokenRepo.MarkRefreshTokenUsed(ctx, hash, now)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// another request rotated this token first, treat it as a replay
+	if !marked {
+		s.revokeFamily(ctx, current, now)
+		return nil, "", ErrRefreshTokenReused
+	}
+
+	next, err := s.issueRefreshToken(ctx, current.Email, current.FamilyID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return current, next, nil
+}
+
+func (s *Service) issueRefreshToken(ctx context.Context, email, familyID string) (string, error) {
+	token, err := opaque.NewToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now().UTC()
+
+	err = s.refreshTokenRepo.SaveRefreshToken(ctx, RefreshToken{
+		Hash:      opaque.Hash(token),
+		FamilyID:  familyID,
+		Email:     email,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.refreshTokenTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func (s *Service) revokeFamily(ctx context.Context, token *RefreshToken, now time.Time) {
+	log.Printf("refresh token reuse detected for %s, revoking family %s", token.Email, token.FamilyID)
+
+	err := s.refreshTokenRepo.RevokeRefreshTokenFamily(ctx, token.FamilyID, now)
+	if err != nil {
+		log.Printf("failed to revoke refresh token family %s: %v", token.FamilyID, err)
+	}
+}
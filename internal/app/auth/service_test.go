@@ -0,0 +1,316 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/alamrios/crabi-solution/internal/app/auth"
+	tmock "github.com/alamrios/crabi-solution/test/mock"
+)
+
+func TestNewService(t *testing.T) {
+	testCases := map[string]struct {
+		refreshTokenRepo auth.RefreshTokenRepository
+		revokedTokenRepo auth.RevokedTokenRepository
+		sessionRepo      auth.SessionRepository
+		refreshTokenTTL  time.Duration
+		err              string
+	}{
+		"success": {
+			refreshTokenRepo: &tmock.RefreshTokenRepository{},
+			revokedTokenRepo: &tmock.RevokedTokenRepository{},
+			sessionRepo:      &tmock.SessionRepository{},
+			refreshTokenTTL:  time.Hour,
+		},
+		"missing refresh token repo": {
+			refreshTokenTTL: time.Hour,
+			err:             "refresh token repo is nil",
+		},
+		"missing revoked token repo": {
+			refreshTokenRepo: &tmock.RefreshTokenRepository{},
+			refreshTokenTTL:  time.Hour,
+			err:              "revoked token repo is nil",
+		},
+		"missing session repo": {
+			refreshTokenRepo: &tmock.RefreshTokenRepository{},
+			revokedTokenRepo: &tmock.RevokedTokenRepository{},
+			refreshTokenTTL:  time.Hour,
+			err:              "session repo is nil",
+		},
+		"invalid refresh token ttl": {
+			refreshTokenRepo: &tmock.RefreshTokenRepository{},
+			revokedTokenRepo: &tmock.RevokedTokenRepository{},
+			sessionRepo:      &tmock.SessionRepository{},
+			err:              "refresh token ttl should be greater than zero",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got, err := auth.NewService(tc.refreshTokenRepo, tc.revokedTokenRepo, tc.sessionRepo, tc.refreshTokenTTL)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				assert.Nil(t, got)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, got)
+			}
+		})
+	}
+}
+
+// TestRefreshSessionRotation covers the refresh tokens a session can not be
+// refreshed with
+func TestRefreshSessionRotation(t *testing.T) {
+	token1 := "k3S0zN9rT6xQ"
+	sum := sha256.Sum256([]byte(token1))
+	hash1 := hex.EncodeToString(sum[:])
+	usedAt := time.Now().Add(-time.Minute)
+
+	active := &auth.RefreshToken{
+		Hash:      hash1,
+		FamilyID:  "family1",
+		Email:     "dua@lipa.com",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	used := &auth.RefreshToken{
+		Hash:      hash1,
+		FamilyID:  "family1",
+		Email:     "dua@lipa.com",
+		ExpiresAt: time.Now().Add(time.Hour),
+		UsedAt:    &usedAt,
+	}
+
+	expired := &auth.RefreshToken{
+		Hash:      hash1,
+		FamilyID:  "family1",
+		Email:     "dua@lipa.com",
+		ExpiresAt: time.Now().Add(-time.Hour),
+	}
+
+	revoked := &auth.RefreshToken{
+		Hash:      hash1,
+		FamilyID:  "family1",
+		Email:     "dua@lipa.com",
+		ExpiresAt: time.Now().Add(time.Hour),
+		RevokedAt: &usedAt,
+	}
+
+	testCases := map[string]struct {
+		token                 string
+		refreshTokenRepoCalls []tmock.Call
+		expectedError         error
+	}{
+		"empty token should return error": {
+			expectedError: fmt.Errorf("refresh token should not be empty"),
+		},
+		"unknown token should return error": {
+			token: token1,
+			refreshTokenRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetRefreshToken",
+					Params:       []interface{}{hash1},
+					Returns:      []interface{}{(*auth.RefreshToken)(nil), nil},
+				},
+			},
+			expectedError: auth.ErrInvalidRefreshToken,
+		},
+		"expired token should return error": {
+			token: token1,
+			refreshTokenRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetRefreshToken",
+					Params:       []interface{}{hash1},
+					Returns:      []interface{}{expired, nil},
+				},
+			},
+			expectedError: auth.ErrInvalidRefreshToken,
+		},
+		"revoked token should return error": {
+			token: token1,
+			refreshTokenRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetRefreshToken",
+					Params:       []interface{}{hash1},
+					Returns:      []interface{}{revoked, nil},
+				},
+			},
+			expectedError: auth.ErrInvalidRefreshToken,
+		},
+		"reused token should revoke family": {
+			token: token1,
+			refreshTokenRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetRefreshToken",
+					Params:       []interface{}{hash1},
+					Returns:      []interface{}{used, nil},
+				},
+				{
+					FunctionName: "RevokeRefreshTokenFamily",
+					Params:       []interface{}{"family1", mock.Anything},
+					Returns:      []interface{}{nil},
+				},
+			},
+			expectedError: auth.ErrRefreshTokenReused,
+		},
+		"concurrent rotation should revoke family": {
+			token: token1,
+			refreshTokenRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetRefreshToken",
+					Params:       []interface{}{hash1},
+					Returns:      []interface{}{active, nil},
+				},
+				{
+					FunctionName: "MarkRefreshTokenUsed",
+					Params:       []interface{}{hash1, mock.Anything},
+					Returns:      []interface{}{false, nil},
+				},
+				{
+					FunctionName: "RevokeRefreshTokenFamily",
+					Params:       []interface{}{"family1", mock.Anything},
+					Returns:      []interface{}{nil},
+				},
+			},
+			expectedError: auth.ErrRefreshTokenReused,
+		},
+		"refresh token repo error should propagate": {
+			token: token1,
+			refreshTokenRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetRefreshToken",
+					Params:       []interface{}{hash1},
+					Returns:      []interface{}{(*auth.RefreshToken)(nil), fmt.Errorf("refresh token repo error")},
+				},
+			},
+			expectedError: fmt.Errorf("refresh token repo error"),
+		},
+	}
+
+	for name, tc := range testCases {
+		ctx := context.Background()
+		refreshTokenRepo := tmock.NewRefreshTokenRepository().AddCall(t, tc.refreshTokenRepoCalls)
+
+		authService, err := auth.NewService(refreshTokenRepo, tmock.NewRevokedTokenRepository(), tmock.NewSessionRepository(), time.Hour)
+		assert.NoError(t, err)
+
+		token := tc.token
+		expectedError := tc.expectedError
+
+		t.Run(name, func(t *testing.T) {
+			session, next, err := authService.RefreshSession(ctx, token, "curl/8.0", "203.0.113.7")
+			assert.Equal(t, expectedError, err)
+			assert.Nil(t, session)
+			assert.Empty(t, next)
+			refreshTokenRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRevokeRefreshToken(t *testing.T) {
+	token1 := "k3S0zN9rT6xQ"
+	sum := sha256.Sum256([]byte(token1))
+	hash1 := hex.EncodeToString(sum[:])
+
+	active := &auth.RefreshToken{
+		Hash:     hash1,
+		FamilyID: "family1",
+		Email:    "dua@lipa.com",
+	}
+
+	testCases := map[string]struct {
+		token                 string
+		refreshTokenRepoCalls []tmock.Call
+		expectedError         error
+	}{
+		"success": {
+			token: token1,
+			refreshTokenRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetRefreshToken",
+					Params:       []interface{}{hash1},
+					Returns:      []interface{}{active, nil},
+				},
+				{
+					FunctionName: "RevokeRefreshTokenFamily",
+					Params:       []interface{}{"family1", mock.Anything},
+					Returns:      []interface{}{nil},
+				},
+			},
+		},
+		"unknown token should be ignored": {
+			token: token1,
+			refreshTokenRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetRefreshToken",
+					Params:       []interface{}{hash1},
+					Returns:      []interface{}{(*auth.RefreshToken)(nil), nil},
+				},
+			},
+		},
+		"empty token should return error": {
+			expectedError: fmt.Errorf("refresh token should not be empty"),
+		},
+	}
+
+	for name, tc := range testCases {
+		ctx := context.Background()
+		refreshTokenRepo := tmock.NewRefreshTokenRepository().AddCall(t, tc.refreshTokenRepoCalls)
+
+		authService, err := auth.NewService(refreshTokenRepo, tmock.NewRevokedTokenRepository(), tmock.NewSessionRepository(), time.Hour)
+		assert.NoError(t, err)
+
+		token := tc.token
+		expectedError := tc.expectedError
+
+		t.Run(name, func(t *testing.T) {
+			err := authService.RevokeRefreshToken(ctx, token)
+			assert.Equal(t, expectedError, err)
+			refreshTokenRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRevokeToken(t *testing.T) {
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Hour)
+
+	revokedTokenRepo := tmock.NewRevokedTokenRepository().AddCall(t, []tmock.Call{
+		{
+			FunctionName: "SaveRevokedToken",
+			Params: []interface{}{
+				mock.MatchedBy(func(token auth.RevokedToken) bool {
+					return token.JTI == "jti1" && token.Email == "dua@lipa.com" && token.ExpiresAt.Equal(expiresAt)
+				}),
+			},
+			Returns: []interface{}{nil},
+		},
+		{
+			FunctionName: "IsTokenRevoked",
+			Params:       []interface{}{"jti1"},
+			Returns:      []interface{}{true, nil},
+		},
+	})
+
+	authService, err := auth.NewService(tmock.NewRefreshTokenRepository(), revokedTokenRepo, tmock.NewSessionRepository(), time.Hour)
+	assert.NoError(t, err)
+
+	err = authService.RevokeToken(ctx, "jti1", "dua@lipa.com", expiresAt)
+	assert.NoError(t, err)
+
+	revoked, err := authService.IsTokenRevoked(ctx, "jti1")
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	err = authService.RevokeToken(ctx, "", "dua@lipa.com", expiresAt)
+	assert.EqualError(t, err, "token id should not be empty")
+	revokedTokenRepo.AssertExpectations(t)
+}
@@ -0,0 +1,182 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/alamrios/crabi-solution/internal/app/opaque"
+)
+
+// ErrSessionNotFound returned when session is unknown or belongs to another user
+var ErrSessionNotFound = fmt.Errorf("session not found")
+
+// sessionTouchInterval minimum time between last seen updates of a session
+const sessionTouchInterval = time.Minute
+
+// Session struct for a login of a user on a device, its ID is the family ID
+// of the refresh tokens issued to that login
+type Session struct {
+	ID         string    `bson:"_id"`
+	Email      string    `bson:"email"`
+	UserAgent  string    `bson:"user_agent"`
+	IP         string    `bson:"ip"`
+	CreatedAt  time.Time `bson:"created_at"`
+	LastSeenAt time.Time `bson:"last_seen_at"`
+	ExpiresAt  time.Time `bson:"expires_at"`
+}
+
+// CreateSession records a new login session of given email and starts its
+// refresh token family, returns the session and its first refresh token
+func (s *Service) CreateSession(ctx context.Context, email, userAgent, ip string) (*Session, string, error) {
+	if email == "" {
+		return nil, "", fmt.Errorf("user's email should not be empty")
+	}
+
+	id, err := opaque.NewToken(16)
+	if err != nil {
+		return nil, "", err
+	}
+
+	session, err := s.saveSession(ctx, id, email, userAgent, ip)
+	if err != nil {
+		return nil, "", err
+	}
+
+	token, err := s.issueRefreshToken(ctx, email, session.ID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return session, token, nil
+}
+
+// RefreshSession rotates given refresh token and extends its session,
+// returns the session and the new refresh token. Families issued before
+// sessions were recorded get a session on their first rotation.
+func (s *Service) RefreshSession(ctx context.Context, token, userAgent, ip string) (*Session, string, error) {
+	current, next, err := s.rotateRefreshToken(ctx, token)
+	if err != nil {
+		return nil, "", err
+	}
+
+	session, err := s.sessionRepo.GetSession(ctx, current.FamilyID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if session == nil {
+		session, err = s.saveSession(ctx, current.FamilyID, current.Email, userAgent, ip)
+		if err != nil {
+			return nil, "", err
+		}
+
+		return session, next, nil
+	}
+
+	now := time.Now().UTC()
+	session.LastSeenAt = now
+	session.ExpiresAt = now.Add(s.refreshTokenTTL)
+
+	err = s.sessionRepo.ExtendSession(ctx, session.ID, session.LastSeenAt, session.ExpiresAt)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return session, next, nil
+}
+
+// GetSession returns session with given id, nil if it does not exist
+func (s *Service) GetSession(ctx context.Context, id string) (*Session, error) {
+	if id == "" {
+		return nil, fmt.Errorf("session id should not be empty")
+	}
+
+	return s.sessionRepo.GetSession(ctx, id)
+}
+
+// ListSessions returns active sessions of given email
+func (s *Service) ListSessions(ctx context.Context, email string) ([]Session, error) {
+	if email == "" {
+		return nil, fmt.Errorf("user's email should not be empty")
+	}
+
+	return s.sessionRepo.ListSessions(ctx, email)
+}
+
+// TouchSession records activity on given session, updates are throttled and
+// failures are logged since they must not reject the request
+func (s *Service) TouchSession(ctx context.Context, session *Session) {
+	now := time.Now().UTC()
+	if now.Sub(session.LastSeenAt) < sessionTouchInterval {
+		return
+	}
+
+	err := s.sessionRepo.UpdateSessionLastSeen(ctx, session.ID, now)
+	if err != nil {
+		log.Printf("failed to update last seen of session %s: %v", session.ID, err)
+		return
+	}
+
+	session.LastSeenAt = now
+}
+
+// DeleteSession ends session with given id of given email and revokes its
+// refresh tokens, access tokens of the session are rejected afterwards
+func (s *Service) DeleteSession(ctx context.Context, email, id string) error {
+	if email == "" {
+		return fmt.Errorf("user's email should not be empty")
+	}
+	if id == "" {
+		return fmt.Errorf("session id should not be empty")
+	}
+
+	session, err := s.sessionRepo.GetSession(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if session == nil || session.Email != email {
+		return ErrSessionNotFound
+	}
+
+	err = s.refreshTokenRepo.RevokeRefreshTokenFamily(ctx, session.ID, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	return s.sessionRepo.DeleteSession(ctx, session.ID)
+}
+
+// DeleteSessions ends every session of given email and revokes every
+// refresh token issued to it
+func (s *Service) DeleteSessions(ctx context.Context, email string) error {
+	err := s.RevokeRefreshTokens(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	return s.sessionRepo.DeleteSessionsByEmail(ctx, email)
+}
+
+func (s *Service) saveSession(ctx context.Context, id, email, userAgent, ip string) (*Session, error) {
+	now := time.Now().UTC()
+
+	session := Session{
+		ID:         id,
+		Email:      email,
+		UserAgent:  userAgent,
+		IP:         ip,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(s.refreshTokenTTL),
+	}
+
+	err := s.sessionRepo.SaveSession(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
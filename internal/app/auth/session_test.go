@@ -0,0 +1,225 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/alamrios/crabi-solution/internal/app/auth"
+	tmock "github.com/alamrios/crabi-solution/test/mock"
+)
+
+func TestCreateSession(t *testing.T) {
+	ctx := context.Background()
+
+	var sessionID string
+	sessionRepo := tmock.NewSessionRepository().AddCall(t, []tmock.Call{
+		{
+			FunctionName: "SaveSession",
+			Params: []interface{}{
+				mock.MatchedBy(func(session auth.Session) bool {
+					sessionID = session.ID
+					return session.ID != "" && session.Email == "dua@lipa.com" &&
+						session.UserAgent == "curl/8.0" && session.IP == "203.0.113.7"
+				}),
+			},
+			Returns: []interface{}{nil},
+		},
+	})
+	refreshTokenRepo := tmock.NewRefreshTokenRepository().AddCall(t, []tmock.Call{
+		{
+			FunctionName: "SaveRefreshToken",
+			Params: []interface{}{
+				mock.MatchedBy(func(token auth.RefreshToken) bool {
+					return token.FamilyID == sessionID
+				}),
+			},
+			Returns: []interface{}{nil},
+		},
+	})
+
+	authService, err := auth.NewService(refreshTokenRepo, tmock.NewRevokedTokenRepository(), sessionRepo, time.Hour)
+	assert.NoError(t, err)
+
+	session, token, err := authService.CreateSession(ctx, "dua@lipa.com", "curl/8.0", "203.0.113.7")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token)
+	assert.Equal(t, sessionID, session.ID)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), session.ExpiresAt, time.Minute)
+	sessionRepo.AssertExpectations(t)
+	refreshTokenRepo.AssertExpectations(t)
+}
+
+func TestRefreshSession(t *testing.T) {
+	token1 := "k3S0zN9rT6xQ"
+	sum := sha256.Sum256([]byte(token1))
+	hash1 := hex.EncodeToString(sum[:])
+
+	active := &auth.RefreshToken{
+		Hash:      hash1,
+		FamilyID:  "session1",
+		Email:     "dua@lipa.com",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	session1 := &auth.Session{
+		ID:         "session1",
+		Email:      "dua@lipa.com",
+		LastSeenAt: time.Now().Add(-time.Hour),
+		ExpiresAt:  time.Now().Add(time.Minute),
+	}
+
+	testCases := map[string]struct {
+		sessionRepoCalls []tmock.Call
+	}{
+		"existing session should be extended": {
+			sessionRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetSession",
+					Params:       []interface{}{"session1"},
+					Returns:      []interface{}{session1, nil},
+				},
+				{
+					FunctionName: "ExtendSession",
+					Params:       []interface{}{"session1", mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time")},
+					Returns:      []interface{}{nil},
+				},
+			},
+		},
+		"family without session should get one": {
+			sessionRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetSession",
+					Params:       []interface{}{"session1"},
+					Returns:      []interface{}{(*auth.Session)(nil), nil},
+				},
+				{
+					FunctionName: "SaveSession",
+					Params: []interface{}{
+						mock.MatchedBy(func(session auth.Session) bool {
+							return session.ID == "session1" && session.Email == "dua@lipa.com"
+						}),
+					},
+					Returns: []interface{}{nil},
+				},
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		ctx := context.Background()
+		refreshTokenRepo := tmock.NewRefreshTokenRepository().AddCall(t, []tmock.Call{
+			{
+				FunctionName: "GetRefreshToken",
+				Params:       []interface{}{hash1},
+				Returns:      []interface{}{active, nil},
+			},
+			{
+				FunctionName: "MarkRefreshTokenUsed",
+				Params:       []interface{}{hash1, mock.Anything},
+				Returns:      []interface{}{true, nil},
+			},
+			{
+				FunctionName: "SaveRefreshToken",
+				Params:       []interface{}{mock.Anything},
+				Returns:      []interface{}{nil},
+			},
+		})
+		sessionRepo := tmock.NewSessionRepository().AddCall(t, tc.sessionRepoCalls)
+
+		authService, err := auth.NewService(refreshTokenRepo, tmock.NewRevokedTokenRepository(), sessionRepo, time.Hour)
+		assert.NoError(t, err)
+
+		t.Run(name, func(t *testing.T) {
+			session, next, err := authService.RefreshSession(ctx, token1, "curl/8.0", "203.0.113.7")
+			assert.NoError(t, err)
+			assert.NotEmpty(t, next)
+			assert.Equal(t, "session1", session.ID)
+			assert.WithinDuration(t, time.Now().Add(time.Hour), session.ExpiresAt, time.Minute)
+			sessionRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDeleteSession(t *testing.T) {
+	session1 := &auth.Session{
+		ID:    "session1",
+		Email: "dua@lipa.com",
+	}
+
+	testCases := map[string]struct {
+		email                 string
+		sessionRepoCalls      []tmock.Call
+		refreshTokenRepoCalls []tmock.Call
+		expectedError         error
+	}{
+		"success should revoke refresh tokens": {
+			email: "dua@lipa.com",
+			sessionRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetSession",
+					Params:       []interface{}{"session1"},
+					Returns:      []interface{}{session1, nil},
+				},
+				{
+					FunctionName: "DeleteSession",
+					Params:       []interface{}{"session1"},
+					Returns:      []interface{}{nil},
+				},
+			},
+			refreshTokenRepoCalls: []tmock.Call{
+				{
+					FunctionName: "RevokeRefreshTokenFamily",
+					Params:       []interface{}{"session1", mock.AnythingOfType("time.Time")},
+					Returns:      []interface{}{nil},
+				},
+			},
+		},
+		"session of another user should not be found": {
+			email: "joaquin@guzman.com",
+			sessionRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetSession",
+					Params:       []interface{}{"session1"},
+					Returns:      []interface{}{session1, nil},
+				},
+			},
+			expectedError: auth.ErrSessionNotFound,
+		},
+		"unknown session should not be found": {
+			email: "dua@lipa.com",
+			sessionRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetSession",
+					Params:       []interface{}{"session1"},
+					Returns:      []interface{}{(*auth.Session)(nil), nil},
+				},
+			},
+			expectedError: auth.ErrSessionNotFound,
+		},
+	}
+
+	for name, tc := range testCases {
+		ctx := context.Background()
+		refreshTokenRepo := tmock.NewRefreshTokenRepository().AddCall(t, tc.refreshTokenRepoCalls)
+		sessionRepo := tmock.NewSessionRepository().AddCall(t, tc.sessionRepoCalls)
+
+		authService, err := auth.NewService(refreshTokenRepo, tmock.NewRevokedTokenRepository(), sessionRepo, time.Hour)
+		assert.NoError(t, err)
+
+		email := tc.email
+		expectedError := tc.expectedError
+
+		t.Run(name, func(t *testing.T) {
+			err := authService.DeleteSession(ctx, email, "session1")
+			assert.Equal(t, expectedError, err)
+			sessionRepo.AssertExpectations(t)
+			refreshTokenRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package opaque_test
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/alamrios/crabi-solution/internal/app/opaque"
+)
+
+func TestNewToken(t *testing.T) {
+	token1, err := opaque.NewToken(32)
+	assert.NoError(t, err)
+
+	token2, err := opaque.NewToken(32)
+	assert.NoError(t, err)
+
+	assert.NotEqual(t, token1, token2)
+
+	decoded, err := base64.RawURLEncoding.DecodeString(token1)
+	assert.NoError(t, err)
+	assert.Len(t, decoded, 32)
+}
+
+func TestHash(t *testing.T) {
+	assert.Equal(t, "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae", opaque.Hash("foo"))
+	assert.NotEqual(t, opaque.Hash("foo"), opaque.Hash("bar"))
+}
@@ -0,0 +1,259 @@
+package pld
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// State of a circuit breaker
+type State string
+
+const (
+	// StateClosed calls reach PLD service
+	StateClosed State = "closed"
+	// StateOpen calls are rejected without reaching PLD service
+	StateOpen State = "open"
+	// StateHalfOpen a few probe calls reach PLD service to decide whether
+	// it recovered
+	StateHalfOpen State = "half_open"
+)
+
+// OpenPolicy handling of registrations while the circuit is open
+type OpenPolicy string
+
+const (
+	// OpenPolicyReject registrations fail with ErrUnavailable
+	OpenPolicyReject OpenPolicy = "reject"
+	// OpenPolicyPendingScreening registrations continue with
+	// ErrScreeningDeferred and users are screened later
+	OpenPolicyPendingScreening OpenPolicy = "pending_screening"
+)
+
+// BreakerPolicy struct for circuit breaker thresholds
+type BreakerPolicy struct {
+	// WindowSize latest calls the failure rate is computed from
+	WindowSize int
+	// MinRequests calls in the window needed before the circuit can open
+	MinRequests int
+	// FailureThreshold percentage of failed calls that opens the circuit
+	FailureThreshold int
+	// OpenDuration time calls are rejected before probing the service again
+	OpenDuration time.Duration
+	// HalfOpenRequests successful probes needed to close the circuit
+	HalfOpenRequests int
+	OpenPolicy       OpenPolicy
+}
+
+// BreakerStats struct for circuit breaker metrics
+type BreakerStats struct {
+	State State `json:"state"`
+	// Transitions number of changes into each state
+	Transitions map[State]int64 `json:"transitions"`
+	Successes   int64           `json:"successes"`
+	Failures    int64           `json:"failures"`
+	// Rejected calls that did not reach PLD service
+	Rejected int64 `json:"rejected"`
+}
+
+// Breaker circuit breaker wrapping a PLD service, it stops calling the
+// service when too many recent calls failed and probes it again after a
+// while. Screenings are successful calls whatever their decision.
+type Breaker struct {
+	service Service
+	policy  BreakerPolicy
+	now     func() time.Time
+
+	mu sync.Mutex
+	// generation changes on every state change so late results of calls made
+	// in a previous state are ignored
+	generation int64
+	state      State
+	openedAt   time.Time
+	// window ring of latest call results, true for failures
+	window   []bool
+	next     int
+	count    int
+	failures int
+	// probes calls admitted in half open state, successes of them
+	probes    int
+	successes int
+	stats     BreakerStats
+}
+
+// NewBreaker returns a closed circuit breaker around given PLD service
+func NewBreaker(service Service, policy BreakerPolicy) (*Breaker, error) {
+	if service == nil {
+		return nil, fmt.Errorf("pld service is nil")
+	}
+	if policy.WindowSize <= 0 {
+		return nil, fmt.Errorf("breaker window size should be greater than zero")
+	}
+	if policy.MinRequests <= 0 || policy.MinRequests > policy.WindowSize {
+		return nil, fmt.Errorf("breaker min requests should be between 1 and window size")
+	}
+	if policy.FailureThreshold <= 0 || policy.FailureThreshold > 100 {
+		return nil, fmt.Errorf("breaker failure threshold should be between 1 and 100")
+	}
+	if policy.OpenDuration <= 0 {
+		return nil, fmt.Errorf("breaker open duration should be greater than zero")
+	}
+	if policy.HalfOpenRequests <= 0 {
+		return nil, fmt.Errorf("breaker half open requests should be greater than zero")
+	}
+	if policy.OpenPolicy != OpenPolicyReject && policy.OpenPolicy != OpenPolicyPendingScreening {
+		return nil, fmt.Errorf("breaker open policy should be reject or pending_screening")
+	}
+
+	return &Breaker{
+		service: service,
+		policy:  policy,
+		now:     time.Now,
+		state:   StateClosed,
+		window:  make([]bool, policy.WindowSize),
+		stats: BreakerStats{
+			Transitions: map[State]int64{},
+		},
+	}, nil
+}
+
+// CheckBlacklist calls PLD service unless the circuit is open, in which case
+// it returns ErrUnavailable or ErrScreeningDeferred depending on the policy
+func (b *Breaker) CheckBlacklist(ctx context.Context, request Request) (*ScreeningResult, error) {
+	generation, ok := b.admit()
+	if !ok {
+		if b.policy.OpenPolicy == OpenPolicyPendingScreening {
+			return nil, ErrScreeningDeferred
+		}
+		return nil, ErrUnavailable
+	}
+
+	result, err := b.service.CheckBlacklist(ctx, request)
+	switch {
+	case err == nil:
+		b.record(generation, false)
+	case errors.Is(err, context.Canceled), errors.Is(err, ErrScreeningNotRecorded):
+		// canceled by the caller or not audited, says nothing about the
+		// service
+		b.release(generation)
+	default:
+		b.record(generation, true)
+	}
+
+	return result, err
+}
+
+// Stats returns a snapshot of circuit breaker metrics
+func (b *Breaker) Stats() BreakerStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stats := b.stats
+	stats.State = b.state
+	stats.Transitions = make(map[State]int64, len(b.stats.Transitions))
+	for state, count := range b.stats.Transitions {
+		stats.Transitions[state] = count
+	}
+
+	return stats
+}
+
+// admit returns whether a call may reach PLD service and the generation it
+// belongs to
+func (b *Breaker) admit() (int64, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.policy.OpenDuration {
+		b.setState(StateHalfOpen)
+	}
+
+	switch b.state {
+	case StateOpen:
+		b.stats.Rejected++
+		return b.generation, false
+	case StateHalfOpen:
+		if b.probes >= b.policy.HalfOpenRequests {
+			b.stats.Rejected++
+			return b.generation, false
+		}
+		b.probes++
+	}
+
+	return b.generation, true
+}
+
+// record stores result of a call admitted in given generation
+func (b *Breaker) record(generation int64, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if failed {
+		b.stats.Failures++
+	} else {
+		b.stats.Successes++
+	}
+
+	if generation != b.generation {
+		return
+	}
+
+	switch b.state {
+	case StateHalfOpen:
+		if failed {
+			b.setState(StateOpen)
+			return
+		}
+		b.successes++
+		if b.successes >= b.policy.HalfOpenRequests {
+			b.setState(StateClosed)
+		}
+	case StateClosed:
+		if b.count == len(b.window) {
+			if b.window[b.next] {
+				b.failures--
+			}
+		} else {
+			b.count++
+		}
+		b.window[b.next] = failed
+		b.next = (b.next + 1) % len(b.window)
+		if failed {
+			b.failures++
+		}
+
+		if b.count >= b.policy.MinRequests && b.failures*100 >= b.count*b.policy.FailureThreshold {
+			b.setState(StateOpen)
+		}
+	}
+}
+
+// release frees the probe slot of a call admitted in given generation whose
+// result is ignored
+func (b *Breaker) release(generation int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if generation == b.generation && b.state == StateHalfOpen {
+		b.probes--
+	}
+}
+
+// setState moves the circuit to given state and starts a new generation,
+// must be called holding the lock
+func (b *Breaker) setState(state State) {
+	log.Printf("pld circuit breaker changed from %s to %s", b.state, state)
+
+	b.state = state
+	b.generation++
+	b.stats.Transitions[state]++
+
+	b.next, b.count, b.failures = 0, 0, 0
+	b.probes, b.successes = 0, 0
+	if state == StateOpen {
+		b.openedAt = b.now()
+	}
+}
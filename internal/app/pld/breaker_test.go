@@ -0,0 +1,216 @@
+package pld
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeService PLD service returning queued errors, clear results for nil
+// errors and once the queue is empty
+type fakeService struct {
+	errs  []error
+	calls int
+}
+
+func (f *fakeService) CheckBlacklist(_ context.Context, _ Request) (*ScreeningResult, error) {
+	f.calls++
+
+	var err error
+	if len(f.errs) > 0 {
+		err = f.errs[0]
+		f.errs = f.errs[1:]
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &ScreeningResult{Decision: DecisionClear}, nil
+}
+
+func TestNewBreaker(t *testing.T) {
+	policy := BreakerPolicy{
+		WindowSize:       4,
+		MinRequests:      2,
+		FailureThreshold: 50,
+		OpenDuration:     time.Second,
+		HalfOpenRequests: 1,
+		OpenPolicy:       OpenPolicyReject,
+	}
+
+	testCases := map[string]struct {
+		service Service
+		policy  func(p BreakerPolicy) BreakerPolicy
+		err     string
+	}{
+		"success": {
+			service: &fakeService{},
+			policy:  func(p BreakerPolicy) BreakerPolicy { return p },
+		},
+		"nil service should return error": {
+			policy: func(p BreakerPolicy) BreakerPolicy { return p },
+			err:    "pld service is nil",
+		},
+		"min requests over window size should return error": {
+			service: &fakeService{},
+			policy: func(p BreakerPolicy) BreakerPolicy {
+				p.MinRequests = 5
+				return p
+			},
+			err: "breaker min requests should be between 1 and window size",
+		},
+		"failure threshold over 100 should return error": {
+			service: &fakeService{},
+			policy: func(p BreakerPolicy) BreakerPolicy {
+				p.FailureThreshold = 101
+				return p
+			},
+			err: "breaker failure threshold should be between 1 and 100",
+		},
+		"unknown open policy should return error": {
+			service: &fakeService{},
+			policy: func(p BreakerPolicy) BreakerPolicy {
+				p.OpenPolicy = "ignore"
+				return p
+			},
+			err: "breaker open policy should be reject or pending_screening",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got, err := NewBreaker(tc.service, tc.policy(policy))
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				assert.Nil(t, got)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, got)
+			}
+		})
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	ctx := context.Background()
+	request := Request{FirstName: "Dua", LastName: "Lipa", Email: "dua@lipa.com"}
+	serverErr := &ProviderError{StatusCode: 503}
+
+	check := func(breaker *Breaker) error {
+		_, err := breaker.CheckBlacklist(ctx, request)
+		return err
+	}
+
+	policy := BreakerPolicy{
+		WindowSize:       4,
+		MinRequests:      2,
+		FailureThreshold: 50,
+		OpenDuration:     time.Minute,
+		HalfOpenRequests: 2,
+		OpenPolicy:       OpenPolicyReject,
+	}
+
+	newBreaker := func(t *testing.T, service Service, policy BreakerPolicy) (*Breaker, *time.Time) {
+		breaker, err := NewBreaker(service, policy)
+		assert.NoError(t, err)
+
+		now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+		breaker.now = func() time.Time { return now }
+
+		return breaker, &now
+	}
+
+	t.Run("failure rate over threshold should open the circuit", func(t *testing.T) {
+		service := &fakeService{errs: []error{nil, nil, serverErr, serverErr}}
+		breaker, _ := newBreaker(t, service, policy)
+
+		assert.NoError(t, check(breaker))
+		assert.NoError(t, check(breaker))
+		assert.Equal(t, serverErr, check(breaker))
+		assert.Equal(t, StateClosed, breaker.Stats().State)
+
+		assert.Equal(t, serverErr, check(breaker))
+		assert.Equal(t, StateOpen, breaker.Stats().State)
+
+		assert.Equal(t, ErrUnavailable, check(breaker))
+		assert.Equal(t, 4, service.calls)
+
+		stats := breaker.Stats()
+		assert.Equal(t, int64(2), stats.Successes)
+		assert.Equal(t, int64(2), stats.Failures)
+		assert.Equal(t, int64(1), stats.Rejected)
+		assert.Equal(t, int64(1), stats.Transitions[StateOpen])
+	})
+
+	t.Run("pending screening policy should defer screening", func(t *testing.T) {
+		pendingPolicy := policy
+		pendingPolicy.OpenPolicy = OpenPolicyPendingScreening
+
+		service := &fakeService{errs: []error{serverErr, serverErr}}
+		breaker, _ := newBreaker(t, service, pendingPolicy)
+
+		check(breaker)
+		check(breaker)
+
+		assert.Equal(t, ErrScreeningDeferred, check(breaker))
+		assert.Equal(t, 2, service.calls)
+	})
+
+	t.Run("successful probes should close the circuit", func(t *testing.T) {
+		service := &fakeService{errs: []error{serverErr, serverErr}}
+		breaker, now := newBreaker(t, service, policy)
+
+		check(breaker)
+		check(breaker)
+		assert.Equal(t, StateOpen, breaker.Stats().State)
+
+		*now = now.Add(policy.OpenDuration)
+
+		assert.NoError(t, check(breaker))
+		assert.Equal(t, StateHalfOpen, breaker.Stats().State)
+
+		assert.NoError(t, check(breaker))
+		assert.Equal(t, StateClosed, breaker.Stats().State)
+	})
+
+	t.Run("failed probe should open the circuit again", func(t *testing.T) {
+		service := &fakeService{errs: []error{serverErr, serverErr, serverErr}}
+		breaker, now := newBreaker(t, service, policy)
+
+		check(breaker)
+		check(breaker)
+
+		*now = now.Add(policy.OpenDuration)
+
+		assert.Equal(t, serverErr, check(breaker))
+		assert.Equal(t, StateOpen, breaker.Stats().State)
+		assert.Equal(t, ErrUnavailable, check(breaker))
+		assert.Equal(t, int64(2), breaker.Stats().Transitions[StateOpen])
+	})
+
+	t.Run("canceled calls should not count", func(t *testing.T) {
+		service := &fakeService{errs: []error{context.Canceled, context.Canceled, context.Canceled}}
+		breaker, _ := newBreaker(t, service, policy)
+
+		check(breaker)
+		check(breaker)
+		check(breaker)
+
+		assert.Equal(t, StateClosed, breaker.Stats().State)
+		assert.Equal(t, int64(0), breaker.Stats().Failures)
+	})
+
+	t.Run("unrecorded screenings should not count", func(t *testing.T) {
+		service := &fakeService{errs: []error{ErrScreeningNotRecorded, ErrScreeningNotRecorded, ErrScreeningNotRecorded}}
+		breaker, _ := newBreaker(t, service, policy)
+
+		check(breaker)
+		check(breaker)
+		check(breaker)
+
+		assert.Equal(t, StateClosed, breaker.Stats().State)
+		assert.Equal(t, int64(0), breaker.Stats().Failures)
+	})
+}
@@ -0,0 +1,76 @@
+package pld
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Screening struct for an audited attempt to screen a user at the PLD
+// provider, failed attempts are recorded too
+type Screening struct {
+	UserID   string `bson:"user_id"`
+	Provider string `bson:"provider"`
+	// Request snapshot of the screened user data
+	Request Request `bson:"request"`
+	// StatusCode http status code of the provider response, zero when no
+	// response was received
+	StatusCode int `bson:"status_code,omitempty"`
+	// ResponseBody raw provider response
+	ResponseBody string        `bson:"response_body,omitempty"`
+	Latency      time.Duration `bson:"latency"`
+	// Decision empty when the attempt failed
+	Decision    Decision `bson:"decision,omitempty"`
+	Lists       []string `bson:"lists,omitempty"`
+	Score       float64  `bson:"score,omitempty"`
+	ReferenceID string   `bson:"reference_id,omitempty"`
+	// Error failure of the attempt, empty when it succeeded
+	Error     string    `bson:"error,omitempty"`
+	CheckedAt time.Time `bson:"checked_at"`
+}
+
+// ScreeningRepository contract for PLD screenings audit trail
+type ScreeningRepository interface {
+	SaveScreening(ctx context.Context, screening Screening) error
+	// ListScreenings returns screenings of user with given id, newest first
+	ListScreenings(ctx context.Context, userID string) ([]Screening, error)
+	// ListScreeningsByEmail returns screenings of given email, newest first
+	ListScreeningsByEmail(ctx context.Context, email string) ([]Screening, error)
+}
+
+// AuditService struct for PLD screenings audit trail
+type AuditService struct {
+	screeningRepo ScreeningRepository
+}
+
+// NewAuditService returns an instance of PLD screenings audit service
+func NewAuditService(screeningRepo ScreeningRepository) (*AuditService, error) {
+	if screeningRepo == nil {
+		return nil, fmt.Errorf("screening repo is nil")
+	}
+
+	return &AuditService{
+		screeningRepo: screeningRepo,
+	}, nil
+}
+
+// ListScreenings returns every screening attempt of user with given id,
+// newest first
+func (s *AuditService) ListScreenings(ctx context.Context, userID string) ([]Screening, error) {
+	if userID == "" {
+		return nil, fmt.Errorf("user's id should not be empty")
+	}
+
+	return s.screeningRepo.ListScreenings(ctx, userID)
+}
+
+// ListScreeningsByEmail returns every screening attempt of given email,
+// newest first. Applicants rejected at registration are not stored as users
+// so their screenings are found by the email they registered with.
+func (s *AuditService) ListScreeningsByEmail(ctx context.Context, email string) ([]Screening, error) {
+	if email == "" {
+		return nil, fmt.Errorf("user's email should not be empty")
+	}
+
+	return s.screeningRepo.ListScreeningsByEmail(ctx, email)
+}
@@ -0,0 +1,111 @@
+package pld_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/alamrios/crabi-solution/internal/app/pld"
+	tmock "github.com/alamrios/crabi-solution/test/mock"
+)
+
+func TestNewAuditService(t *testing.T) {
+	got, err := pld.NewAuditService(nil)
+	assert.EqualError(t, err, "screening repo is nil")
+	assert.Nil(t, got)
+}
+
+func TestListScreenings(t *testing.T) {
+	ctx := context.Background()
+	screenings := []pld.Screening{
+		{UserID: "user1", Provider: "crabi-pld", Decision: pld.DecisionClear},
+	}
+
+	testCases := map[string]struct {
+		userID        string
+		calls         []tmock.Call
+		expected      []pld.Screening
+		expectedError error
+	}{
+		"success": {
+			userID: "user1",
+			calls: []tmock.Call{
+				{
+					FunctionName: "ListScreenings",
+					Params:       []interface{}{"user1"},
+					Returns:      []interface{}{screenings, nil},
+				},
+			},
+			expected: screenings,
+		},
+		"empty user id should return error": {
+			expectedError: fmt.Errorf("user's id should not be empty"),
+		},
+		"repository error should propagate": {
+			userID: "user1",
+			calls: []tmock.Call{
+				{
+					FunctionName: "ListScreenings",
+					Params:       []interface{}{"user1"},
+					Returns:      []interface{}{([]pld.Screening)(nil), fmt.Errorf("db error")},
+				},
+			},
+			expectedError: fmt.Errorf("db error"),
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			screeningRepo := tmock.NewScreeningRepository().AddCall(t, tc.calls)
+			service, err := pld.NewAuditService(screeningRepo)
+			assert.NoError(t, err)
+
+			got, err := service.ListScreenings(ctx, tc.userID)
+			assert.Equal(t, tc.expectedError, err)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestListScreeningsByEmail(t *testing.T) {
+	ctx := context.Background()
+	screenings := []pld.Screening{
+		{UserID: "user1", Provider: "crabi-pld", Request: pld.Request{Email: "dua@lipa.com"}, Decision: pld.DecisionHit},
+	}
+
+	testCases := map[string]struct {
+		email         string
+		calls         []tmock.Call
+		expected      []pld.Screening
+		expectedError error
+	}{
+		"success": {
+			email: "dua@lipa.com",
+			calls: []tmock.Call{
+				{
+					FunctionName: "ListScreeningsByEmail",
+					Params:       []interface{}{"dua@lipa.com"},
+					Returns:      []interface{}{screenings, nil},
+				},
+			},
+			expected: screenings,
+		},
+		"empty email should return error": {
+			expectedError: fmt.Errorf("user's email should not be empty"),
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			screeningRepo := tmock.NewScreeningRepository().AddCall(t, tc.calls)
+			service, err := pld.NewAuditService(screeningRepo)
+			assert.NoError(t, err)
+
+			got, err := service.ListScreeningsByEmail(ctx, tc.email)
+			assert.Equal(t, tc.expectedError, err)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
@@ -2,16 +2,82 @@ package pld
 
 import (
 	"context"
+	"fmt"
+	"time"
 )
 
+// ErrUnavailable returned without calling PLD service while it is failing
+var ErrUnavailable = fmt.Errorf("pld service is unavailable")
+
+// ErrScreeningDeferred returned without calling PLD service while it is
+// failing, when users may be registered and screened later
+var ErrScreeningDeferred = fmt.Errorf("pld screening was deferred")
+
+// ErrScreeningNotRecorded returned when a screening attempt could not be
+// stored in the audit trail, the check is not trusted without it
+var ErrScreeningNotRecorded = fmt.Errorf("pld screening could not be recorded")
+
+// ErrInvalidResponse returned when PLD server response can not be read
+var ErrInvalidResponse = fmt.Errorf("pld server returned invalid response")
+
+// ProviderError returned when PLD server answers with a failure status code
+type ProviderError struct {
+	StatusCode int
+}
+
+// Error returns provider error message with its status code
+func (e *ProviderError) Error() string {
+	return fmt.Sprintf("pld server returned %d status code", e.StatusCode)
+}
+
+// Decision outcome of a PLD screening
+type Decision string
+
+const (
+	// DecisionClear user was not found in any blacklist
+	DecisionClear Decision = "clear"
+	// DecisionHit user was found in a blacklist
+	DecisionHit Decision = "hit"
+	// DecisionReview user partially matched a blacklist and needs a manual
+	// review
+	DecisionReview Decision = "review"
+)
+
+// Valid reports whether decision is known
+func (d Decision) Valid() bool {
+	switch d {
+	case DecisionClear, DecisionHit, DecisionReview:
+		return true
+	default:
+		return false
+	}
+}
+
 // Request struct for PLD service
 type Request struct {
-	FirstName string
-	LastName  string
-	Email     string
+	// UserID user the screening is recorded for, it is neither sent to PLD
+	// nor stored in the request snapshot
+	UserID    string `bson:"-"`
+	FirstName string `bson:"first_name"`
+	LastName  string `bson:"last_name"`
+	Email     string `bson:"email"`
+}
+
+// ScreeningResult struct for the outcome of a PLD blacklist check
+type ScreeningResult struct {
+	Decision Decision
+	// Lists names of the blacklists the user matched
+	Lists []string
+	// Score confidence of the match reported by the provider, zero when clear
+	Score float64
+	// ReferenceID identifier of the screening at the provider
+	ReferenceID string
+	CheckedAt   time.Time
 }
 
 // Service contract for PLD service
 type Service interface {
-	CheckBlacklist(ctx context.Context, request Request) error
+	// CheckBlacklist screens given user against PLD blacklists, errors are
+	// returned only when the screening could not be made
+	CheckBlacklist(ctx context.Context, request Request) (*ScreeningResult, error)
 }
@@ -0,0 +1,74 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// BcryptHasher hashes passwords with bcrypt, encoded hashes carry
+// algorithm version, cost and salt: $2a$<cost>$<salt><hash>
+type BcryptHasher struct {
+	cost int
+}
+
+// NewBcryptHasher returns an instance of bcrypt hasher
+func NewBcryptHasher(cost int) (*BcryptHasher, error) {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("bcrypt cost should be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+
+	return &BcryptHasher{
+		cost: cost,
+	}, nil
+}
+
+// Hash returns bcrypt encoded hash for given password
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// Verify reports whether given password matches encoded hash
+func (h *BcryptHasher) Verify(password, encoded string) (bool, error) {
+	if !h.Supports(encoded) {
+		return false, ErrUnsupportedHash
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Supports reports whether encoded hash was produced by bcrypt
+func (h *BcryptHasher) Supports(encoded string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(encoded, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NeedsRehash reports whether encoded hash cost differs from current one
+func (h *BcryptHasher) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	if err != nil {
+		return true
+	}
+
+	return cost != h.cost
+}
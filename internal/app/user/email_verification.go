@@ -0,0 +1,146 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alamrios/crabi-solution/internal/app/opaque"
+)
+
+// ErrInvalidVerificationToken returned when verification token is unknown, expired or already used
+var ErrInvalidVerificationToken = fmt.Errorf("invalid or expired email verification token")
+
+// EmailVerificationToken struct, only the hash of the opaque token is stored
+type EmailVerificationToken struct {
+	Hash      string     `bson:"_id"`
+	Email     string     `bson:"email"`
+	CreatedAt time.Time  `bson:"created_at"`
+	ExpiresAt time.Time  `bson:"expires_at"`
+	UsedAt    *time.Time `bson:"used_at,omitempty"`
+}
+
+// EmailVerifier contract to send verification links to new users
+type EmailVerifier interface {
+	SendVerification(ctx context.Context, email string) error
+}
+
+// EmailVerificationService struct for email ownership confirmation
+type EmailVerificationService struct {
+	userRepo         Repository
+	verificationRepo EmailVerificationRepository
+	notifier         Notifier
+	tokenTTL         time.Duration
+}
+
+// NewEmailVerificationService returns an instance of email verification service
+func NewEmailVerificationService(
+	userRepo Repository,
+	verificationRepo EmailVerificationRepository,
+	notifier Notifier,
+	tokenTTL time.Duration,
+) (*EmailVerificationService, error) {
+	if userRepo == nil {
+		return nil, fmt.Errorf("user repo is nil")
+	}
+
+	if verificationRepo == nil {
+		return nil, fmt.Errorf("email verification repo is nil")
+	}
+
+	if notifier == nil {
+		return nil, fmt.Errorf("notifier is nil")
+	}
+
+	if tokenTTL <= 0 {
+		return nil, fmt.Errorf("email verification token ttl should be greater than zero")
+	}
+
+	return &EmailVerificationService{
+		userRepo:         userRepo,
+		verificationRepo: verificationRepo,
+		notifier:         notifier,
+		tokenTTL:         tokenTTL,
+	}, nil
+}
+
+// SendVerification replaces outstanding verification tokens of given email
+// with a new one and delivers it
+func (s *EmailVerificationService) SendVerification(ctx context.Context, email string) error {
+	if email == "" {
+		return fmt.Errorf("user's email should not be empty")
+	}
+
+	err := s.verificationRepo.DeleteEmailVerificationTokens(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	token, err := opaque.NewToken(32)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+
+	err = s.verificationRepo.SaveEmailVerificationToken(ctx, EmailVerificationToken{
+		Hash:      opaque.Hash(token),
+		Email:     email,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.tokenTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.notifier.SendEmailVerification(ctx, email, token)
+}
+
+// VerifyEmail consumes given verification token and activates its user,
+// returns the email of the verified user
+func (s *EmailVerificationService) VerifyEmail(ctx context.Context, token string) (string, error) {
+	if token == "" {
+		return "", fmt.Errorf("email verification token should not be empty")
+	}
+
+	hash := opaque.Hash(token)
+
+	current, err := s.verificationRepo.GetEmailVerificationToken(ctx, hash)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now().UTC()
+
+	if current == nil || current.UsedAt != nil || !now.Before(current.ExpiresAt) {
+		return "", ErrInvalidVerificationToken
+	}
+
+	marked, err := s.verificationRepo.MarkEmailVerificationTokenUsed(ctx, hash, now)
+	if err != nil {
+		return "", err
+	}
+
+	if !marked {
+		return "", ErrInvalidVerificationToken
+	}
+
+	user, err := s.userRepo.GetUserByEmail(ctx, current.Email)
+	if err != nil {
+		return "", err
+	}
+
+	if user == nil {
+		return "", ErrInvalidVerificationToken
+	}
+
+	// only pending users are activated, any other status is kept as is
+	if user.Status == StatusPendingVerification {
+		err = s.userRepo.UpdateStatus(ctx, user.Email, StatusActive)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return user.Email, nil
+}
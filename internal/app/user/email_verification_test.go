@@ -0,0 +1,281 @@
+package user_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/alamrios/crabi-solution/internal/app/user"
+	tmock "github.com/alamrios/crabi-solution/test/mock"
+)
+
+func TestNewEmailVerificationService(t *testing.T) {
+	testCases := map[string]struct {
+		userRepo         user.Repository
+		verificationRepo user.EmailVerificationRepository
+		notifier         user.Notifier
+		tokenTTL         time.Duration
+		err              string
+	}{
+		"success": {
+			userRepo:         &tmock.UserRepository{},
+			verificationRepo: &tmock.EmailVerificationRepository{},
+			notifier:         &tmock.Notifier{},
+			tokenTTL:         time.Hour,
+		},
+		"missing user repo": {
+			err: "user repo is nil",
+		},
+		"missing email verification repo": {
+			userRepo: &tmock.UserRepository{},
+			err:      "email verification repo is nil",
+		},
+		"missing notifier": {
+			userRepo:         &tmock.UserRepository{},
+			verificationRepo: &tmock.EmailVerificationRepository{},
+			err:              "notifier is nil",
+		},
+		"invalid token ttl": {
+			userRepo:         &tmock.UserRepository{},
+			verificationRepo: &tmock.EmailVerificationRepository{},
+			notifier:         &tmock.Notifier{},
+			err:              "email verification token ttl should be greater than zero",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got, err := user.NewEmailVerificationService(tc.userRepo, tc.verificationRepo, tc.notifier, tc.tokenTTL)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				assert.Nil(t, got)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, got)
+			}
+		})
+	}
+}
+
+func TestSendVerification(t *testing.T) {
+	email1 := "dua@lipa.com"
+
+	testCases := map[string]struct {
+		email                 string
+		verificationRepoCalls []tmock.Call
+		notifierCalls         []tmock.Call
+		expectedError         error
+	}{
+		"success": {
+			email: email1,
+			verificationRepoCalls: []tmock.Call{
+				{
+					FunctionName: "DeleteEmailVerificationTokens",
+					Params:       []interface{}{email1},
+					Returns:      []interface{}{nil},
+				},
+				{
+					FunctionName: "SaveEmailVerificationToken",
+					Params: []interface{}{
+						mock.MatchedBy(func(token user.EmailVerificationToken) bool {
+							return token.Email == email1 && token.Hash != "" && token.ExpiresAt.After(time.Now())
+						}),
+					},
+					Returns: []interface{}{nil},
+				},
+			},
+			notifierCalls: []tmock.Call{
+				{
+					FunctionName: "SendEmailVerification",
+					Params:       []interface{}{email1, mock.AnythingOfType("string")},
+					Returns:      []interface{}{nil},
+				},
+			},
+		},
+		"repository error should propagate": {
+			email: email1,
+			verificationRepoCalls: []tmock.Call{
+				{
+					FunctionName: "DeleteEmailVerificationTokens",
+					Params:       []interface{}{email1},
+					Returns:      []interface{}{fmt.Errorf("repo error")},
+				},
+			},
+			expectedError: fmt.Errorf("repo error"),
+		},
+		"empty email should return error": {
+			expectedError: fmt.Errorf("user's email should not be empty"),
+		},
+	}
+
+	for name, tc := range testCases {
+		ctx := context.Background()
+		verificationRepo := tmock.NewEmailVerificationRepository().AddCall(t, tc.verificationRepoCalls)
+		notifier := tmock.NewNotifier().AddCall(t, tc.notifierCalls)
+
+		verificationService, err := user.NewEmailVerificationService(
+			tmock.NewUserRepository(),
+			verificationRepo,
+			notifier,
+			time.Hour,
+		)
+		assert.NoError(t, err)
+
+		email := tc.email
+		expectedError := tc.expectedError
+
+		t.Run(name, func(t *testing.T) {
+			err := verificationService.SendVerification(ctx, email)
+			assert.Equal(t, expectedError, err)
+			verificationRepo.AssertExpectations(t)
+			notifier.AssertExpectations(t)
+		})
+	}
+}
+
+func TestVerifyEmail(t *testing.T) {
+	email1 := "dua@lipa.com"
+	token1 := "k3S0zN9rT6xQ"
+	sum := sha256.Sum256([]byte(token1))
+	hash1 := hex.EncodeToString(sum[:])
+	usedAt := time.Now().Add(-time.Minute)
+
+	active := &user.EmailVerificationToken{
+		Hash:      hash1,
+		Email:     email1,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	used := &user.EmailVerificationToken{
+		Hash:      hash1,
+		Email:     email1,
+		ExpiresAt: time.Now().Add(time.Hour),
+		UsedAt:    &usedAt,
+	}
+
+	expired := &user.EmailVerificationToken{
+		Hash:      hash1,
+		Email:     email1,
+		ExpiresAt: time.Now().Add(-time.Hour),
+	}
+
+	pendingUser1 := &user.User{
+		Email:  email1,
+		Status: user.StatusPendingVerification,
+	}
+
+	activeUser1 := &user.User{
+		Email:  email1,
+		Status: user.StatusActive,
+	}
+
+	getTokenCall := func(token *user.EmailVerificationToken) tmock.Call {
+		return tmock.Call{
+			FunctionName: "GetEmailVerificationToken",
+			Params:       []interface{}{hash1},
+			Returns:      []interface{}{token, nil},
+		}
+	}
+
+	markUsedCall := func(marked bool) tmock.Call {
+		return tmock.Call{
+			FunctionName: "MarkEmailVerificationTokenUsed",
+			Params:       []interface{}{hash1, mock.Anything},
+			Returns:      []interface{}{marked, nil},
+		}
+	}
+
+	testCases := map[string]struct {
+		token                 string
+		verificationRepoCalls []tmock.Call
+		userRepoCalls         []tmock.Call
+		expectedError         error
+	}{
+		"success": {
+			token:                 token1,
+			verificationRepoCalls: []tmock.Call{getTokenCall(active), markUsedCall(true)},
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetUserByEmail",
+					Params:       []interface{}{email1},
+					Returns:      []interface{}{pendingUser1, nil},
+				},
+				{
+					FunctionName: "UpdateStatus",
+					Params:       []interface{}{email1, user.StatusActive},
+					Returns:      []interface{}{nil},
+				},
+			},
+		},
+		"already active user should keep status": {
+			token:                 token1,
+			verificationRepoCalls: []tmock.Call{getTokenCall(active), markUsedCall(true)},
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetUserByEmail",
+					Params:       []interface{}{email1},
+					Returns:      []interface{}{activeUser1, nil},
+				},
+			},
+		},
+		"used token should return error": {
+			token:                 token1,
+			verificationRepoCalls: []tmock.Call{getTokenCall(used)},
+			expectedError:         user.ErrInvalidVerificationToken,
+		},
+		"expired token should return error": {
+			token:                 token1,
+			verificationRepoCalls: []tmock.Call{getTokenCall(expired)},
+			expectedError:         user.ErrInvalidVerificationToken,
+		},
+		"unknown token should return error": {
+			token:                 token1,
+			verificationRepoCalls: []tmock.Call{getTokenCall(nil)},
+			expectedError:         user.ErrInvalidVerificationToken,
+		},
+		"concurrently used token should return error": {
+			token:                 token1,
+			verificationRepoCalls: []tmock.Call{getTokenCall(active), markUsedCall(false)},
+			expectedError:         user.ErrInvalidVerificationToken,
+		},
+		"empty token should return error": {
+			expectedError: fmt.Errorf("email verification token should not be empty"),
+		},
+	}
+
+	for name, tc := range testCases {
+		ctx := context.Background()
+		verificationRepo := tmock.NewEmailVerificationRepository().AddCall(t, tc.verificationRepoCalls)
+		userRepo := tmock.NewUserRepository().AddCall(t, tc.userRepoCalls)
+
+		verificationService, err := user.NewEmailVerificationService(
+			userRepo,
+			verificationRepo,
+			tmock.NewNotifier(),
+			time.Hour,
+		)
+		assert.NoError(t, err)
+
+		token := tc.token
+		expectedError := tc.expectedError
+
+		t.Run(name, func(t *testing.T) {
+			email, err := verificationService.VerifyEmail(ctx, token)
+			if expectedError == nil {
+				assert.NoError(t, err)
+				assert.Equal(t, email1, email)
+			} else {
+				assert.Equal(t, expectedError, err)
+				assert.Empty(t, email)
+			}
+			userRepo.AssertExpectations(t)
+			verificationRepo.AssertExpectations(t)
+		})
+	}
+}
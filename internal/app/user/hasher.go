@@ -0,0 +1,121 @@
+package user
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+)
+
+// ErrUnsupportedHash returned when a hasher can not handle given encoded hash
+var ErrUnsupportedHash = fmt.Errorf("unsupported password hash format")
+
+// PasswordHasher contract for password hashing algorithms
+type PasswordHasher interface {
+	// Hash returns a self-describing encoded hash for given password
+	Hash(password string) (string, error)
+	// Verify reports whether given password matches encoded hash
+	Verify(password, encoded string) (bool, error)
+	// Supports reports whether encoded hash was produced by this algorithm
+	Supports(encoded string) bool
+	// NeedsRehash reports whether encoded hash should be regenerated
+	NeedsRehash(encoded string) bool
+}
+
+// CompositeHasher hashes with a primary algorithm and verifies hashes
+// produced by the primary or any of the fallback algorithms
+type CompositeHasher struct {
+	primary   PasswordHasher
+	fallbacks []PasswordHasher
+}
+
+// NewCompositeHasher returns an instance of composite hasher
+func NewCompositeHasher(primary PasswordHasher, fallbacks ...PasswordHasher) (*CompositeHasher, error) {
+	if primary == nil {
+		return nil, fmt.Errorf("primary hasher is nil")
+	}
+
+	return &CompositeHasher{
+		primary:   primary,
+		fallbacks: fallbacks,
+	}, nil
+}
+
+// Hash hashes given password with primary algorithm
+func (h *CompositeHasher) Hash(password string) (string, error) {
+	return h.primary.Hash(password)
+}
+
+// Verify checks given password with the algorithm that produced encoded hash
+func (h *CompositeHasher) Verify(password, encoded string) (bool, error) {
+	hasher := h.find(encoded)
+	if hasher == nil {
+		return false, ErrUnsupportedHash
+	}
+
+	return hasher.Verify(password, encoded)
+}
+
+// Supports reports whether any known algorithm produced encoded hash
+func (h *CompositeHasher) Supports(encoded string) bool {
+	return h.find(encoded) != nil
+}
+
+// NeedsRehash reports whether encoded hash was not produced by primary
+// algorithm or was produced with outdated parameters
+func (h *CompositeHasher) NeedsRehash(encoded string) bool {
+	if !h.primary.Supports(encoded) {
+		return true
+	}
+
+	return h.primary.NeedsRehash(encoded)
+}
+
+func (h *CompositeHasher) find(encoded string) PasswordHasher {
+	if h.primary.Supports(encoded) {
+		return h.primary
+	}
+
+	for _, hasher := range h.fallbacks {
+		if hasher.Supports(encoded) {
+			return hasher
+		}
+	}
+
+	return nil
+}
+
+// LegacySHA256Hasher handles unsalted hex encoded SHA-256 hashes,
+// kept only to verify and upgrade passwords stored before salted hashing
+type LegacySHA256Hasher struct{}
+
+// Hash returns hex encoded SHA-256 of given password
+func (LegacySHA256Hasher) Hash(password string) (string, error) {
+	hash := sha256.Sum256([]byte(password))
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// Verify reports whether given password matches encoded hash
+func (h LegacySHA256Hasher) Verify(password, encoded string) (bool, error) {
+	if !h.Supports(encoded) {
+		return false, ErrUnsupportedHash
+	}
+
+	hash, _ := h.Hash(password)
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(encoded)) == 1, nil
+}
+
+// Supports reports whether encoded hash looks like a hex encoded SHA-256
+func (LegacySHA256Hasher) Supports(encoded string) bool {
+	if len(encoded) != hex.EncodedLen(sha256.Size) {
+		return false
+	}
+
+	_, err := hex.DecodeString(encoded)
+	return err == nil
+}
+
+// NeedsRehash always returns true, legacy hashes must be upgraded
+func (LegacySHA256Hasher) NeedsRehash(string) bool {
+	return true
+}
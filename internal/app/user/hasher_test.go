@@ -0,0 +1,143 @@
+package user_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/alamrios/crabi-solution/internal/app/user"
+)
+
+func TestPasswordHashers(t *testing.T) {
+	argon2id, err := user.NewArgon2idHasher(user.Argon2idParams{
+		Memory:      1024,
+		Iterations:  1,
+		Parallelism: 1,
+		SaltLength:  16,
+		KeyLength:   32,
+	})
+	assert.NoError(t, err)
+
+	bcrypt, err := user.NewBcryptHasher(4)
+	assert.NoError(t, err)
+
+	testCases := map[string]struct {
+		hasher user.PasswordHasher
+	}{
+		"argon2id": {
+			hasher: argon2id,
+		},
+		"bcrypt": {
+			hasher: bcrypt,
+		},
+		"legacy sha256": {
+			hasher: user.LegacySHA256Hasher{},
+		},
+	}
+
+	for name, tc := range testCases {
+		hasher := tc.hasher
+
+		t.Run(name, func(t *testing.T) {
+			encoded, err := hasher.Hash("dua123lipa")
+			assert.NoError(t, err)
+			assert.True(t, hasher.Supports(encoded))
+
+			valid, err := hasher.Verify("dua123lipa", encoded)
+			assert.NoError(t, err)
+			assert.True(t, valid)
+
+			valid, err = hasher.Verify("wrong", encoded)
+			assert.NoError(t, err)
+			assert.False(t, valid)
+		})
+	}
+}
+
+func TestArgon2idHasherSaltsEveryHash(t *testing.T) {
+	hasher, err := user.NewArgon2idHasher(user.Argon2idParams{
+		Memory:      1024,
+		Iterations:  1,
+		Parallelism: 1,
+		SaltLength:  16,
+		KeyLength:   32,
+	})
+	assert.NoError(t, err)
+
+	first, err := hasher.Hash("dua123lipa")
+	assert.NoError(t, err)
+	second, err := hasher.Hash("dua123lipa")
+	assert.NoError(t, err)
+
+	assert.NotEqual(t, first, second)
+	assert.Contains(t, first, "$argon2id$v=19$m=1024,t=1,p=1$")
+	assert.False(t, hasher.NeedsRehash(first))
+}
+
+func TestCompositeHasher(t *testing.T) {
+	argon2id, err := user.NewArgon2idHasher(user.Argon2idParams{
+		Memory:      1024,
+		Iterations:  1,
+		Parallelism: 1,
+		SaltLength:  16,
+		KeyLength:   32,
+	})
+	assert.NoError(t, err)
+
+	stronger, err := user.NewArgon2idHasher(user.Argon2idParams{
+		Memory:      2048,
+		Iterations:  1,
+		Parallelism: 1,
+		SaltLength:  16,
+		KeyLength:   32,
+	})
+	assert.NoError(t, err)
+
+	bcrypt, err := user.NewBcryptHasher(4)
+	assert.NoError(t, err)
+
+	hasher, err := user.NewCompositeHasher(argon2id, bcrypt, user.LegacySHA256Hasher{})
+	assert.NoError(t, err)
+
+	legacy, _ := user.LegacySHA256Hasher{}.Hash("dua123lipa")
+	bcrypted, _ := bcrypt.Hash("dua123lipa")
+	current, _ := argon2id.Hash("dua123lipa")
+	outdated, _ := stronger.Hash("dua123lipa")
+
+	testCases := map[string]struct {
+		encoded     string
+		needsRehash bool
+	}{
+		"legacy sha256 should be rehashed": {
+			encoded:     legacy,
+			needsRehash: true,
+		},
+		"fallback algorithm should be rehashed": {
+			encoded:     bcrypted,
+			needsRehash: true,
+		},
+		"outdated parameters should be rehashed": {
+			encoded:     outdated,
+			needsRehash: true,
+		},
+		"current parameters should not be rehashed": {
+			encoded:     current,
+			needsRehash: false,
+		},
+	}
+
+	for name, tc := range testCases {
+		encoded := tc.encoded
+		needsRehash := tc.needsRehash
+
+		t.Run(name, func(t *testing.T) {
+			valid, err := hasher.Verify("dua123lipa", encoded)
+			assert.NoError(t, err)
+			assert.True(t, valid)
+			assert.Equal(t, needsRehash, hasher.NeedsRehash(encoded))
+		})
+	}
+
+	_, err = hasher.Verify("dua123lipa", "plain")
+	assert.Equal(t, user.ErrUnsupportedHash, err)
+}
@@ -0,0 +1,49 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+)
+
+// NewID returns a random immutable user identifier, a version 4 UUID
+func NewID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+
+	return formatUUID(b, 4), nil
+}
+
+// LegacyID returns the identifier of a user stored before identifiers
+// existed, derived from its storage key so an interrupted backfill assigns
+// the same identifier when resumed
+func LegacyID(key []byte) string {
+	sum := sha1.Sum(key)
+
+	var b [16]byte
+	copy(b[:], sum[:16])
+
+	return formatUUID(b, 5)
+}
+
+// formatUUID sets version and RFC 4122 variant bits of given bytes and
+// returns them in canonical UUID form
+func formatUUID(b [16]byte, version byte) string {
+	b[6] = b[6]&0x0f | version<<4
+	b[8] = b[8]&0x3f | 0x80
+
+	buf := make([]byte, 36)
+	hex.Encode(buf[0:8], b[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], b[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], b[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], b[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], b[10:])
+
+	return string(buf)
+}
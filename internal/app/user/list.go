@@ -0,0 +1,220 @@
+package user
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultListLimit users per page when no limit is requested
+	DefaultListLimit = 20
+	// MaxListLimit maximum users per page
+	MaxListLimit = 100
+)
+
+// ErrInvalidCursor returned when a page cursor is malformed or was issued
+// for a different sort
+var ErrInvalidCursor = fmt.Errorf("invalid page cursor")
+
+// SortField user field listed users are ordered by
+type SortField string
+
+const (
+	// SortCreatedAt orders users by creation date
+	SortCreatedAt SortField = "created_at"
+	// SortEmail orders users by email
+	SortEmail SortField = "email"
+	// SortLastName orders users by last name
+	SortLastName SortField = "last_name"
+)
+
+// Sort order of listed users, ties are broken by email in the same direction
+type Sort struct {
+	Field      SortField
+	Descending bool
+}
+
+// DefaultSort newest users first
+var DefaultSort = Sort{Field: SortCreatedAt, Descending: true}
+
+// ParseSort reads a sort as the field name, prefixed with - for descending
+// order, empty value returns DefaultSort
+func ParseSort(value string) (Sort, error) {
+	if value == "" {
+		return DefaultSort, nil
+	}
+
+	sort := Sort{Field: SortField(strings.TrimPrefix(value, "-"))}
+	sort.Descending = strings.HasPrefix(value, "-")
+
+	switch sort.Field {
+	case SortCreatedAt, SortEmail, SortLastName:
+		return sort, nil
+	default:
+		return Sort{}, fmt.Errorf("users can not be sorted by %s", sort.Field)
+	}
+}
+
+// String returns sort in the format read by ParseSort
+func (s Sort) String() string {
+	if s.Descending {
+		return "-" + string(s.Field)
+	}
+
+	return string(s.Field)
+}
+
+// Filter struct for user listing criteria, zero fields match every user
+type Filter struct {
+	// Email exact email, looks a user up by email
+	Email  string
+	Status Status
+	// CreatedFrom inclusive lower bound of creation date
+	CreatedFrom *time.Time
+	// CreatedTo exclusive upper bound of creation date
+	CreatedTo *time.Time
+	// NamePrefix case sensitive prefix of first or last name
+	NamePrefix string
+	// EmailDomain case insensitive domain of email
+	EmailDomain string
+}
+
+// ListCursor struct for the position of the last user of a page, the next
+// page starts right after it
+type ListCursor struct {
+	Sort      string    `json:"s"`
+	CreatedAt time.Time `json:"c"`
+	LastName  string    `json:"l,omitempty"`
+	Email     string    `json:"e"`
+}
+
+// ListQuery struct for a page of users request
+type ListQuery struct {
+	Filter Filter
+	Sort   Sort
+	// Limit users per page, DefaultListLimit when zero
+	Limit int
+	// Cursor opaque position returned as NextCursor of the previous page
+	Cursor string
+	// IncludeTotal counts every user matching the filter
+	IncludeTotal bool
+}
+
+// UserPage struct for a page of listed users
+type UserPage struct {
+	Users []User
+	// NextCursor position of the next page, empty on the last page
+	NextCursor string
+	// Total users matching the filter, nil unless requested
+	Total *int64
+}
+
+// ListUsers returns a page of users matching given query, deleted users are
+// never listed
+func (s *Service) ListUsers(ctx context.Context, query ListQuery) (*UserPage, error) {
+	limit := query.Limit
+	if limit == 0 {
+		limit = DefaultListLimit
+	}
+	if limit < 0 || limit > MaxListLimit {
+		return nil, fmt.Errorf("limit should be between 1 and %d", MaxListLimit)
+	}
+
+	if query.Sort.Field == "" {
+		query.Sort = DefaultSort
+	}
+
+	filter := query.Filter
+	filter.EmailDomain = strings.ToLower(filter.EmailDomain)
+	if filter.CreatedFrom != nil && filter.CreatedTo != nil && !filter.CreatedFrom.Before(*filter.CreatedTo) {
+		return nil, fmt.Errorf("created from date should be before created to date")
+	}
+
+	var after *ListCursor
+	if query.Cursor != "" {
+		cursor, err := decodeCursor(query.Cursor)
+		if err != nil || cursor.Sort != query.Sort.String() {
+			return nil, ErrInvalidCursor
+		}
+		after = cursor
+	}
+
+	// one extra user tells whether there is a next page
+	users, err := s.userRepo.ListUsers(ctx, filter, query.Sort, after, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &UserPage{
+		Users: users,
+	}
+
+	if len(users) > limit {
+		page.Users = users[:limit]
+
+		last := page.Users[limit-1]
+		page.NextCursor, err = encodeCursor(ListCursor{
+			Sort:      query.Sort.String(),
+			CreatedAt: last.CreatedAt,
+			LastName:  last.LastName,
+			Email:     last.Email,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if query.IncludeTotal {
+		total, err := s.userRepo.CountUsers(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		page.Total = &total
+	}
+
+	return page, nil
+}
+
+// encodeCursor returns given cursor as an opaque url safe string
+func encodeCursor(cursor ListCursor) (string, error) {
+	payload, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(payload), nil
+}
+
+// decodeCursor reads cursor written by encodeCursor
+func decodeCursor(value string) (*ListCursor, error) {
+	payload, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor ListCursor
+	err = json.Unmarshal(payload, &cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	if cursor.Email == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	return &cursor, nil
+}
+
+// emailDomain returns lower cased domain of given email
+func emailDomain(email string) string {
+	i := strings.LastIndexByte(email, '@')
+	if i < 0 {
+		return ""
+	}
+
+	return strings.ToLower(email[i+1:])
+}
@@ -0,0 +1,199 @@
+package user_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/alamrios/crabi-solution/internal/app/user"
+	tmock "github.com/alamrios/crabi-solution/test/mock"
+)
+
+func TestParseSort(t *testing.T) {
+	testCases := map[string]struct {
+		value    string
+		expected user.Sort
+		err      string
+	}{
+		"empty value should return default sort": {
+			expected: user.DefaultSort,
+		},
+		"field should sort ascending": {
+			value:    "email",
+			expected: user.Sort{Field: user.SortEmail},
+		},
+		"dash prefix should sort descending": {
+			value:    "-last_name",
+			expected: user.Sort{Field: user.SortLastName, Descending: true},
+		},
+		"unknown field should return error": {
+			value: "password",
+			err:   "users can not be sorted by password",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got, err := user.ParseSort(tc.value)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, got)
+			if tc.value != "" {
+				assert.Equal(t, tc.value, got.String())
+			}
+		})
+	}
+}
+
+func TestListUsers(t *testing.T) {
+	ctx := context.Background()
+	createdAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	users := []user.User{
+		{FirstName: "Dua", LastName: "Lipa", Email: "dua@lipa.com", CreatedAt: createdAt},
+		{FirstName: "Ana", LastName: "Lipa", Email: "ana@lipa.com", CreatedAt: createdAt.Add(-time.Hour)},
+		{FirstName: "Rina", LastName: "Lipa", Email: "rina@lipa.com", CreatedAt: createdAt.Add(-2 * time.Hour)},
+	}
+
+	filter := user.Filter{EmailDomain: "lipa.com"}
+
+	newService := func(userRepo *tmock.UserRepository) *user.Service {
+		userService, err := user.NewService(
+			tmock.NewPLDService(),
+			userRepo,
+			tmock.NewPasswordHasher(),
+			user.LockoutPolicy{},
+			user.PasswordPolicy{},
+			tmock.NewEmailVerifier(),
+			tmock.NewSessionRevoker(),
+		)
+		assert.NoError(t, err)
+
+		return userService
+	}
+
+	t.Run("pages should chain through the cursor", func(t *testing.T) {
+		userRepo := tmock.NewUserRepository().AddCall(t, []tmock.Call{
+			{
+				FunctionName: "ListUsers",
+				Params:       []interface{}{filter, user.DefaultSort, (*user.ListCursor)(nil), 3},
+				Returns:      []interface{}{users, nil},
+			},
+			{
+				FunctionName: "ListUsers",
+				Params: []interface{}{filter, user.DefaultSort, &user.ListCursor{
+					Sort:      "-created_at",
+					CreatedAt: users[1].CreatedAt,
+					LastName:  users[1].LastName,
+					Email:     users[1].Email,
+				}, 3},
+				Returns: []interface{}{users[2:], nil},
+			},
+			{
+				FunctionName: "CountUsers",
+				Params:       []interface{}{filter},
+				Returns:      []interface{}{int64(3), nil},
+			},
+		})
+		userService := newService(userRepo)
+
+		first, err := userService.ListUsers(ctx, user.ListQuery{
+			Filter: user.Filter{EmailDomain: "LIPA.com"},
+			Limit:  2,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, users[:2], first.Users)
+		assert.NotEmpty(t, first.NextCursor)
+		assert.Nil(t, first.Total)
+
+		second, err := userService.ListUsers(ctx, user.ListQuery{
+			Filter:       filter,
+			Limit:        2,
+			Cursor:       first.NextCursor,
+			IncludeTotal: true,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, users[2:], second.Users)
+		assert.Empty(t, second.NextCursor)
+		assert.Equal(t, int64(3), *second.Total)
+
+		userRepo.AssertExpectations(t)
+	})
+
+	t.Run("cursor of another sort should return error", func(t *testing.T) {
+		userRepo := tmock.NewUserRepository().AddCall(t, []tmock.Call{
+			{
+				FunctionName: "ListUsers",
+				Params:       []interface{}{user.Filter{}, user.DefaultSort, (*user.ListCursor)(nil), 2},
+				Returns:      []interface{}{users[:2], nil},
+			},
+		})
+		userService := newService(userRepo)
+
+		first, err := userService.ListUsers(ctx, user.ListQuery{Limit: 1})
+		assert.NoError(t, err)
+
+		_, err = userService.ListUsers(ctx, user.ListQuery{
+			Sort:   user.Sort{Field: user.SortEmail},
+			Limit:  1,
+			Cursor: first.NextCursor,
+		})
+		assert.Equal(t, user.ErrInvalidCursor, err)
+	})
+
+	testCases := map[string]struct {
+		query         user.ListQuery
+		userRepoCalls []tmock.Call
+		expectedError error
+	}{
+		"malformed cursor should return error": {
+			query:         user.ListQuery{Cursor: "not a cursor"},
+			expectedError: user.ErrInvalidCursor,
+		},
+		"limit over maximum should return error": {
+			query:         user.ListQuery{Limit: user.MaxListLimit + 1},
+			expectedError: fmt.Errorf("limit should be between 1 and %d", user.MaxListLimit),
+		},
+		"empty created date range should return error": {
+			query: user.ListQuery{Filter: user.Filter{
+				CreatedFrom: &createdAt,
+				CreatedTo:   &createdAt,
+			}},
+			expectedError: fmt.Errorf("created from date should be before created to date"),
+		},
+		"user repo error should propagate": {
+			query: user.ListQuery{},
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "ListUsers",
+					Params:       []interface{}{user.Filter{}, user.DefaultSort, mock.Anything, user.DefaultListLimit + 1},
+					Returns:      []interface{}{[]user.User(nil), fmt.Errorf("user repo error")},
+				},
+			},
+			expectedError: fmt.Errorf("user repo error"),
+		},
+	}
+
+	for name, tc := range testCases {
+		userRepo := tmock.NewUserRepository().AddCall(t, tc.userRepoCalls)
+		userService := newService(userRepo)
+
+		query := tc.query
+		expectedError := tc.expectedError
+
+		t.Run(name, func(t *testing.T) {
+			got, err := userService.ListUsers(ctx, query)
+			assert.Equal(t, expectedError, err)
+			assert.Nil(t, got)
+			userRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ErrAccountLocked returned when login is refused because of previous failures
+var ErrAccountLocked = fmt.Errorf("account temporarily locked due to failed login attempts")
+
+// LockedError carries the date after which login can be attempted again
+type LockedError struct {
+	Until time.Time
+}
+
+// Error returns locked error message
+func (e *LockedError) Error() string {
+	return ErrAccountLocked.Error()
+}
+
+// Is makes errors.Is(err, ErrAccountLocked) match locked errors
+func (e *LockedError) Is(target error) bool {
+	return target == ErrAccountLocked
+}
+
+// LoginAttempts struct for failed login tracking stored with the user
+type LoginAttempts struct {
+	Failed      int        `bson:"failed"`
+	WindowStart *time.Time `bson:"window_start,omitempty"`
+	LastFailed  *time.Time `bson:"last_failed,omitempty"`
+	LockedUntil *time.Time `bson:"locked_until,omitempty"`
+}
+
+// LockoutPolicy struct for failed login thresholds, a zero MaxAttempts
+// disables lockout and delays
+type LockoutPolicy struct {
+	// MaxAttempts failures within Window that lock the account
+	MaxAttempts int
+	Window      time.Duration
+	// Duration the account stays locked
+	Duration time.Duration
+	// BaseDelay wait after first failure, doubled on each following failure
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// retryAt returns when next login attempt is allowed, nil if allowed now
+func (p LockoutPolicy) retryAt(attempts LoginAttempts, now time.Time) *time.Time {
+	if p.MaxAttempts <= 0 {
+		return nil
+	}
+
+	if attempts.LockedUntil != nil && now.Before(*attempts.LockedUntil) {
+		return attempts.LockedUntil
+	}
+
+	if attempts.Failed == 0 || attempts.LastFailed == nil || p.BaseDelay <= 0 {
+		return nil
+	}
+
+	if attempts.WindowStart != nil && now.Sub(*attempts.WindowStart) > p.Window {
+		return nil
+	}
+
+	next := attempts.LastFailed.Add(p.delay(attempts.Failed))
+	if now.Before(next) {
+		return &next
+	}
+
+	return nil
+}
+
+// delay returns exponential backoff wait after given consecutive failures
+func (p LockoutPolicy) delay(failed int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < failed && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		return p.MaxDelay
+	}
+
+	return delay
+}
+
+// registerFailure counts a failed login of user with given email at given
+// date and locks the account once max attempts are reached. Counting is done
+// by the repository so concurrent failures are never lost.
+func (p LockoutPolicy) registerFailure(ctx context.Context, userRepo Repository, email string, now time.Time) error {
+	if p.MaxAttempts <= 0 {
+		return nil
+	}
+
+	attempts, err := userRepo.IncrementLoginFailures(ctx, email, now, now.Add(-p.Window))
+	if err != nil || attempts == nil {
+		return err
+	}
+
+	if attempts.Failed < p.MaxAttempts {
+		return nil
+	}
+
+	return userRepo.LockLogin(ctx, email, p.MaxAttempts, now.Add(p.Duration))
+}
@@ -0,0 +1,309 @@
+package user_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/alamrios/crabi-solution/internal/app/user"
+	tmock "github.com/alamrios/crabi-solution/test/mock"
+)
+
+func TestLoginLockout(t *testing.T) {
+	policy := user.LockoutPolicy{
+		MaxAttempts: 3,
+		Window:      15 * time.Minute,
+		Duration:    15 * time.Minute,
+		BaseDelay:   time.Second,
+		MaxDelay:    time.Minute,
+	}
+
+	email := "dua@lipa.com"
+	password := "dua123lipa"
+	ePassword := "$argon2id$v=19$m=65536,t=3,p=2$c2FsdA$aGFzaA"
+
+	now := time.Now().UTC()
+	lockedUntil := now.Add(10 * time.Minute)
+	windowStart := now.Add(-5 * time.Minute)
+	lastFailed := now.Add(-10 * time.Minute)
+	recentFailed := now.Add(-time.Second)
+
+	newUser := func(attempts user.LoginAttempts) *user.User {
+		return &user.User{
+			Email:         email,
+			Password:      ePassword,
+			LoginAttempts: attempts,
+		}
+	}
+
+	verifyCall := func(valid bool) tmock.Call {
+		return tmock.Call{
+			FunctionName: "Verify",
+			Params:       []interface{}{password, ePassword},
+			Returns:      []interface{}{valid, nil},
+		}
+	}
+
+	testCases := map[string]struct {
+		user        *user.User
+		valid       bool
+		hasherCalls []tmock.Call
+		// failures attempts counted by the repository after a failure
+		failures      *user.LoginAttempts
+		locked        bool
+		attempts      func(attempts user.LoginAttempts) bool
+		expectedError error
+	}{
+		"locked account should be rejected before verifying password": {
+			user:          newUser(user.LoginAttempts{LockedUntil: &lockedUntil}),
+			expectedError: &user.LockedError{Until: lockedUntil},
+		},
+		"attempt during backoff delay should be rejected": {
+			user: newUser(user.LoginAttempts{
+				Failed:      2,
+				WindowStart: &windowStart,
+				LastFailed:  &recentFailed,
+			}),
+			expectedError: &user.LockedError{Until: recentFailed.Add(2 * time.Second)},
+		},
+		"first failure should be counted": {
+			user:          newUser(user.LoginAttempts{}),
+			hasherCalls:   []tmock.Call{verifyCall(false)},
+			failures:      &user.LoginAttempts{Failed: 1, WindowStart: &now, LastFailed: &now},
+			expectedError: user.ErrInvalidCredentials,
+		},
+		"reaching max attempts should lock account": {
+			user: newUser(user.LoginAttempts{
+				Failed:      2,
+				WindowStart: &windowStart,
+				LastFailed:  &lastFailed,
+			}),
+			hasherCalls:   []tmock.Call{verifyCall(false)},
+			failures:      &user.LoginAttempts{Failed: 3, WindowStart: &windowStart, LastFailed: &now},
+			locked:        true,
+			expectedError: user.ErrInvalidCredentials,
+		},
+		"success should reset attempts": {
+			user: newUser(user.LoginAttempts{
+				Failed:      2,
+				WindowStart: &windowStart,
+				LastFailed:  &lastFailed,
+			}),
+			hasherCalls: []tmock.Call{
+				verifyCall(true),
+				{
+					FunctionName: "NeedsRehash",
+					Params:       []interface{}{ePassword},
+					Returns:      []interface{}{false},
+				},
+			},
+			attempts: func(attempts user.LoginAttempts) bool {
+				return attempts == user.LoginAttempts{}
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		ctx := context.Background()
+
+		userRepoCalls := []tmock.Call{
+			{
+				FunctionName: "GetUserByEmail",
+				Params:       []interface{}{email},
+				Returns:      []interface{}{tc.user, nil},
+			},
+		}
+		if tc.failures != nil {
+			userRepoCalls = append(userRepoCalls, tmock.Call{
+				FunctionName: "IncrementLoginFailures",
+				Params: []interface{}{
+					email,
+					mock.AnythingOfType("time.Time"),
+					mock.MatchedBy(func(windowStart time.Time) bool {
+						return !windowStart.After(time.Now().Add(-policy.Window))
+					}),
+				},
+				Returns: []interface{}{tc.failures, nil},
+			})
+		}
+		if tc.locked {
+			userRepoCalls = append(userRepoCalls, tmock.Call{
+				FunctionName: "LockLogin",
+				Params: []interface{}{
+					email,
+					policy.MaxAttempts,
+					mock.MatchedBy(func(lockedUntil time.Time) bool {
+						return lockedUntil.Sub(now) >= policy.Duration
+					}),
+				},
+				Returns: []interface{}{nil},
+			})
+		}
+		if tc.attempts != nil {
+			userRepoCalls = append(userRepoCalls, tmock.Call{
+				FunctionName: "UpdateLoginAttempts",
+				Params:       []interface{}{email, mock.MatchedBy(tc.attempts)},
+				Returns:      []interface{}{nil},
+			})
+		}
+
+		userRepo := tmock.NewUserRepository().AddCall(t, userRepoCalls)
+		hasher := tmock.NewPasswordHasher().AddCall(t, tc.hasherCalls)
+
+		userService, err := user.NewService(tmock.NewPLDService(), userRepo, hasher, policy, user.PasswordPolicy{}, tmock.NewEmailVerifier(), tmock.NewSessionRevoker())
+		assert.NoError(t, err)
+
+		expectedError := tc.expectedError
+
+		t.Run(name, func(t *testing.T) {
+			got, err := userService.Login(ctx, email, password)
+
+			if expectedError == nil {
+				assert.NoError(t, err)
+				assert.NotNil(t, got)
+			} else {
+				assert.Equal(t, expectedError, err)
+				assert.Nil(t, got)
+			}
+			if errors.Is(expectedError, user.ErrAccountLocked) {
+				assert.True(t, errors.Is(err, user.ErrAccountLocked))
+			}
+			userRepo.AssertExpectations(t)
+			hasher.AssertExpectations(t)
+		})
+	}
+}
+
+// atomicUserRepository users repository counting login failures under a
+// lock, as the mongo repository does in a single update
+type atomicUserRepository struct {
+	*tmock.UserRepository
+
+	mu   sync.Mutex
+	user user.User
+}
+
+func (r *atomicUserRepository) GetUserByEmail(_ context.Context, _ string) (*user.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	found := r.user
+	return &found, nil
+}
+
+func (r *atomicUserRepository) IncrementLoginFailures(_ context.Context, _ string, failedAt, windowStart time.Time) (*user.LoginAttempts, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempts := &r.user.LoginAttempts
+	if attempts.WindowStart == nil || attempts.WindowStart.Before(windowStart) {
+		attempts.Failed = 0
+		attempts.WindowStart = &failedAt
+		attempts.LockedUntil = nil
+	}
+	attempts.Failed++
+	attempts.LastFailed = &failedAt
+
+	updated := *attempts
+	return &updated, nil
+}
+
+func (r *atomicUserRepository) LockLogin(_ context.Context, _ string, maxAttempts int, lockedUntil time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempts := &r.user.LoginAttempts
+	if attempts.Failed < maxAttempts {
+		return nil
+	}
+	attempts.Failed = 0
+	attempts.WindowStart = nil
+	attempts.LockedUntil = &lockedUntil
+
+	return nil
+}
+
+func TestLoginLockoutConcurrentFailures(t *testing.T) {
+	ctx := context.Background()
+	policy := user.LockoutPolicy{
+		MaxAttempts: 5,
+		Window:      15 * time.Minute,
+		Duration:    15 * time.Minute,
+	}
+
+	email := "dua@lipa.com"
+	ePassword := "$argon2id$v=19$m=65536,t=3,p=2$c2FsdA$aGFzaA"
+
+	userRepo := &atomicUserRepository{
+		UserRepository: tmock.NewUserRepository(),
+		user:           user.User{Email: email, Password: ePassword},
+	}
+	hasher := tmock.NewPasswordHasher().AddCall(t, []tmock.Call{
+		{
+			FunctionName: "Verify",
+			Params:       []interface{}{"wrong", ePassword},
+			Returns:      []interface{}{false, nil},
+		},
+	})
+
+	userService, err := user.NewService(tmock.NewPLDService(), userRepo, hasher, policy, user.PasswordPolicy{}, tmock.NewEmailVerifier(), tmock.NewSessionRevoker())
+	assert.NoError(t, err)
+
+	// every attempt reads the user before any failure is recorded
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < 4*policy.MaxAttempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+
+			_, err := userService.Login(ctx, email, "wrong")
+			assert.True(t, errors.Is(err, user.ErrInvalidCredentials) || errors.Is(err, user.ErrAccountLocked))
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	assert.NotNil(t, userRepo.user.LoginAttempts.LockedUntil)
+
+	_, err = userService.Login(ctx, email, "dua123lipa")
+	assert.True(t, errors.Is(err, user.ErrAccountLocked))
+}
+
+func TestUnlockUser(t *testing.T) {
+	ctx := context.Background()
+	lockedUntil := time.Now().Add(time.Hour)
+
+	userRepo := tmock.NewUserRepository().AddCall(t, []tmock.Call{
+		{
+			FunctionName: "GetUserByID",
+			Params:       []interface{}{"user1"},
+			Returns: []interface{}{
+				&user.User{
+					ID:            "user1",
+					Email:         "dua@lipa.com",
+					LoginAttempts: user.LoginAttempts{LockedUntil: &lockedUntil},
+				},
+				nil,
+			},
+		},
+		{
+			FunctionName: "UpdateLoginAttempts",
+			Params:       []interface{}{"dua@lipa.com", user.LoginAttempts{}},
+			Returns:      []interface{}{nil},
+		},
+	})
+
+	userService, err := user.NewService(tmock.NewPLDService(), userRepo, tmock.NewPasswordHasher(), user.LockoutPolicy{}, user.PasswordPolicy{}, tmock.NewEmailVerifier(), tmock.NewSessionRevoker())
+	assert.NoError(t, err)
+
+	err = userService.UnlockUser(ctx, "user1")
+	assert.NoError(t, err)
+	userRepo.AssertExpectations(t)
+}
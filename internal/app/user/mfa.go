@@ -0,0 +1,266 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alamrios/crabi-solution/internal/app/opaque"
+)
+
+// recoveryCodesCount number of recovery codes generated on MFA confirmation
+const recoveryCodesCount = 10
+
+var (
+	// ErrMFAAlreadyEnabled returned when enrolling a user that already uses MFA
+	ErrMFAAlreadyEnabled = fmt.Errorf("mfa is already enabled")
+	// ErrMFANotEnrolled returned when confirming or verifying without an enrollment
+	ErrMFANotEnrolled = fmt.Errorf("mfa enrollment not found")
+	// ErrInvalidMFACode returned when code matches neither TOTP nor recovery codes
+	ErrInvalidMFACode = fmt.Errorf("invalid mfa code")
+)
+
+// MFA struct for TOTP second factor stored with the user
+type MFA struct {
+	Enabled bool   `bson:"enabled"`
+	Secret  string `bson:"secret,omitempty"`
+	// LastUsedStep time step of the last accepted code, prevents replays
+	LastUsedStep int64 `bson:"last_used_step,omitempty"`
+	// RecoveryCodes hashes of unused recovery codes
+	RecoveryCodes []string   `bson:"recovery_codes,omitempty"`
+	EnabledAt     *time.Time `bson:"enabled_at,omitempty"`
+}
+
+// MFAEnrollment struct returned when TOTP enrollment starts
+type MFAEnrollment struct {
+	Secret string
+	URI    string
+}
+
+// MFAService struct for TOTP two-factor authentication
+type MFAService struct {
+	userRepo Repository
+	lockout  LockoutPolicy
+	issuer   string
+}
+
+// NewMFAService returns an instance of MFA service, issuer is the name
+// shown by authenticator apps
+func NewMFAService(userRepo Repository, lockout LockoutPolicy, issuer string) (*MFAService, error) {
+	if userRepo == nil {
+		return nil, fmt.Errorf("user repo is nil")
+	}
+
+	if issuer == "" {
+		return nil, fmt.Errorf("mfa issuer should not be empty")
+	}
+
+	if lockout.MaxAttempts > 0 && (lockout.Window <= 0 || lockout.Duration <= 0) {
+		return nil, fmt.Errorf("lockout window and duration should be greater than zero")
+	}
+
+	return &MFAService{
+		userRepo: userRepo,
+		lockout:  lockout,
+		issuer:   issuer,
+	}, nil
+}
+
+// Enroll creates a new TOTP secret for user with given email, MFA is not
+// enforced until the secret is confirmed
+func (s *MFAService) Enroll(ctx context.Context, email string) (*MFAEnrollment, error) {
+	user, err := s.getUser(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.MFA.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.userRepo.UpdateMFA(ctx, user.Email, MFA{Secret: secret})
+	if err != nil {
+		return nil, err
+	}
+
+	return &MFAEnrollment{
+		Secret: secret,
+		URI:    totpURI(s.issuer, user.Email, secret),
+	}, nil
+}
+
+// Confirm enables MFA when given code matches the enrolled secret, returns
+// recovery codes that are shown only once
+func (s *MFAService) Confirm(ctx context.Context, email, code string) ([]string, error) {
+	user, err := s.getUser(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.MFA.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	if user.MFA.Secret == "" {
+		return nil, ErrMFANotEnrolled
+	}
+
+	now := time.Now().UTC()
+
+	step, ok := matchTOTP(user.MFA.Secret, normalizeCode(code), now, 0)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes := make([]string, recoveryCodesCount)
+	hashes := make([]string, recoveryCodesCount)
+	for i := range codes {
+		codes[i], err = newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		hashes[i] = opaque.Hash(normalizeCode(codes[i]))
+	}
+
+	err = s.userRepo.UpdateMFA(ctx, user.Email, MFA{
+		Enabled:       true,
+		Secret:        user.MFA.Secret,
+		LastUsedStep:  step,
+		RecoveryCodes: hashes,
+		EnabledAt:     &now,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Verify checks second factor of user with given email, code can be a TOTP
+// code or an unused recovery code. Failures count towards login lockout.
+func (s *MFAService) Verify(ctx context.Context, email, code string) (*User, error) {
+	if email == "" {
+		return nil, fmt.Errorf("user's email should not be empty")
+	}
+
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	if !user.MFA.Enabled {
+		return nil, ErrMFANotEnrolled
+	}
+
+	now := time.Now().UTC()
+
+	retryAt := s.lockout.retryAt(user.LoginAttempts, now)
+	if retryAt != nil {
+		return nil, &LockedError{Until: *retryAt}
+	}
+
+	ok, err := s.useMFACode(ctx, user, normalizeCode(code), now)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		err = s.lockout.registerFailure(ctx, s.userRepo, user.Email, now)
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, ErrInvalidMFACode
+	}
+
+	if user.LoginAttempts != (LoginAttempts{}) {
+		err = s.userRepo.UpdateLoginAttempts(ctx, user.Email, LoginAttempts{})
+		if err != nil {
+			return nil, err
+		}
+		user.LoginAttempts = LoginAttempts{}
+	}
+
+	return user, nil
+}
+
+func (s *MFAService) getUser(ctx context.Context, email string) (*User, error) {
+	if email == "" {
+		return nil, fmt.Errorf("user's email should not be empty")
+	}
+
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+// useMFACode consumes given code of given user, the matched time step is
+// remembered and recovery codes are removed. The repository accepts each
+// time step and recovery code once, so concurrent requests with the same
+// code are accepted only once.
+func (s *MFAService) useMFACode(ctx context.Context, user *User, code string, now time.Time) (bool, error) {
+	step, ok := matchTOTP(user.MFA.Secret, code, now, user.MFA.LastUsedStep)
+	if ok {
+		used, err := s.userRepo.UseMFAStep(ctx, user.Email, step)
+		if err != nil || !used {
+			return false, err
+		}
+
+		user.MFA.LastUsedStep = step
+		return true, nil
+	}
+
+	hash := opaque.Hash(code)
+	for i, recoveryCode := range user.MFA.RecoveryCodes {
+		if recoveryCode != hash {
+			continue
+		}
+
+		used, err := s.userRepo.UseMFARecoveryCode(ctx, user.Email, hash)
+		if err != nil || !used {
+			return false, err
+		}
+
+		remaining := make([]string, 0, len(user.MFA.RecoveryCodes)-1)
+		remaining = append(remaining, user.MFA.RecoveryCodes[:i]...)
+		user.MFA.RecoveryCodes = append(remaining, user.MFA.RecoveryCodes[i+1:]...)
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// newRecoveryCode returns a random code formatted as xxxxx-xxxxx
+func newRecoveryCode() (string, error) {
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return "", err
+	}
+
+	code := strings.ToLower(secret[:10])
+	return code[:5] + "-" + code[5:], nil
+}
+
+// normalizeCode removes separators and case typed by users
+func normalizeCode(code string) string {
+	code = strings.ReplaceAll(code, "-", "")
+	code = strings.ReplaceAll(code, " ", "")
+	return strings.ToLower(code)
+}
@@ -0,0 +1,335 @@
+package user_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/alamrios/crabi-solution/internal/app/user"
+	tmock "github.com/alamrios/crabi-solution/test/mock"
+)
+
+const mfaSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestNewMFAService(t *testing.T) {
+	testCases := map[string]struct {
+		userRepo user.Repository
+		lockout  user.LockoutPolicy
+		issuer   string
+		err      string
+	}{
+		"success": {
+			userRepo: &tmock.UserRepository{},
+			issuer:   "crabi-solution",
+		},
+		"missing user repo": {
+			err: "user repo is nil",
+		},
+		"missing issuer": {
+			userRepo: &tmock.UserRepository{},
+			err:      "mfa issuer should not be empty",
+		},
+		"invalid lockout policy": {
+			userRepo: &tmock.UserRepository{},
+			lockout:  user.LockoutPolicy{MaxAttempts: 3},
+			issuer:   "crabi-solution",
+			err:      "lockout window and duration should be greater than zero",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got, err := user.NewMFAService(tc.userRepo, tc.lockout, tc.issuer)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				assert.Nil(t, got)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, got)
+			}
+		})
+	}
+}
+
+func TestEnrollMFA(t *testing.T) {
+	email1 := "dua@lipa.com"
+
+	testCases := map[string]struct {
+		user          *user.User
+		updated       bool
+		expectedError error
+	}{
+		"success": {
+			user:    &user.User{Email: email1},
+			updated: true,
+		},
+		"pending enrollment should be replaced": {
+			user:    &user.User{Email: email1, MFA: user.MFA{Secret: mfaSecret}},
+			updated: true,
+		},
+		"enabled mfa should return error": {
+			user:          &user.User{Email: email1, MFA: user.MFA{Enabled: true, Secret: mfaSecret}},
+			expectedError: user.ErrMFAAlreadyEnabled,
+		},
+	}
+
+	for name, tc := range testCases {
+		ctx := context.Background()
+
+		userRepoCalls := []tmock.Call{
+			{
+				FunctionName: "GetUserByEmail",
+				Params:       []interface{}{email1},
+				Returns:      []interface{}{tc.user, nil},
+			},
+		}
+		if tc.updated {
+			userRepoCalls = append(userRepoCalls, tmock.Call{
+				FunctionName: "UpdateMFA",
+				Params: []interface{}{
+					email1,
+					mock.MatchedBy(func(mfa user.MFA) bool {
+						return !mfa.Enabled && mfa.Secret != "" && mfa.Secret != mfaSecret
+					}),
+				},
+				Returns: []interface{}{nil},
+			})
+		}
+		userRepo := tmock.NewUserRepository().AddCall(t, userRepoCalls)
+
+		mfaService, err := user.NewMFAService(userRepo, user.LockoutPolicy{}, "crabi-solution")
+		assert.NoError(t, err)
+
+		expectedError := tc.expectedError
+
+		t.Run(name, func(t *testing.T) {
+			got, err := mfaService.Enroll(ctx, email1)
+			if expectedError == nil {
+				assert.NoError(t, err)
+				assert.NotEmpty(t, got.Secret)
+				assert.True(t, strings.HasPrefix(got.URI, "otpauth://totp/crabi-solution:dua@lipa.com?"))
+				assert.Contains(t, got.URI, "secret="+got.Secret)
+			} else {
+				assert.Equal(t, expectedError, err)
+				assert.Nil(t, got)
+			}
+			userRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestConfirmMFA(t *testing.T) {
+	email1 := "dua@lipa.com"
+
+	code, err := user.TOTPCode(mfaSecret, time.Now())
+	assert.NoError(t, err)
+
+	testCases := map[string]struct {
+		user          *user.User
+		code          string
+		updated       bool
+		expectedError error
+	}{
+		"success": {
+			user:    &user.User{Email: email1, MFA: user.MFA{Secret: mfaSecret}},
+			code:    code,
+			updated: true,
+		},
+		"wrong code should return error": {
+			user:          &user.User{Email: email1, MFA: user.MFA{Secret: mfaSecret}},
+			code:          "000000",
+			expectedError: user.ErrInvalidMFACode,
+		},
+		"missing enrollment should return error": {
+			user:          &user.User{Email: email1},
+			code:          code,
+			expectedError: user.ErrMFANotEnrolled,
+		},
+		"enabled mfa should return error": {
+			user:          &user.User{Email: email1, MFA: user.MFA{Enabled: true, Secret: mfaSecret}},
+			code:          code,
+			expectedError: user.ErrMFAAlreadyEnabled,
+		},
+	}
+
+	for name, tc := range testCases {
+		ctx := context.Background()
+
+		userRepoCalls := []tmock.Call{
+			{
+				FunctionName: "GetUserByEmail",
+				Params:       []interface{}{email1},
+				Returns:      []interface{}{tc.user, nil},
+			},
+		}
+		if tc.updated {
+			userRepoCalls = append(userRepoCalls, tmock.Call{
+				FunctionName: "UpdateMFA",
+				Params: []interface{}{
+					email1,
+					mock.MatchedBy(func(mfa user.MFA) bool {
+						return mfa.Enabled && mfa.Secret == mfaSecret && mfa.LastUsedStep > 0 &&
+							len(mfa.RecoveryCodes) == 10 && mfa.EnabledAt != nil
+					}),
+				},
+				Returns: []interface{}{nil},
+			})
+		}
+		userRepo := tmock.NewUserRepository().AddCall(t, userRepoCalls)
+
+		mfaService, err := user.NewMFAService(userRepo, user.LockoutPolicy{}, "crabi-solution")
+		assert.NoError(t, err)
+
+		code := tc.code
+		expectedError := tc.expectedError
+
+		t.Run(name, func(t *testing.T) {
+			got, err := mfaService.Confirm(ctx, email1, code)
+			if expectedError == nil {
+				assert.NoError(t, err)
+				assert.Len(t, got, 10)
+			} else {
+				assert.Equal(t, expectedError, err)
+				assert.Nil(t, got)
+			}
+			userRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestVerifyMFA(t *testing.T) {
+	email1 := "dua@lipa.com"
+	policy := user.LockoutPolicy{
+		MaxAttempts: 3,
+		Window:      15 * time.Minute,
+		Duration:    15 * time.Minute,
+	}
+
+	now := time.Now()
+	code, err := user.TOTPCode(mfaSecret, now)
+	assert.NoError(t, err)
+
+	sum := sha256.Sum256([]byte("abcde12345"))
+	recoveryHash := hex.EncodeToString(sum[:])
+	otherHash := strings.Repeat("0", 64)
+
+	enabled := user.MFA{
+		Enabled:       true,
+		Secret:        mfaSecret,
+		RecoveryCodes: []string{otherHash, recoveryHash},
+	}
+
+	// every code up to one step ahead was already used
+	replayed := enabled
+	replayed.LastUsedStep = now.Unix()/30 + 1
+
+	useStepCall := func(used bool) tmock.Call {
+		return tmock.Call{
+			FunctionName: "UseMFAStep",
+			Params:       []interface{}{email1, mock.AnythingOfType("int64")},
+			Returns:      []interface{}{used, nil},
+		}
+	}
+
+	useRecoveryCodeCall := func(used bool) tmock.Call {
+		return tmock.Call{
+			FunctionName: "UseMFARecoveryCode",
+			Params:       []interface{}{email1, recoveryHash},
+			Returns:      []interface{}{used, nil},
+		}
+	}
+
+	testCases := map[string]struct {
+		mfa           user.MFA
+		code          string
+		useCalls      []tmock.Call
+		failed        bool
+		expectedError error
+	}{
+		"totp code should be accepted": {
+			mfa:      enabled,
+			code:     code,
+			useCalls: []tmock.Call{useStepCall(true)},
+		},
+		"recovery code should be accepted once": {
+			mfa:      enabled,
+			code:     "ABCDE-12345",
+			useCalls: []tmock.Call{useRecoveryCodeCall(true)},
+		},
+		"totp code used by a concurrent request should be rejected": {
+			mfa:           enabled,
+			code:          code,
+			useCalls:      []tmock.Call{useStepCall(false)},
+			failed:        true,
+			expectedError: user.ErrInvalidMFACode,
+		},
+		"recovery code used by a concurrent request should be rejected": {
+			mfa:           enabled,
+			code:          "ABCDE-12345",
+			useCalls:      []tmock.Call{useRecoveryCodeCall(false)},
+			failed:        true,
+			expectedError: user.ErrInvalidMFACode,
+		},
+		"replayed totp code should be rejected": {
+			mfa:           replayed,
+			code:          code,
+			failed:        true,
+			expectedError: user.ErrInvalidMFACode,
+		},
+		"wrong code should count as failed attempt": {
+			mfa:           enabled,
+			code:          "abcde-00000",
+			failed:        true,
+			expectedError: user.ErrInvalidMFACode,
+		},
+		"disabled mfa should return error": {
+			code:          code,
+			expectedError: user.ErrMFANotEnrolled,
+		},
+	}
+
+	for name, tc := range testCases {
+		ctx := context.Background()
+
+		userRepoCalls := []tmock.Call{
+			{
+				FunctionName: "GetUserByEmail",
+				Params:       []interface{}{email1},
+				Returns:      []interface{}{&user.User{Email: email1, MFA: tc.mfa}, nil},
+			},
+		}
+		userRepoCalls = append(userRepoCalls, tc.useCalls...)
+		if tc.failed {
+			userRepoCalls = append(userRepoCalls, tmock.Call{
+				FunctionName: "IncrementLoginFailures",
+				Params:       []interface{}{email1, mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time")},
+				Returns:      []interface{}{&user.LoginAttempts{Failed: 1}, nil},
+			})
+		}
+		userRepo := tmock.NewUserRepository().AddCall(t, userRepoCalls)
+
+		mfaService, err := user.NewMFAService(userRepo, policy, "crabi-solution")
+		assert.NoError(t, err)
+
+		code := tc.code
+		expectedError := tc.expectedError
+
+		t.Run(name, func(t *testing.T) {
+			got, err := mfaService.Verify(ctx, email1, code)
+			if expectedError == nil {
+				assert.NoError(t, err)
+				assert.NotNil(t, got)
+			} else {
+				assert.Equal(t, expectedError, err)
+				assert.Nil(t, got)
+			}
+			userRepo.AssertExpectations(t)
+		})
+	}
+}
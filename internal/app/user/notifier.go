@@ -0,0 +1,11 @@
+package user
+
+import (
+	"context"
+)
+
+// Notifier contract for delivering account messages to users
+type Notifier interface {
+	SendPasswordReset(ctx context.Context, email, token string) error
+	SendEmailVerification(ctx context.Context, email, token string) error
+}
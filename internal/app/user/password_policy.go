@@ -0,0 +1,180 @@
+package user
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrWeakPassword returned when a password does not satisfy the password policy
+var ErrWeakPassword = fmt.Errorf("password does not satisfy password policy")
+
+// Password policy rules reported in violations
+const (
+	RuleMinLength    = "min_length"
+	RuleMaxLength    = "max_length"
+	RuleUppercase    = "uppercase"
+	RuleLowercase    = "lowercase"
+	RuleDigit        = "digit"
+	RuleSymbol       = "symbol"
+	RulePersonalInfo = "personal_info"
+	RuleReused       = "reused"
+	RuleBreached     = "breached"
+)
+
+// BreachedPasswords contract for lists of known compromised passwords
+type BreachedPasswords interface {
+	// Contains reports whether given password appears in the list
+	Contains(password string) (bool, error)
+}
+
+// PasswordViolation struct for a single failing password policy rule
+type PasswordViolation struct {
+	Rule    string
+	Message string
+}
+
+// PasswordPolicyError carries every password policy rule a password fails
+type PasswordPolicyError struct {
+	Violations []PasswordViolation
+}
+
+// Error returns password policy error message listing failing rules
+func (e *PasswordPolicyError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		messages = append(messages, violation.Message)
+	}
+
+	return fmt.Sprintf("%s: %s", ErrWeakPassword.Error(), strings.Join(messages, "; "))
+}
+
+// Is makes errors.Is(err, ErrWeakPassword) match password policy errors
+func (e *PasswordPolicyError) Is(target error) bool {
+	return target == ErrWeakPassword
+}
+
+// PasswordPolicy struct for password strength rules, zero values disable
+// each rule
+type PasswordPolicy struct {
+	// MinLength minimum number of characters
+	MinLength int
+	// MaxLength maximum number of bytes, keeps hashing cost bounded and
+	// avoids bcrypt silently ignoring bytes past 72
+	MaxLength        int
+	RequireUppercase bool
+	RequireLowercase bool
+	RequireDigit     bool
+	RequireSymbol    bool
+	// RejectPersonalInfo refuses passwords containing user's name or email
+	RejectPersonalInfo bool
+	// HistorySize number of last passwords, current included, that can not be reused
+	HistorySize int
+	// Breached list of compromised passwords, nil disables screening
+	Breached BreachedPasswords
+}
+
+// validate returns an error if policy settings are inconsistent
+func (p PasswordPolicy) validate() error {
+	if p.MinLength < 0 || p.MaxLength < 0 || p.HistorySize < 0 {
+		return fmt.Errorf("password policy values should not be negative")
+	}
+
+	if p.MaxLength > 0 && p.MinLength > p.MaxLength {
+		return fmt.Errorf("password min length should not be greater than max length")
+	}
+
+	return nil
+}
+
+// check returns the violations of given password for given user, reuse of
+// previous passwords and breached passwords are checked by the service
+func (p PasswordPolicy) check(user *User, password string) []PasswordViolation {
+	var violations []PasswordViolation
+
+	if p.MinLength > 0 && utf8.RuneCountInString(password) < p.MinLength {
+		violations = append(violations, PasswordViolation{
+			Rule:    RuleMinLength,
+			Message: fmt.Sprintf("password should have at least %d characters", p.MinLength),
+		})
+	}
+
+	if p.MaxLength > 0 && len(password) > p.MaxLength {
+		violations = append(violations, PasswordViolation{
+			Rule:    RuleMaxLength,
+			Message: fmt.Sprintf("password should have at most %d bytes", p.MaxLength),
+		})
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+
+	if p.RequireUppercase && !upper {
+		violations = append(violations, PasswordViolation{
+			Rule:    RuleUppercase,
+			Message: "password should contain an uppercase letter",
+		})
+	}
+
+	if p.RequireLowercase && !lower {
+		violations = append(violations, PasswordViolation{
+			Rule:    RuleLowercase,
+			Message: "password should contain a lowercase letter",
+		})
+	}
+
+	if p.RequireDigit && !digit {
+		violations = append(violations, PasswordViolation{
+			Rule:    RuleDigit,
+			Message: "password should contain a digit",
+		})
+	}
+
+	if p.RequireSymbol && !symbol {
+		violations = append(violations, PasswordViolation{
+			Rule:    RuleSymbol,
+			Message: "password should contain a symbol",
+		})
+	}
+
+	if p.RejectPersonalInfo && user != nil && containsPersonalInfo(user, password) {
+		violations = append(violations, PasswordViolation{
+			Rule:    RulePersonalInfo,
+			Message: "password should not contain user's name or email",
+		})
+	}
+
+	return violations
+}
+
+// containsPersonalInfo reports whether password contains user's names or
+// email, parts shorter than 3 characters are ignored to avoid false positives
+func containsPersonalInfo(user *User, password string) bool {
+	password = strings.ToLower(password)
+
+	parts := strings.Fields(user.FirstName + " " + user.LastName)
+	if at := strings.LastIndex(user.Email, "@"); at > 0 {
+		parts = append(parts, user.Email[:at])
+	}
+
+	for _, part := range parts {
+		part = strings.ToLower(part)
+		if utf8.RuneCountInString(part) >= 3 && strings.Contains(password, part) {
+			return true
+		}
+	}
+
+	return false
+}
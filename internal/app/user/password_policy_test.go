@@ -0,0 +1,245 @@
+package user_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/alamrios/crabi-solution/internal/app/user"
+	tmock "github.com/alamrios/crabi-solution/test/mock"
+)
+
+func TestNewServicePasswordPolicy(t *testing.T) {
+	_, err := user.NewService(
+		tmock.NewPLDService(),
+		tmock.NewUserRepository(),
+		tmock.NewPasswordHasher(),
+		user.LockoutPolicy{},
+		user.PasswordPolicy{MinLength: 80, MaxLength: 72},
+		tmock.NewEmailVerifier(),
+		tmock.NewSessionRevoker(),
+	)
+	assert.EqualError(t, err, "password min length should not be greater than max length")
+}
+
+func TestCreateUserPasswordPolicy(t *testing.T) {
+	policy := user.PasswordPolicy{
+		MinLength:          10,
+		MaxLength:          72,
+		RequireUppercase:   true,
+		RequireLowercase:   true,
+		RequireDigit:       true,
+		RequireSymbol:      true,
+		RejectPersonalInfo: true,
+	}
+
+	testCases := map[string]struct {
+		password      string
+		expectedRules []string
+	}{
+		"every failing rule should be returned": {
+			password:      "lipa",
+			expectedRules: []string{user.RuleMinLength, user.RuleUppercase, user.RuleDigit, user.RuleSymbol, user.RulePersonalInfo},
+		},
+		"max length should be measured in bytes": {
+			password:      "Aa1!" + string(make([]byte, 70)),
+			expectedRules: []string{user.RuleMaxLength},
+		},
+		"email local part should be rejected": {
+			password:      "Xx-DUA.LIPA-9",
+			expectedRules: []string{user.RulePersonalInfo},
+		},
+		"name parts shorter than 3 characters should be ignored": {
+			password:      "Al-q1!",
+			expectedRules: []string{user.RuleMinLength},
+		},
+	}
+
+	for name, tc := range testCases {
+		ctx := context.Background()
+
+		userService, err := user.NewService(
+			tmock.NewPLDService(),
+			tmock.NewUserRepository(),
+			tmock.NewPasswordHasher(),
+			user.LockoutPolicy{},
+			policy,
+			tmock.NewEmailVerifier(),
+			tmock.NewSessionRevoker(),
+		)
+		assert.NoError(t, err)
+
+		password := tc.password
+		expectedRules := tc.expectedRules
+
+		t.Run(name, func(t *testing.T) {
+			got, err := userService.CreateUser(ctx, user.User{
+				FirstName: "Al",
+				LastName:  "Lipa",
+				Email:     "dua.lipa@crabi.com",
+				Password:  password,
+			})
+			assert.Nil(t, got)
+			assert.True(t, errors.Is(err, user.ErrWeakPassword))
+
+			var policyErr *user.PasswordPolicyError
+			assert.True(t, errors.As(err, &policyErr))
+
+			rules := []string{}
+			for _, violation := range policyErr.Violations {
+				rules = append(rules, violation.Rule)
+			}
+			assert.Equal(t, expectedRules, rules)
+		})
+	}
+}
+
+func TestCreateUserBreachedPassword(t *testing.T) {
+	ctx := context.Background()
+	breached := tmock.NewBreachedPasswords().AddCall(t, []tmock.Call{
+		{
+			FunctionName: "Contains",
+			Params:       []interface{}{"password123"},
+			Returns:      []interface{}{true, nil},
+		},
+	})
+
+	userService, err := user.NewService(
+		tmock.NewPLDService(),
+		tmock.NewUserRepository(),
+		tmock.NewPasswordHasher(),
+		user.LockoutPolicy{},
+		user.PasswordPolicy{MinLength: 12, Breached: breached},
+		tmock.NewEmailVerifier(),
+		tmock.NewSessionRevoker(),
+	)
+	assert.NoError(t, err)
+
+	got, err := userService.CreateUser(ctx, user.User{
+		FirstName: "Dua",
+		LastName:  "Lipa",
+		Email:     "dua@lipa.com",
+		Password:  "password123",
+	})
+	assert.Nil(t, got)
+	assert.Equal(t, &user.PasswordPolicyError{
+		Violations: []user.PasswordViolation{
+			{Rule: user.RuleMinLength, Message: "password should have at least 12 characters"},
+			{Rule: user.RuleBreached, Message: "password appears in a list of compromised passwords"},
+		},
+	}, err)
+	breached.AssertExpectations(t)
+}
+
+func TestSetPasswordHistory(t *testing.T) {
+	email1 := "dua@lipa.com"
+
+	user1 := &user.User{
+		FirstName:       "Dua",
+		LastName:        "Lipa",
+		Email:           email1,
+		Password:        "hash0",
+		PasswordHistory: []string{"hash1", "hash2", "hash3"},
+	}
+
+	testCases := map[string]struct {
+		password      string
+		userRepoCalls []tmock.Call
+		hasherCalls   []tmock.Call
+		expectedError error
+	}{
+		"success should keep replaced password in history": {
+			password: "new-password",
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetUserByEmail",
+					Params:       []interface{}{email1},
+					Returns:      []interface{}{user1, nil},
+				},
+				{
+					FunctionName: "UpdatePasswordHistory",
+					Params:       []interface{}{email1, []string{"hash0", "hash1"}},
+					Returns:      []interface{}{nil},
+				},
+				{
+					FunctionName: "UpdatePassword",
+					Params:       []interface{}{email1, "hash4"},
+					Returns:      []interface{}{nil},
+				},
+				{
+					FunctionName: "UpdateTokensValidAfter",
+					Params:       []interface{}{email1, mock.AnythingOfType("time.Time")},
+					Returns:      []interface{}{nil},
+				},
+			},
+			hasherCalls: []tmock.Call{
+				{
+					FunctionName: "Verify",
+					Params:       []interface{}{"new-password", mock.AnythingOfType("string")},
+					Returns:      []interface{}{false, nil},
+				},
+				{
+					FunctionName: "Hash",
+					Params:       []interface{}{"new-password"},
+					Returns:      []interface{}{"hash4", nil},
+				},
+			},
+		},
+		"reused password should return error": {
+			password: "old-password",
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetUserByEmail",
+					Params:       []interface{}{email1},
+					Returns:      []interface{}{user1, nil},
+				},
+			},
+			hasherCalls: []tmock.Call{
+				{
+					FunctionName: "Verify",
+					Params:       []interface{}{"old-password", "hash2"},
+					Returns:      []interface{}{true, nil},
+				},
+				{
+					FunctionName: "Verify",
+					Params:       []interface{}{"old-password", mock.AnythingOfType("string")},
+					Returns:      []interface{}{false, nil},
+				},
+			},
+			expectedError: &user.PasswordPolicyError{
+				Violations: []user.PasswordViolation{
+					{Rule: user.RuleReused, Message: "password should not match any of the last 3 passwords"},
+				},
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		ctx := context.Background()
+		userRepo := tmock.NewUserRepository().AddCall(t, tc.userRepoCalls)
+		hasher := tmock.NewPasswordHasher().AddCall(t, tc.hasherCalls)
+
+		userService, err := user.NewService(
+			tmock.NewPLDService(),
+			userRepo,
+			hasher,
+			user.LockoutPolicy{},
+			user.PasswordPolicy{HistorySize: 3},
+			tmock.NewEmailVerifier(),
+			tmock.NewSessionRevoker(),
+		)
+		assert.NoError(t, err)
+
+		password := tc.password
+		expectedError := tc.expectedError
+
+		t.Run(name, func(t *testing.T) {
+			err := userService.SetPassword(ctx, email1, password)
+			assert.Equal(t, expectedError, err)
+			userRepo.AssertExpectations(t)
+		})
+	}
+}
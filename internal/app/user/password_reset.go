@@ -0,0 +1,198 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/alamrios/crabi-solution/internal/app/opaque"
+)
+
+// resetTimeout time given to send a reset token once the request answered,
+// and to release a reset token whose password could not be set
+const resetTimeout = 30 * time.Second
+
+// ErrInvalidResetToken returned when reset token is unknown, expired or already used
+var ErrInvalidResetToken = fmt.Errorf("invalid or expired password reset token")
+
+// PasswordResetToken struct, only the hash of the opaque token is stored
+type PasswordResetToken struct {
+	Hash      string     `bson:"_id"`
+	Email     string     `bson:"email"`
+	CreatedAt time.Time  `bson:"created_at"`
+	ExpiresAt time.Time  `bson:"expires_at"`
+	UsedAt    *time.Time `bson:"used_at,omitempty"`
+}
+
+// PasswordResetService struct for self-service password recovery
+type PasswordResetService struct {
+	userService *Service
+	resetRepo   PasswordResetRepository
+	notifier    Notifier
+	tokenTTL    time.Duration
+}
+
+// NewPasswordResetService returns an instance of password reset service
+func NewPasswordResetService(
+	userService *Service,
+	resetRepo PasswordResetRepository,
+	notifier Notifier,
+	tokenTTL time.Duration,
+) (*PasswordResetService, error) {
+	if userService == nil {
+		return nil, fmt.Errorf("user service is nil")
+	}
+
+	if resetRepo == nil {
+		return nil, fmt.Errorf("password reset repo is nil")
+	}
+
+	if notifier == nil {
+		return nil, fmt.Errorf("notifier is nil")
+	}
+
+	if tokenTTL <= 0 {
+		return nil, fmt.Errorf("password reset token ttl should be greater than zero")
+	}
+
+	return &PasswordResetService{
+		userService: userService,
+		resetRepo:   resetRepo,
+		notifier:    notifier,
+		tokenTTL:    tokenTTL,
+	}, nil
+}
+
+// RequestReset sends a reset token to given email if it belongs to a user.
+// Unknown emails and delivery failures are not reported to the caller so
+// the response never reveals whether an email is registered. The lookup and
+// delivery run in background, so both cases answer in the same time.
+func (s *PasswordResetService) RequestReset(_ context.Context, email string) error {
+	if email == "" {
+		return fmt.Errorf("user's email should not be empty")
+	}
+
+	go s.requestReset(email)
+
+	return nil
+}
+
+// requestReset sends a reset token to given email if it belongs to a user,
+// failures are logged
+func (s *PasswordResetService) requestReset(email string) {
+	ctx, cancel := context.WithTimeout(context.Background(), resetTimeout)
+	defer cancel()
+
+	user, err := s.userService.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		log.Printf("failed to look up user for password reset: %v", err)
+		return
+	}
+
+	if user == nil {
+		return
+	}
+
+	err = s.sendResetToken(ctx, user.Email)
+	if err != nil {
+		log.Printf("failed to send password reset to %s: %v", user.Email, err)
+	}
+}
+
+// ResetPassword consumes given reset token, sets the new password and ends
+// every session of the user. Passwords refused by the password policy or
+// that could not be stored do not consume the token.
+func (s *PasswordResetService) ResetPassword(ctx context.Context, token, password string) error {
+	if token == "" {
+		return fmt.Errorf("password reset token should not be empty")
+	}
+	if password == "" {
+		return fmt.Errorf("user's password should not be empty")
+	}
+
+	hash := opaque.Hash(token)
+
+	current, err := s.resetRepo.GetPasswordResetToken(ctx, hash)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+
+	if current == nil || current.UsedAt != nil || !now.Before(current.ExpiresAt) {
+		return ErrInvalidResetToken
+	}
+
+	user, err := s.userService.userRepo.GetUserByEmail(ctx, current.Email)
+	if err != nil {
+		return err
+	}
+
+	if user == nil {
+		return ErrInvalidResetToken
+	}
+
+	err = s.userService.checkPassword(user, password)
+	if err != nil {
+		return err
+	}
+
+	marked, err := s.resetRepo.MarkPasswordResetTokenUsed(ctx, hash, now)
+	if err != nil {
+		return err
+	}
+
+	if !marked {
+		return ErrInvalidResetToken
+	}
+
+	err = s.userService.updatePassword(ctx, user, password)
+	if err != nil {
+		s.releaseResetToken(hash, now)
+		return err
+	}
+
+	return s.userService.sessions.DeleteSessions(ctx, user.Email)
+}
+
+// releaseResetToken lets reset token with given hash, marked used at usedAt,
+// be used again. It does not depend on the request context so canceled
+// resets release the token too, failures are logged.
+func (s *PasswordResetService) releaseResetToken(hash string, usedAt time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), resetTimeout)
+	defer cancel()
+
+	err := s.resetRepo.UnmarkPasswordResetTokenUsed(ctx, hash, usedAt)
+	if err != nil {
+		log.Printf("failed to release password reset token: %v", err)
+	}
+}
+
+// sendResetToken replaces outstanding reset tokens of given email with a
+// new one and delivers it
+func (s *PasswordResetService) sendResetToken(ctx context.Context, email string) error {
+	err := s.resetRepo.DeletePasswordResetTokens(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	token, err := opaque.NewToken(32)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+
+	err = s.resetRepo.SavePasswordResetToken(ctx, PasswordResetToken{
+		Hash:      opaque.Hash(token),
+		Email:     email,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.tokenTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.notifier.SendPasswordReset(ctx, email, token)
+}
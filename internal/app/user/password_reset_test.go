@@ -0,0 +1,502 @@
+package user_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/alamrios/crabi-solution/internal/app/user"
+	tmock "github.com/alamrios/crabi-solution/test/mock"
+)
+
+func TestNewPasswordResetService(t *testing.T) {
+	userService, err := user.NewService(
+		tmock.NewPLDService(),
+		tmock.NewUserRepository(),
+		tmock.NewPasswordHasher(),
+		user.LockoutPolicy{},
+		user.PasswordPolicy{},
+		tmock.NewEmailVerifier(),
+		tmock.NewSessionRevoker(),
+	)
+	assert.NoError(t, err)
+
+	testCases := map[string]struct {
+		userService *user.Service
+		resetRepo   user.PasswordResetRepository
+		notifier    user.Notifier
+		tokenTTL    time.Duration
+		err         string
+	}{
+		"success": {
+			userService: userService,
+			resetRepo:   &tmock.PasswordResetRepository{},
+			notifier:    &tmock.Notifier{},
+			tokenTTL:    time.Hour,
+		},
+		"missing user service": {
+			err: "user service is nil",
+		},
+		"missing password reset repo": {
+			userService: userService,
+			err:         "password reset repo is nil",
+		},
+		"missing notifier": {
+			userService: userService,
+			resetRepo:   &tmock.PasswordResetRepository{},
+			err:         "notifier is nil",
+		},
+		"invalid token ttl": {
+			userService: userService,
+			resetRepo:   &tmock.PasswordResetRepository{},
+			notifier:    &tmock.Notifier{},
+			err:         "password reset token ttl should be greater than zero",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got, err := user.NewPasswordResetService(tc.userService, tc.resetRepo, tc.notifier, tc.tokenTTL)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				assert.Nil(t, got)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, got)
+			}
+		})
+	}
+}
+
+// quietT discards the failures of expectations checked while polling
+type quietT struct{}
+
+func (quietT) Logf(string, ...interface{})   {}
+func (quietT) Errorf(string, ...interface{}) {}
+func (quietT) FailNow()                      {}
+
+// expectationsMet reports whether given mocks got every expected call, for
+// work done in background
+func expectationsMet(mocks ...interface{ AssertExpectations(mock.TestingT) bool }) bool {
+	for _, m := range mocks {
+		if !m.AssertExpectations(quietT{}) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestRequestReset(t *testing.T) {
+	email1 := "dua@lipa.com"
+
+	user1 := &user.User{
+		FirstName: "Dua",
+		LastName:  "Lipa",
+		Email:     email1,
+	}
+
+	testCases := map[string]struct {
+		email          string
+		userRepoCalls  []tmock.Call
+		resetRepoCalls []tmock.Call
+		notifierCalls  []tmock.Call
+		expectedError  error
+	}{
+		"success": {
+			email: email1,
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetUserByEmail",
+					Params:       []interface{}{email1},
+					Returns:      []interface{}{user1, nil},
+				},
+			},
+			resetRepoCalls: []tmock.Call{
+				{
+					FunctionName: "DeletePasswordResetTokens",
+					Params:       []interface{}{email1},
+					Returns:      []interface{}{nil},
+				},
+				{
+					FunctionName: "SavePasswordResetToken",
+					Params: []interface{}{
+						mock.MatchedBy(func(token user.PasswordResetToken) bool {
+							return token.Email == email1 && token.Hash != "" && token.ExpiresAt.After(time.Now())
+						}),
+					},
+					Returns: []interface{}{nil},
+				},
+			},
+			notifierCalls: []tmock.Call{
+				{
+					FunctionName: "SendPasswordReset",
+					Params:       []interface{}{email1, mock.AnythingOfType("string")},
+					Returns:      []interface{}{nil},
+				},
+			},
+		},
+		"unknown email should not return error": {
+			email: email1,
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetUserByEmail",
+					Params:       []interface{}{email1},
+					Returns:      []interface{}{(*user.User)(nil), nil},
+				},
+			},
+		},
+		"delivery error should not return error": {
+			email: email1,
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetUserByEmail",
+					Params:       []interface{}{email1},
+					Returns:      []interface{}{user1, nil},
+				},
+			},
+			resetRepoCalls: []tmock.Call{
+				{
+					FunctionName: "DeletePasswordResetTokens",
+					Params:       []interface{}{email1},
+					Returns:      []interface{}{nil},
+				},
+				{
+					FunctionName: "SavePasswordResetToken",
+					Params:       []interface{}{mock.Anything},
+					Returns:      []interface{}{nil},
+				},
+			},
+			notifierCalls: []tmock.Call{
+				{
+					FunctionName: "SendPasswordReset",
+					Params:       []interface{}{email1, mock.AnythingOfType("string")},
+					Returns:      []interface{}{fmt.Errorf("notifier error")},
+				},
+			},
+		},
+		"empty email should return error": {
+			expectedError: fmt.Errorf("user's email should not be empty"),
+		},
+	}
+
+	for name, tc := range testCases {
+		ctx := context.Background()
+		userRepo := tmock.NewUserRepository().AddCall(t, tc.userRepoCalls)
+		resetRepo := tmock.NewPasswordResetRepository().AddCall(t, tc.resetRepoCalls)
+		notifier := tmock.NewNotifier().AddCall(t, tc.notifierCalls)
+
+		userService, err := user.NewService(tmock.NewPLDService(), userRepo, tmock.NewPasswordHasher(), user.LockoutPolicy{}, user.PasswordPolicy{}, tmock.NewEmailVerifier(), tmock.NewSessionRevoker())
+		assert.NoError(t, err)
+
+		resetService, err := user.NewPasswordResetService(userService, resetRepo, notifier, time.Hour)
+		assert.NoError(t, err)
+
+		email := tc.email
+		expectedError := tc.expectedError
+
+		t.Run(name, func(t *testing.T) {
+			err := resetService.RequestReset(ctx, email)
+			assert.Equal(t, expectedError, err)
+
+			// the reset is sent in background
+			assert.Eventually(t, func() bool {
+				return expectationsMet(userRepo, resetRepo, notifier)
+			}, time.Second, 5*time.Millisecond)
+		})
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	email1 := "dua@lipa.com"
+	token1 := "k3S0zN9rT6xQ"
+	sum := sha256.Sum256([]byte(token1))
+	hash1 := hex.EncodeToString(sum[:])
+	ePassword := "$argon2id$v=19$m=65536,t=3,p=2$c2FsdA$aGFzaA"
+	usedAt := time.Now().Add(-time.Minute)
+
+	active := &user.PasswordResetToken{
+		Hash:      hash1,
+		Email:     email1,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	used := &user.PasswordResetToken{
+		Hash:      hash1,
+		Email:     email1,
+		ExpiresAt: time.Now().Add(time.Hour),
+		UsedAt:    &usedAt,
+	}
+
+	expired := &user.PasswordResetToken{
+		Hash:      hash1,
+		Email:     email1,
+		ExpiresAt: time.Now().Add(-time.Hour),
+	}
+
+	user1 := &user.User{
+		FirstName: "Dua",
+		LastName:  "Lipa",
+		Email:     email1,
+	}
+
+	testCases := map[string]struct {
+		token          string
+		password       string
+		policy         user.PasswordPolicy
+		userRepoCalls  []tmock.Call
+		resetRepoCalls []tmock.Call
+		hasherCalls    []tmock.Call
+		sessionsCalls  []tmock.Call
+		expectedError  error
+	}{
+		"success": {
+			token:    token1,
+			password: "dua456lipa",
+			resetRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetPasswordResetToken",
+					Params:       []interface{}{hash1},
+					Returns:      []interface{}{active, nil},
+				},
+				{
+					FunctionName: "MarkPasswordResetTokenUsed",
+					Params:       []interface{}{hash1, mock.Anything},
+					Returns:      []interface{}{true, nil},
+				},
+			},
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetUserByEmail",
+					Params:       []interface{}{email1},
+					Returns:      []interface{}{user1, nil},
+				},
+				{
+					FunctionName: "UpdatePassword",
+					Params:       []interface{}{email1, ePassword},
+					Returns:      []interface{}{nil},
+				},
+				{
+					FunctionName: "UpdateTokensValidAfter",
+					Params:       []interface{}{email1, mock.AnythingOfType("time.Time")},
+					Returns:      []interface{}{nil},
+				},
+			},
+			hasherCalls: []tmock.Call{
+				{
+					FunctionName: "Hash",
+					Params:       []interface{}{"dua456lipa"},
+					Returns:      []interface{}{ePassword, nil},
+				},
+			},
+			sessionsCalls: []tmock.Call{
+				{
+					FunctionName: "DeleteSessions",
+					Params:       []interface{}{email1},
+					Returns:      []interface{}{nil},
+				},
+			},
+		},
+		"session revocation error should propagate": {
+			token:    token1,
+			password: "dua456lipa",
+			resetRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetPasswordResetToken",
+					Params:       []interface{}{hash1},
+					Returns:      []interface{}{active, nil},
+				},
+				{
+					FunctionName: "MarkPasswordResetTokenUsed",
+					Params:       []interface{}{hash1, mock.Anything},
+					Returns:      []interface{}{true, nil},
+				},
+			},
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetUserByEmail",
+					Params:       []interface{}{email1},
+					Returns:      []interface{}{user1, nil},
+				},
+				{
+					FunctionName: "UpdatePassword",
+					Params:       []interface{}{email1, ePassword},
+					Returns:      []interface{}{nil},
+				},
+				{
+					FunctionName: "UpdateTokensValidAfter",
+					Params:       []interface{}{email1, mock.AnythingOfType("time.Time")},
+					Returns:      []interface{}{nil},
+				},
+			},
+			hasherCalls: []tmock.Call{
+				{
+					FunctionName: "Hash",
+					Params:       []interface{}{"dua456lipa"},
+					Returns:      []interface{}{ePassword, nil},
+				},
+			},
+			sessionsCalls: []tmock.Call{
+				{
+					FunctionName: "DeleteSessions",
+					Params:       []interface{}{email1},
+					Returns:      []interface{}{fmt.Errorf("session repo error")},
+				},
+			},
+			expectedError: fmt.Errorf("session repo error"),
+		},
+		"password update error should release token": {
+			token:    token1,
+			password: "dua456lipa",
+			resetRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetPasswordResetToken",
+					Params:       []interface{}{hash1},
+					Returns:      []interface{}{active, nil},
+				},
+				{
+					FunctionName: "MarkPasswordResetTokenUsed",
+					Params:       []interface{}{hash1, mock.Anything},
+					Returns:      []interface{}{true, nil},
+				},
+				{
+					FunctionName: "UnmarkPasswordResetTokenUsed",
+					Params:       []interface{}{hash1, mock.Anything},
+					Returns:      []interface{}{nil},
+				},
+			},
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetUserByEmail",
+					Params:       []interface{}{email1},
+					Returns:      []interface{}{user1, nil},
+				},
+				{
+					FunctionName: "UpdatePassword",
+					Params:       []interface{}{email1, ePassword},
+					Returns:      []interface{}{fmt.Errorf("user repo error")},
+				},
+			},
+			hasherCalls: []tmock.Call{
+				{
+					FunctionName: "Hash",
+					Params:       []interface{}{"dua456lipa"},
+					Returns:      []interface{}{ePassword, nil},
+				},
+			},
+			expectedError: fmt.Errorf("user repo error"),
+		},
+		"used token should return error": {
+			token:    token1,
+			password: "dua456lipa",
+			resetRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetPasswordResetToken",
+					Params:       []interface{}{hash1},
+					Returns:      []interface{}{used, nil},
+				},
+			},
+			expectedError: user.ErrInvalidResetToken,
+		},
+		"expired token should return error": {
+			token:    token1,
+			password: "dua456lipa",
+			resetRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetPasswordResetToken",
+					Params:       []interface{}{hash1},
+					Returns:      []interface{}{expired, nil},
+				},
+			},
+			expectedError: user.ErrInvalidResetToken,
+		},
+		"concurrently used token should return error": {
+			token:    token1,
+			password: "dua456lipa",
+			resetRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetPasswordResetToken",
+					Params:       []interface{}{hash1},
+					Returns:      []interface{}{active, nil},
+				},
+				{
+					FunctionName: "MarkPasswordResetTokenUsed",
+					Params:       []interface{}{hash1, mock.Anything},
+					Returns:      []interface{}{false, nil},
+				},
+			},
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetUserByEmail",
+					Params:       []interface{}{email1},
+					Returns:      []interface{}{user1, nil},
+				},
+			},
+			expectedError: user.ErrInvalidResetToken,
+		},
+		"password refused by policy should not consume token": {
+			token:    token1,
+			password: "short",
+			policy:   user.PasswordPolicy{MinLength: 8},
+			resetRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetPasswordResetToken",
+					Params:       []interface{}{hash1},
+					Returns:      []interface{}{active, nil},
+				},
+			},
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetUserByEmail",
+					Params:       []interface{}{email1},
+					Returns:      []interface{}{user1, nil},
+				},
+			},
+			expectedError: &user.PasswordPolicyError{
+				Violations: []user.PasswordViolation{
+					{Rule: user.RuleMinLength, Message: "password should have at least 8 characters"},
+				},
+			},
+		},
+		"empty password should return error": {
+			token:         token1,
+			expectedError: fmt.Errorf("user's password should not be empty"),
+		},
+	}
+
+	for name, tc := range testCases {
+		ctx := context.Background()
+		userRepo := tmock.NewUserRepository().AddCall(t, tc.userRepoCalls)
+		resetRepo := tmock.NewPasswordResetRepository().AddCall(t, tc.resetRepoCalls)
+		hasher := tmock.NewPasswordHasher().AddCall(t, tc.hasherCalls)
+		sessions := tmock.NewSessionRevoker().AddCall(t, tc.sessionsCalls)
+
+		userService, err := user.NewService(tmock.NewPLDService(), userRepo, hasher, user.LockoutPolicy{}, tc.policy, tmock.NewEmailVerifier(), sessions)
+		assert.NoError(t, err)
+
+		resetService, err := user.NewPasswordResetService(userService, resetRepo, tmock.NewNotifier(), time.Hour)
+		assert.NoError(t, err)
+
+		token := tc.token
+		password := tc.password
+		expectedError := tc.expectedError
+
+		t.Run(name, func(t *testing.T) {
+			err := resetService.ResetPassword(ctx, token, password)
+			if expectedError == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, expectedError, err)
+			}
+			userRepo.AssertExpectations(t)
+			resetRepo.AssertExpectations(t)
+			sessions.AssertExpectations(t)
+		})
+	}
+}
@@ -2,11 +2,84 @@ package user
 
 import (
 	"context"
+	"fmt"
+	"time"
 )
 
-// Repository contract for users repository
+// ErrDuplicateEmail returned by SaveUser when the email is already used,
+// by a deleted user too
+var ErrDuplicateEmail = fmt.Errorf("user's email already exists")
+
+// Repository contract for users repository, soft deleted users are
+// excluded unless a method states otherwise
 type Repository interface {
+	// SaveUser stores given user, ErrDuplicateEmail when its email is used
 	SaveUser(ctx context.Context, user User) error
+	// UpdateUser replaces profile fields of user with the same email
+	UpdateUser(ctx context.Context, user User) error
+	GetUserByID(ctx context.Context, id string) (*User, error)
 	GetUserByEmail(ctx context.Context, email string) (*User, error)
-	GetUserByEmailAndPassword(ctx context.Context, email, password string) (*User, error)
+	UpdatePassword(ctx context.Context, email, password string) error
+	UpdatePasswordHistory(ctx context.Context, email string, history []string) error
+	UpdateTokensValidAfter(ctx context.Context, email string, validAfter time.Time) error
+	UpdateLoginAttempts(ctx context.Context, email string, attempts LoginAttempts) error
+	// IncrementLoginFailures atomically counts a failed login of user with
+	// given email at given date, a new window is started when the current one
+	// began before windowStart. Returns updated attempts, nil when the user
+	// does not exist
+	IncrementLoginFailures(ctx context.Context, email string, failedAt, windowStart time.Time) (*LoginAttempts, error)
+	// LockLogin locks login of user with given email until given date and
+	// starts a new attempts window, only while at least maxAttempts failures
+	// are counted so concurrent lockings apply once
+	LockLogin(ctx context.Context, email string, maxAttempts int, lockedUntil time.Time) error
+	UpdateStatus(ctx context.Context, email string, status Status) error
+	UpdateMFA(ctx context.Context, email string, mfa MFA) error
+	// UseMFAStep records given TOTP time step as used by user with given
+	// email, returns false when it or a later step was already used
+	UseMFAStep(ctx context.Context, email string, step int64) (bool, error)
+	// UseMFARecoveryCode removes recovery code with given hash of user with
+	// given email, returns false when it was already used
+	UseMFARecoveryCode(ctx context.Context, email, hash string) (bool, error)
+	UpdateRole(ctx context.Context, email string, role Role) error
+	// UpdateScreening sets PLD screening status of user with given email,
+	// empty status clears it
+	UpdateScreening(ctx context.Context, email string, screening ScreeningStatus) error
+	// GetUsersByScreening returns up to limit users with given screening
+	// status, oldest first
+	GetUsersByScreening(ctx context.Context, screening ScreeningStatus, limit int) ([]User, error)
+	// ListUsers returns up to limit users matching given filter in given
+	// order, starting after given cursor when not nil
+	ListUsers(ctx context.Context, filter Filter, sort Sort, after *ListCursor, limit int) ([]User, error)
+	CountUsers(ctx context.Context, filter Filter) (int64, error)
+	// DeleteUser flags user with given email as deleted on given date
+	DeleteUser(ctx context.Context, email string, deletedAt time.Time) error
+	// GetDeletedUserByID returns soft deleted user with given id
+	GetDeletedUserByID(ctx context.Context, id string) (*User, error)
+	// GetDeletedUserByEmail returns soft deleted user with given email
+	GetDeletedUserByEmail(ctx context.Context, email string) (*User, error)
+	// RestoreUser clears the deleted flag of user with given id
+	RestoreUser(ctx context.Context, id string) error
+	// PurgeUsers permanently removes users deleted before given date,
+	// returns the number of removed users
+	PurgeUsers(ctx context.Context, deletedBefore time.Time) (int64, error)
+}
+
+// PasswordResetRepository contract for password reset tokens repository
+type PasswordResetRepository interface {
+	SavePasswordResetToken(ctx context.Context, token PasswordResetToken) error
+	GetPasswordResetToken(ctx context.Context, hash string) (*PasswordResetToken, error)
+	// MarkPasswordResetTokenUsed flags token as used, returns false if it was already used
+	MarkPasswordResetTokenUsed(ctx context.Context, hash string, usedAt time.Time) (bool, error)
+	// UnmarkPasswordResetTokenUsed clears the used flag set at usedAt
+	UnmarkPasswordResetTokenUsed(ctx context.Context, hash string, usedAt time.Time) error
+	DeletePasswordResetTokens(ctx context.Context, email string) error
+}
+
+// EmailVerificationRepository contract for email verification tokens repository
+type EmailVerificationRepository interface {
+	SaveEmailVerificationToken(ctx context.Context, token EmailVerificationToken) error
+	GetEmailVerificationToken(ctx context.Context, hash string) (*EmailVerificationToken, error)
+	// MarkEmailVerificationTokenUsed flags token as used, returns false if it was already used
+	MarkEmailVerificationTokenUsed(ctx context.Context, hash string, usedAt time.Time) (bool, error)
+	DeleteEmailVerificationTokens(ctx context.Context, email string) error
 }
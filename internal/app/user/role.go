@@ -0,0 +1,107 @@
+package user
+
+import (
+	"fmt"
+)
+
+// ErrInvalidRole returned when role is not one of the known roles
+var ErrInvalidRole = fmt.Errorf("invalid user role")
+
+// Role of a user, grants a set of permissions
+type Role string
+
+const (
+	// RoleAdmin manages users, sessions and roles
+	RoleAdmin Role = "admin"
+	// RoleOperator registers and looks up customers
+	RoleOperator Role = "operator"
+	// RoleCustomer can only access its own record
+	RoleCustomer Role = "customer"
+)
+
+// Permission required by an operation
+type Permission string
+
+const (
+	// PermissionUsersCreate allows creating customers
+	PermissionUsersCreate Permission = "users:create"
+	// PermissionUsersRead allows reading any user
+	PermissionUsersRead Permission = "users:read"
+	// PermissionUsersUpdate allows changing any user's profile and password
+	PermissionUsersUpdate Permission = "users:update"
+	// PermissionUsersDelete allows soft deleting any user
+	PermissionUsersDelete Permission = "users:delete"
+	// PermissionUsersRestore allows restoring soft deleted users
+	PermissionUsersRestore Permission = "users:restore"
+	// PermissionUsersUnlock allows clearing login lockouts
+	PermissionUsersUnlock Permission = "users:unlock"
+	// PermissionSessionsRead allows listing sessions of any user
+	PermissionSessionsRead Permission = "sessions:read"
+	// PermissionSessionsRevoke allows revoking sessions of any user
+	PermissionSessionsRevoke Permission = "sessions:revoke"
+	// PermissionRolesAssign allows creating users with roles other than customer
+	PermissionRolesAssign Permission = "roles:assign"
+	// PermissionAPIKeysManage allows creating, listing and revoking API keys
+	PermissionAPIKeysManage Permission = "apikeys:manage"
+	// PermissionClientsManage allows registering and disabling OAuth2 clients
+	PermissionClientsManage Permission = "clients:manage"
+	// PermissionScreeningsRead allows reviewing PLD screening history of any
+	// user
+	PermissionScreeningsRead Permission = "screenings:read"
+)
+
+// permissions every known permission
+var permissions = []Permission{
+	PermissionUsersCreate,
+	PermissionUsersRead,
+	PermissionUsersUpdate,
+	PermissionUsersDelete,
+	PermissionUsersRestore,
+	PermissionUsersUnlock,
+	PermissionSessionsRead,
+	PermissionSessionsRevoke,
+	PermissionRolesAssign,
+	PermissionAPIKeysManage,
+	PermissionClientsManage,
+	PermissionScreeningsRead,
+}
+
+// rolePermissions permissions granted to each role, access to a user's own
+// record does not need any permission
+var rolePermissions = map[Role][]Permission{
+	RoleAdmin: permissions,
+	RoleOperator: {
+		PermissionUsersCreate,
+		PermissionUsersRead,
+		PermissionScreeningsRead,
+	},
+	RoleCustomer: {},
+}
+
+// Valid reports whether role is one of the known roles
+func (r Role) Valid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// Can reports whether role grants given permission
+func (r Role) Can(permission Permission) bool {
+	for _, granted := range rolePermissions[r] {
+		if granted == permission {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Valid reports whether permission is one of the known permissions
+func (p Permission) Valid() bool {
+	for _, permission := range permissions {
+		if permission == p {
+			return true
+		}
+	}
+
+	return false
+}
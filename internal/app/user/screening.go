@@ -0,0 +1,106 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	pld "github.com/alamrios/crabi-solution/internal/app/pld"
+)
+
+// ScreeningBatchSize users screened at most on every ScreenPendingUsers call
+const ScreeningBatchSize = 100
+
+// ErrScreeningPending returned on login of users registered while PLD
+// service was unavailable and not screened yet
+var ErrScreeningPending = fmt.Errorf("user's pld screening is pending")
+
+// ErrBlacklisted returned when PLD screening finds the user in a blacklist,
+// also on login of users found after registering
+var ErrBlacklisted = fmt.Errorf("user was found in pld blacklist")
+
+// ErrScreeningReview returned when PLD screening can not clear the user
+// without a manual review
+var ErrScreeningReview = fmt.Errorf("user needs a manual pld review")
+
+// ScreeningError carries the PLD screening result that did not clear a user
+type ScreeningError struct {
+	// UserID id the screening attempts were recorded under, rejected
+	// applicants are not stored so it is the way to find their screenings
+	UserID string
+	Result pld.ScreeningResult
+}
+
+// Error returns message of the screening decision
+func (e *ScreeningError) Error() string {
+	if e.Result.Decision == pld.DecisionReview {
+		return ErrScreeningReview.Error()
+	}
+
+	return ErrBlacklisted.Error()
+}
+
+// Is makes errors.Is(err, ErrBlacklisted) match hits and
+// errors.Is(err, ErrScreeningReview) match reviews
+func (e *ScreeningError) Is(target error) bool {
+	if e.Result.Decision == pld.DecisionReview {
+		return target == ErrScreeningReview
+	}
+
+	return target == ErrBlacklisted
+}
+
+// checkScreening returns ScreeningError unless given result clears user with
+// given id
+func checkScreening(userID string, result *pld.ScreeningResult) error {
+	if result.Decision == pld.DecisionClear {
+		return nil
+	}
+
+	return &ScreeningError{UserID: userID, Result: *result}
+}
+
+// ScreenPendingUsers checks users registered while PLD service was
+// unavailable against PLD blacklist, oldest first. Users that pass can log
+// in, found users are rejected and partial matches wait for a manual review.
+// Returns the number of screened users, it stops at the first PLD failure so
+// the rest wait for the next call.
+func (s *Service) ScreenPendingUsers(ctx context.Context) (int, error) {
+	users, err := s.userRepo.GetUsersByScreening(ctx, ScreeningPending, ScreeningBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	screened := 0
+	for _, user := range users {
+		result, err := s.pldService.CheckBlacklist(
+			ctx,
+			pld.Request{
+				UserID:    user.ID,
+				FirstName: user.FirstName,
+				LastName:  user.LastName,
+				Email:     user.Email,
+			})
+		if err != nil {
+			return screened, err
+		}
+
+		var screening ScreeningStatus
+		switch result.Decision {
+		case pld.DecisionHit:
+			log.Printf("user %s was found in pld blacklist after registering", user.ID)
+			screening = ScreeningRejected
+		case pld.DecisionReview:
+			log.Printf("user %s needs a manual pld review after registering", user.ID)
+			screening = ScreeningReview
+		}
+
+		err = s.userRepo.UpdateScreening(ctx, user.Email, screening)
+		if err != nil {
+			return screened, err
+		}
+		screened++
+	}
+
+	return screened, nil
+}
@@ -0,0 +1,131 @@
+package user_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/alamrios/crabi-solution/internal/app/pld"
+	"github.com/alamrios/crabi-solution/internal/app/user"
+	tmock "github.com/alamrios/crabi-solution/test/mock"
+)
+
+func TestScreenPendingUsers(t *testing.T) {
+	ctx := context.Background()
+
+	users := []user.User{
+		{ID: "user1", FirstName: "Dua", LastName: "Lipa", Email: "dua@lipa.com", Screening: user.ScreeningPending},
+		{ID: "user2", FirstName: "Joaquin", LastName: "Guzman", Email: "joaquin@guzman.com", Screening: user.ScreeningPending},
+		{ID: "user3", FirstName: "Rina", LastName: "Sawayama", Email: "rina@sawayama.com", Screening: user.ScreeningPending},
+	}
+
+	getUsersCall := tmock.Call{
+		FunctionName: "GetUsersByScreening",
+		Params:       []interface{}{user.ScreeningPending, user.ScreeningBatchSize},
+		Returns:      []interface{}{users, nil},
+	}
+
+	checkCall := func(u user.User, decision pld.Decision, err error) tmock.Call {
+		result := &pld.ScreeningResult{Decision: decision}
+		if err != nil {
+			result = nil
+		}
+
+		return tmock.Call{
+			FunctionName: "CheckBlacklist",
+			Params: []interface{}{
+				pld.Request{UserID: u.ID, FirstName: u.FirstName, LastName: u.LastName, Email: u.Email},
+			},
+			Returns: []interface{}{result, err},
+		}
+	}
+
+	testCases := map[string]struct {
+		pldServiceCalls []tmock.Call
+		userRepoCalls   []tmock.Call
+		expected        int
+		expectedError   error
+	}{
+		"passed, found and partially matched users should be screened": {
+			pldServiceCalls: []tmock.Call{
+				checkCall(users[0], pld.DecisionClear, nil),
+				checkCall(users[1], pld.DecisionHit, nil),
+				checkCall(users[2], pld.DecisionReview, nil),
+			},
+			userRepoCalls: []tmock.Call{
+				getUsersCall,
+				{
+					FunctionName: "UpdateScreening",
+					Params:       []interface{}{users[0].Email, user.ScreeningStatus("")},
+					Returns:      []interface{}{nil},
+				},
+				{
+					FunctionName: "UpdateScreening",
+					Params:       []interface{}{users[1].Email, user.ScreeningRejected},
+					Returns:      []interface{}{nil},
+				},
+				{
+					FunctionName: "UpdateScreening",
+					Params:       []interface{}{users[2].Email, user.ScreeningReview},
+					Returns:      []interface{}{nil},
+				},
+			},
+			expected: 3,
+		},
+		"pld failure should stop screening": {
+			pldServiceCalls: []tmock.Call{
+				checkCall(users[0], pld.DecisionClear, nil),
+				checkCall(users[1], "", pld.ErrScreeningDeferred),
+			},
+			userRepoCalls: []tmock.Call{
+				getUsersCall,
+				{
+					FunctionName: "UpdateScreening",
+					Params:       []interface{}{users[0].Email, user.ScreeningStatus("")},
+					Returns:      []interface{}{nil},
+				},
+			},
+			expected:      1,
+			expectedError: pld.ErrScreeningDeferred,
+		},
+		"user repo error should propagate": {
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetUsersByScreening",
+					Params:       []interface{}{user.ScreeningPending, user.ScreeningBatchSize},
+					Returns:      []interface{}{[]user.User(nil), fmt.Errorf("user repo error")},
+				},
+			},
+			expectedError: fmt.Errorf("user repo error"),
+		},
+	}
+
+	for name, tc := range testCases {
+		pldService := tmock.NewPLDService().AddCall(t, tc.pldServiceCalls)
+		userRepo := tmock.NewUserRepository().AddCall(t, tc.userRepoCalls)
+
+		userService, err := user.NewService(
+			pldService,
+			userRepo,
+			tmock.NewPasswordHasher(),
+			user.LockoutPolicy{},
+			user.PasswordPolicy{},
+			tmock.NewEmailVerifier(),
+			tmock.NewSessionRevoker(),
+		)
+		assert.NoError(t, err)
+
+		expected := tc.expected
+		expectedError := tc.expectedError
+
+		t.Run(name, func(t *testing.T) {
+			got, err := userService.ScreenPendingUsers(ctx)
+			assert.Equal(t, expectedError, err)
+			assert.Equal(t, expected, got)
+			pldService.AssertExpectations(t)
+			userRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -2,21 +2,53 @@ package user
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
+	"errors"
 	"fmt"
+	"log"
+	"sync"
+	"time"
 
 	pld "github.com/alamrios/crabi-solution/internal/app/pld"
 )
 
+// ErrInvalidCredentials returned when email or password do not match
+var ErrInvalidCredentials = fmt.Errorf("user not exists or invalid credentials")
+
+// ErrUserNotFound returned when the requested user does not exist
+var ErrUserNotFound = fmt.Errorf("user not exists")
+
+// ErrEmailNotVerified returned on login of users that did not confirm their email
+var ErrEmailNotVerified = fmt.Errorf("user's email is not verified")
+
+// dummyPassword password of the hash checked on logins of unknown users
+const dummyPassword = "crabi-solution-dummy-password"
+
 // Service struct for users service
 type Service struct {
 	pldService pld.Service
 	userRepo   Repository
+	hasher     PasswordHasher
+	lockout    LockoutPolicy
+	policy     PasswordPolicy
+	verifier   EmailVerifier
+	sessions   SessionRevoker
+
+	// dummyHash hash of dummyPassword made once with the configured hasher
+	// so it costs as much as stored passwords
+	dummyHash string
+	dummyOnce sync.Once
 }
 
 // NewService returns an instance of users service
-func NewService(pldService pld.Service, userRepo Repository) (*Service, error) {
+func NewService(
+	pldService pld.Service,
+	userRepo Repository,
+	hasher PasswordHasher,
+	lockout LockoutPolicy,
+	policy PasswordPolicy,
+	verifier EmailVerifier,
+	sessions SessionRevoker,
+) (*Service, error) {
 	if pldService == nil {
 		return nil, fmt.Errorf("pld service is nil")
 	}
@@ -25,13 +57,43 @@ func NewService(pldService pld.Service, userRepo Repository) (*Service, error) {
 		return nil, fmt.Errorf("user repo is nil")
 	}
 
+	if hasher == nil {
+		return nil, fmt.Errorf("password hasher is nil")
+	}
+
+	if verifier == nil {
+		return nil, fmt.Errorf("email verifier is nil")
+	}
+
+	if sessions == nil {
+		return nil, fmt.Errorf("session revoker is nil")
+	}
+
+	if lockout.MaxAttempts > 0 && (lockout.Window <= 0 || lockout.Duration <= 0) {
+		return nil, fmt.Errorf("lockout window and duration should be greater than zero")
+	}
+
+	err := policy.validate()
+	if err != nil {
+		return nil, err
+	}
+
 	return &Service{
 		pldService: pldService,
 		userRepo:   userRepo,
+		hasher:     hasher,
+		lockout:    lockout,
+		policy:     policy,
+		verifier:   verifier,
+		sessions:   sessions,
 	}, nil
 }
 
-// CreateUser stores given user in users repository if valid, error otherwise
+// CreateUser stores given user in users repository if valid, error otherwise.
+// New users stay pending until they follow the verification link sent to
+// their email, users without role are created as customers. Users found in
+// a PLD blacklist are not stored, partial matches are stored waiting for a
+// manual review as ScreenPendingUsers does.
 func (s *Service) CreateUser(ctx context.Context, user User) (*User, error) {
 	if user.FirstName == "" {
 		return nil, fmt.Errorf("user's first name should not be empty")
@@ -45,16 +107,47 @@ func (s *Service) CreateUser(ctx context.Context, user User) (*User, error) {
 	if user.Password == "" {
 		return nil, fmt.Errorf("user's password should not be empty")
 	}
+	if user.Role == "" {
+		user.Role = RoleCustomer
+	}
+	if !user.Role.Valid() {
+		return nil, ErrInvalidRole
+	}
+
+	err := s.checkPassword(&User{
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Email:     user.Email,
+	}, user.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	// id is set before screening so the attempt is recorded for the user
+	user.ID, err = NewID()
+	if err != nil {
+		return nil, err
+	}
 
-	pldErr := s.pldService.CheckBlacklist(
+	var screening ScreeningStatus
+	result, pldErr := s.pldService.CheckBlacklist(
 		ctx,
 		pld.Request{
+			UserID:    user.ID,
 			FirstName: user.FirstName,
 			LastName:  user.LastName,
 			Email:     user.Email,
 		})
-	if pldErr != nil {
+	if errors.Is(pldErr, pld.ErrScreeningDeferred) {
+		// screened by ScreenPendingUsers once PLD service recovers
+		screening = ScreeningPending
+	} else if pldErr != nil {
 		return nil, pldErr
+	} else if err = checkScreening(user.ID, result); errors.Is(err, ErrScreeningReview) {
+		log.Printf("user %s needs a manual pld review", user.ID)
+		screening = ScreeningReview
+	} else if err != nil {
+		return nil, err
 	}
 
 	duplicate, err := s.userRepo.GetUserByEmail(ctx, user.Email)
@@ -62,18 +155,42 @@ func (s *Service) CreateUser(ctx context.Context, user User) (*User, error) {
 		return nil, err
 	}
 
+	if duplicate == nil {
+		// deleted users keep their email until purged so they can be restored
+		duplicate, err = s.userRepo.GetDeletedUserByEmail(ctx, user.Email)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if duplicate != nil {
 		return nil, fmt.Errorf("user with email %s already exists", user.Email)
 	}
 
-	hash := sha256.Sum256([]byte(user.Password))
-	user.Password = hex.EncodeToString(hash[:])
+	hash, err := s.hasher.Hash(user.Password)
+	if err != nil {
+		return nil, err
+	}
+	user.Password = hash
+	user.Status = StatusPendingVerification
+	user.Screening = screening
+	user.EmailDomain = emailDomain(user.Email)
+	user.CreatedAt = time.Now().UTC()
 
 	rErr := s.userRepo.SaveUser(ctx, user)
+	if errors.Is(rErr, ErrDuplicateEmail) {
+		// registered concurrently since the duplicate check
+		return nil, fmt.Errorf("user with email %s already exists", user.Email)
+	}
 	if rErr != nil {
 		return nil, rErr
 	}
 
+	err = s.verifier.SendVerification(ctx, user.Email)
+	if err != nil {
+		log.Printf("failed to send email verification to %s: %v", user.Email, err)
+	}
+
 	return &user, nil
 }
 
@@ -86,23 +203,136 @@ func (s *Service) Login(ctx context.Context, email, password string) (*User, err
 		return nil, fmt.Errorf("user's password should not be empty")
 	}
 
-	hash := sha256.Sum256([]byte(password))
-	ePassword := hex.EncodeToString(hash[:])
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		// unknown emails take as long as wrong passwords
+		s.verifyDummyHash(password)
+		return nil, ErrInvalidCredentials
+	}
 
-	user, err := s.userRepo.GetUserByEmailAndPassword(ctx, email, ePassword)
+	err = s.checkCredentials(ctx, user, password)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Status == StatusPendingVerification {
+		return nil, ErrEmailNotVerified
+	}
+
+	switch user.Screening {
+	case ScreeningPending, ScreeningReview:
+		return nil, ErrScreeningPending
+	case ScreeningRejected:
+		return nil, ErrBlacklisted
+	}
+
+	if user.LoginAttempts != (LoginAttempts{}) {
+		err = s.userRepo.UpdateLoginAttempts(ctx, user.Email, LoginAttempts{})
+		if err != nil {
+			return nil, err
+		}
+		user.LoginAttempts = LoginAttempts{}
+	}
+
+	if s.hasher.NeedsRehash(user.Password) {
+		s.rehashPassword(ctx, user, password)
+	}
+
+	return user, nil
+}
+
+// checkCredentials verifies password of given user honoring the lockout
+// policy, mismatches are recorded as failed login attempts
+func (s *Service) checkCredentials(ctx context.Context, user *User, password string) error {
+	now := time.Now().UTC()
+
+	retryAt := s.lockout.retryAt(user.LoginAttempts, now)
+	if retryAt != nil {
+		return &LockedError{Until: *retryAt}
+	}
+
+	valid, err := s.hasher.Verify(password, user.Password)
+	if err != nil {
+		return err
+	}
+
+	if !valid {
+		err = s.lockout.registerFailure(ctx, s.userRepo, user.Email, now)
+		if err != nil {
+			return err
+		}
+
+		return ErrInvalidCredentials
+	}
+
+	return nil
+}
+
+// verifyDummyHash checks given password against a dummy hash so logins of
+// unknown users spend the same time as the ones of registered users
+func (s *Service) verifyDummyHash(password string) {
+	s.dummyOnce.Do(func() {
+		hash, err := s.hasher.Hash(dummyPassword)
+		if err != nil {
+			log.Printf("failed to hash dummy password: %v", err)
+			return
+		}
+		s.dummyHash = hash
+	})
+
+	if s.dummyHash == "" {
+		return
+	}
+
+	_, err := s.hasher.Verify(password, s.dummyHash)
+	if err != nil {
+		log.Printf("failed to verify dummy password: %v", err)
+	}
+}
+
+// rehashPassword upgrades stored password hash to current hasher settings,
+// failures are logged and do not abort the login
+func (s *Service) rehashPassword(ctx context.Context, user *User, password string) {
+	hash, err := s.hasher.Hash(password)
+	if err != nil {
+		log.Printf("failed to rehash password for %s: %v", user.Email, err)
+		return
+	}
+
+	err = s.userRepo.UpdatePassword(ctx, user.Email, hash)
+	if err != nil {
+		log.Printf("failed to update rehashed password for %s: %v", user.Email, err)
+		return
+	}
+
+	user.Password = hash
+}
+
+// GetUser returns user with given id
+func (s *Service) GetUser(ctx context.Context, id string) (*User, error) {
+	if id == "" {
+		return nil, fmt.Errorf("user's id should not be empty")
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
 	if user == nil {
-		return nil, fmt.Errorf("user not exists or invalid credentials")
+		return nil, ErrUserNotFound
 	}
 
 	return user, nil
 }
 
-// GetUser returns user with given email
-func (s *Service) GetUser(ctx context.Context, email string) (*User, error) {
+// GetUserByEmail returns user with given email, for flows that only know
+// the email such as refreshing a session
+func (s *Service) GetUserByEmail(ctx context.Context, email string) (*User, error) {
 	if email == "" {
 		return nil, fmt.Errorf("user's email should not be empty")
 	}
@@ -113,8 +343,342 @@ func (s *Service) GetUser(ctx context.Context, email string) (*User, error) {
 	}
 
 	if user == nil {
-		return nil, fmt.Errorf("user not exists")
+		return nil, ErrUserNotFound
 	}
 
 	return user, nil
 }
+
+// RevokeSessions invalidates every access token issued to user with given
+// id until now
+func (s *Service) RevokeSessions(ctx context.Context, id string) error {
+	user, err := s.GetUser(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return s.revokeTokens(ctx, user.Email)
+}
+
+// revokeTokens invalidates every access token issued to user with given
+// email until now
+func (s *Service) revokeTokens(ctx context.Context, email string) error {
+	// tokens carry second precision issue dates
+	validAfter := time.Now().UTC().Truncate(time.Second)
+
+	return s.userRepo.UpdateTokensValidAfter(ctx, email, validAfter)
+}
+
+// UnlockUser clears failed login attempts and lockout of user with given id
+func (s *Service) UnlockUser(ctx context.Context, id string) error {
+	user, err := s.GetUser(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return s.userRepo.UpdateLoginAttempts(ctx, user.Email, LoginAttempts{})
+}
+
+// UpdateUser applies given profile changes to user with given id, a name
+// change is checked against the PLD blacklist again
+func (s *Service) UpdateUser(ctx context.Context, id string, update ProfileUpdate) (*User, error) {
+	if id == "" {
+		return nil, fmt.Errorf("user's id should not be empty")
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	updated := *user
+	if update.FirstName != nil {
+		updated.FirstName = *update.FirstName
+	}
+	if update.LastName != nil {
+		updated.LastName = *update.LastName
+	}
+
+	if updated.FirstName == "" {
+		return nil, fmt.Errorf("user's first name should not be empty")
+	}
+	if updated.LastName == "" {
+		return nil, fmt.Errorf("user's last name should not be empty")
+	}
+
+	if updated.FirstName == user.FirstName && updated.LastName == user.LastName {
+		return user, nil
+	}
+
+	result, err := s.pldService.CheckBlacklist(
+		ctx,
+		pld.Request{
+			UserID:    user.ID,
+			FirstName: updated.FirstName,
+			LastName:  updated.LastName,
+			Email:     updated.Email,
+		})
+	if errors.Is(err, pld.ErrScreeningDeferred) {
+		// profile changes are never left unscreened
+		return nil, pld.ErrUnavailable
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkScreening(user.ID, result)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.userRepo.UpdateUser(ctx, updated)
+	if err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+// ChangePassword replaces password of user with given id after checking
+// its current password and ends every session of the user, failures count
+// towards the lockout policy
+func (s *Service) ChangePassword(ctx context.Context, id, currentPassword, newPassword string) error {
+	if id == "" {
+		return fmt.Errorf("user's id should not be empty")
+	}
+	if currentPassword == "" {
+		return fmt.Errorf("user's current password should not be empty")
+	}
+	if newPassword == "" {
+		return fmt.Errorf("user's password should not be empty")
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if user == nil {
+		return ErrUserNotFound
+	}
+
+	err = s.checkCredentials(ctx, user, currentPassword)
+	if err != nil {
+		return err
+	}
+
+	err = s.checkPassword(user, newPassword)
+	if err != nil {
+		return err
+	}
+
+	err = s.updatePassword(ctx, user, newPassword)
+	if err != nil {
+		return err
+	}
+
+	return s.sessions.DeleteSessions(ctx, user.Email)
+}
+
+// SetPassword replaces password of user with given email, clears any
+// lockout and revokes every access token issued before
+func (s *Service) SetPassword(ctx context.Context, email, password string) error {
+	if email == "" {
+		return fmt.Errorf("user's email should not be empty")
+	}
+	if password == "" {
+		return fmt.Errorf("user's password should not be empty")
+	}
+
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	if user == nil {
+		return ErrUserNotFound
+	}
+
+	err = s.checkPassword(user, password)
+	if err != nil {
+		return err
+	}
+
+	return s.updatePassword(ctx, user, password)
+}
+
+// checkPassword returns a PasswordPolicyError with every rule given password
+// fails for given user, including known breached passwords and reuse of the
+// user's last passwords
+func (s *Service) checkPassword(user *User, password string) error {
+	violations := s.policy.check(user, password)
+
+	if s.policy.Breached != nil {
+		breached, err := s.policy.Breached.Contains(password)
+		if err != nil {
+			return err
+		}
+
+		if breached {
+			violations = append(violations, PasswordViolation{
+				Rule:    RuleBreached,
+				Message: "password appears in a list of compromised passwords",
+			})
+		}
+	}
+
+	reused, err := s.reusesPassword(user, password)
+	if err != nil {
+		return err
+	}
+
+	if reused {
+		violations = append(violations, PasswordViolation{
+			Rule:    RuleReused,
+			Message: fmt.Sprintf("password should not match any of the last %d passwords", s.policy.HistorySize),
+		})
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+
+	return nil
+}
+
+// reusesPassword reports whether given password matches the current or one
+// of the previous passwords kept by the policy history
+func (s *Service) reusesPassword(user *User, password string) (bool, error) {
+	if s.policy.HistorySize <= 0 || user.Password == "" {
+		return false, nil
+	}
+
+	hashes := append([]string{user.Password}, user.PasswordHistory...)
+	if len(hashes) > s.policy.HistorySize {
+		hashes = hashes[:s.policy.HistorySize]
+	}
+
+	for _, hash := range hashes {
+		valid, err := s.hasher.Verify(password, hash)
+		if err != nil && err != ErrUnsupportedHash {
+			return false, err
+		}
+
+		if valid {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// updatePassword stores given password for user, keeps the replaced hash in
+// the password history, clears any lockout and revokes every access token
+// issued before
+func (s *Service) updatePassword(ctx context.Context, user *User, password string) error {
+	hash, err := s.hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	if s.policy.HistorySize > 1 && user.Password != "" {
+		history := append([]string{user.Password}, user.PasswordHistory...)
+		if len(history) > s.policy.HistorySize-1 {
+			history = history[:s.policy.HistorySize-1]
+		}
+
+		err = s.userRepo.UpdatePasswordHistory(ctx, user.Email, history)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = s.userRepo.UpdatePassword(ctx, user.Email, hash)
+	if err != nil {
+		return err
+	}
+
+	if user.LoginAttempts != (LoginAttempts{}) {
+		err = s.userRepo.UpdateLoginAttempts(ctx, user.Email, LoginAttempts{})
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.revokeTokens(ctx, user.Email)
+}
+
+// AssignRole replaces role of user with given email
+func (s *Service) AssignRole(ctx context.Context, email string, role Role) error {
+	if email == "" {
+		return fmt.Errorf("user's email should not be empty")
+	}
+	if !role.Valid() {
+		return ErrInvalidRole
+	}
+
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	if user == nil {
+		return ErrUserNotFound
+	}
+
+	if user.Role == role {
+		return nil
+	}
+
+	return s.userRepo.UpdateRole(ctx, email, role)
+}
+
+// DeleteUser soft deletes user with given id, deleted users can not log in
+// nor be found until restored and are purged after the retention period
+func (s *Service) DeleteUser(ctx context.Context, id string) error {
+	user, err := s.GetUser(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return s.userRepo.DeleteUser(ctx, user.Email, time.Now().UTC())
+}
+
+// RestoreUser undoes soft deletion of user with given id
+func (s *Service) RestoreUser(ctx context.Context, id string) (*User, error) {
+	if id == "" {
+		return nil, fmt.Errorf("user's id should not be empty")
+	}
+
+	user, err := s.userRepo.GetDeletedUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		return nil, fmt.Errorf("deleted user not exists")
+	}
+
+	err = s.userRepo.RestoreUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	user.DeletedAt = nil
+
+	return user, nil
+}
+
+// PurgeDeletedUsers permanently removes users soft deleted longer than given
+// retention, returns the number of removed users
+func (s *Service) PurgeDeletedUsers(ctx context.Context, retention time.Duration) (int64, error) {
+	if retention <= 0 {
+		return 0, fmt.Errorf("deleted users retention should be greater than zero")
+	}
+
+	return s.userRepo.PurgeUsers(ctx, time.Now().UTC().Add(-retention))
+}
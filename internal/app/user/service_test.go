@@ -2,27 +2,52 @@ package user_test
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
+	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 
 	"github.com/alamrios/crabi-solution/internal/app/pld"
 	"github.com/alamrios/crabi-solution/internal/app/user"
 	tmock "github.com/alamrios/crabi-solution/test/mock"
 )
 
+// clearScreening PLD result of users not found in any blacklist
+var clearScreening = &pld.ScreeningResult{Decision: pld.DecisionClear}
+
+// reviewScreening PLD result of users that need a manual review
+var reviewScreening = &pld.ScreeningResult{
+	Decision:    pld.DecisionReview,
+	Score:       72.5,
+	ReferenceID: "scr_2",
+}
+
+// hitScreening PLD result of users found in a blacklist
+var hitScreening = &pld.ScreeningResult{
+	Decision:    pld.DecisionHit,
+	Lists:       []string{"OFAC"},
+	Score:       98,
+	ReferenceID: "scr_1",
+}
+
 func TestNewService(t *testing.T) {
 	testCases := map[string]struct {
 		pldService pld.Service
 		userRepo   user.Repository
+		hasher     user.PasswordHasher
+		verifier   user.EmailVerifier
+		sessions   user.SessionRevoker
 		err        string
 	}{
 		"success": {
 			pldService: &tmock.PLDService{},
 			userRepo:   &tmock.UserRepository{},
+			hasher:     &tmock.PasswordHasher{},
+			verifier:   &tmock.EmailVerifier{},
+			sessions:   &tmock.SessionRevoker{},
 		},
 		"missing pld service": {
 			err: "pld service is nil",
@@ -31,11 +56,29 @@ func TestNewService(t *testing.T) {
 			pldService: &tmock.PLDService{},
 			err:        "user repo is nil",
 		},
+		"missing password hasher": {
+			pldService: &tmock.PLDService{},
+			userRepo:   &tmock.UserRepository{},
+			err:        "password hasher is nil",
+		},
+		"missing email verifier": {
+			pldService: &tmock.PLDService{},
+			userRepo:   &tmock.UserRepository{},
+			hasher:     &tmock.PasswordHasher{},
+			err:        "email verifier is nil",
+		},
+		"missing session revoker": {
+			pldService: &tmock.PLDService{},
+			userRepo:   &tmock.UserRepository{},
+			hasher:     &tmock.PasswordHasher{},
+			verifier:   &tmock.EmailVerifier{},
+			err:        "session revoker is nil",
+		},
 	}
 
 	for name, tc := range testCases {
 		t.Run(name, func(t *testing.T) {
-			got, err := user.NewService(tc.pldService, tc.userRepo)
+			got, err := user.NewService(tc.pldService, tc.userRepo, tc.hasher, user.LockoutPolicy{}, user.PasswordPolicy{}, tc.verifier, tc.sessions)
 			if tc.err != "" {
 				assert.EqualError(t, err, tc.err)
 				assert.Nil(t, got)
@@ -55,20 +98,67 @@ func TestCreateUser(t *testing.T) {
 		Password:  "dua123lipa",
 	}
 
-	hash := sha256.Sum256([]byte(input1.Password))
-	ePassword := hex.EncodeToString(hash[:])
+	ePassword := "$argon2id$v=19$m=65536,t=3,p=2$c2FsdA$aGFzaA"
 
 	input1e := user.User{
-		FirstName: "Dua",
-		LastName:  "Lipa",
-		Email:     "dua@lipa.com",
-		Password:  ePassword,
+		FirstName:   "Dua",
+		LastName:    "Lipa",
+		Email:       "dua@lipa.com",
+		Password:    ePassword,
+		Status:      user.StatusPendingVerification,
+		Role:        user.RoleCustomer,
+		EmailDomain: "lipa.com",
+	}
+
+	// id and creation date are set when the user is saved
+	savedInput1e := mock.MatchedBy(func(got user.User) bool {
+		id, createdAt := got.ID, got.CreatedAt
+		got.ID, got.CreatedAt = "", time.Time{}
+		return id != "" && !createdAt.IsZero() && assert.ObjectsAreEqual(input1e, got)
+	})
+
+	reviewInput1e := input1e
+	reviewInput1e.Screening = user.ScreeningReview
+	savedReviewInput1e := mock.MatchedBy(func(got user.User) bool {
+		got.ID, got.CreatedAt = "", time.Time{}
+		return assert.ObjectsAreEqual(reviewInput1e, got)
+	})
+
+	pendingInput1e := input1e
+	pendingInput1e.Screening = user.ScreeningPending
+	savedPendingInput1e := mock.MatchedBy(func(got user.User) bool {
+		got.ID, got.CreatedAt = "", time.Time{}
+		return assert.ObjectsAreEqual(pendingInput1e, got)
+	})
+
+	// id is generated before screening the user
+	screenedInput1 := mock.MatchedBy(func(got pld.Request) bool {
+		id := got.UserID
+		got.UserID = ""
+		return id != "" && assert.ObjectsAreEqual(pld.Request{
+			FirstName: input1.FirstName,
+			LastName:  input1.LastName,
+			Email:     input1.Email,
+		}, got)
+	})
+
+	hashCall := tmock.Call{
+		FunctionName: "Hash",
+		Params: []interface{}{
+			input1.Password,
+		},
+		Returns: []interface{}{
+			ePassword,
+			nil,
+		},
 	}
 
 	testCases := map[string]struct {
 		input           user.User
 		pldServiceCalls []tmock.Call
 		userRepoCalls   []tmock.Call
+		hasherCalls     []tmock.Call
+		verifierCalls   []tmock.Call
 		expectedError   error
 	}{
 		"success": {
@@ -77,13 +167,203 @@ func TestCreateUser(t *testing.T) {
 				{
 					FunctionName: "CheckBlacklist",
 					Params: []interface{}{
-						pld.Request{
-							FirstName: input1.FirstName,
-							LastName:  input1.LastName,
-							Email:     input1.Email,
-						},
+						screenedInput1,
+					},
+					Returns: []interface{}{
+						clearScreening,
+						nil,
+					},
+				},
+			},
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetUserByEmail",
+					Params: []interface{}{
+						input1.Email,
+					},
+					Returns: []interface{}{
+						(*user.User)(nil),
+						nil,
+					},
+				},
+				{
+					FunctionName: "GetDeletedUserByEmail",
+					Params: []interface{}{
+						input1.Email,
+					},
+					Returns: []interface{}{
+						(*user.User)(nil),
+						nil,
+					},
+				},
+				{
+					FunctionName: "SaveUser",
+					Params: []interface{}{
+						savedInput1e,
+					},
+					Returns: []interface{}{
+						nil,
+					},
+				},
+			},
+			hasherCalls: []tmock.Call{hashCall},
+			verifierCalls: []tmock.Call{
+				{
+					FunctionName: "SendVerification",
+					Params: []interface{}{
+						input1.Email,
+					},
+					Returns: []interface{}{
+						nil,
+					},
+				},
+			},
+			expectedError: nil,
+		},
+		"deferred pld screening should save pending user": {
+			input: input1,
+			pldServiceCalls: []tmock.Call{
+				{
+					FunctionName: "CheckBlacklist",
+					Params: []interface{}{
+						screenedInput1,
+					},
+					Returns: []interface{}{
+						(*pld.ScreeningResult)(nil),
+						pld.ErrScreeningDeferred,
+					},
+				},
+			},
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetUserByEmail",
+					Params: []interface{}{
+						input1.Email,
+					},
+					Returns: []interface{}{
+						(*user.User)(nil),
+						nil,
+					},
+				},
+				{
+					FunctionName: "GetDeletedUserByEmail",
+					Params: []interface{}{
+						input1.Email,
+					},
+					Returns: []interface{}{
+						(*user.User)(nil),
+						nil,
+					},
+				},
+				{
+					FunctionName: "SaveUser",
+					Params: []interface{}{
+						savedPendingInput1e,
+					},
+					Returns: []interface{}{
+						nil,
+					},
+				},
+			},
+			hasherCalls: []tmock.Call{hashCall},
+			verifierCalls: []tmock.Call{
+				{
+					FunctionName: "SendVerification",
+					Params: []interface{}{
+						input1.Email,
+					},
+					Returns: []interface{}{
+						nil,
+					},
+				},
+			},
+			expectedError: nil,
+		},
+		"pld review should save user waiting for review": {
+			input: input1,
+			pldServiceCalls: []tmock.Call{
+				{
+					FunctionName: "CheckBlacklist",
+					Params: []interface{}{
+						screenedInput1,
+					},
+					Returns: []interface{}{
+						reviewScreening,
+						nil,
+					},
+				},
+			},
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetUserByEmail",
+					Params: []interface{}{
+						input1.Email,
+					},
+					Returns: []interface{}{
+						(*user.User)(nil),
+						nil,
+					},
+				},
+				{
+					FunctionName: "GetDeletedUserByEmail",
+					Params: []interface{}{
+						input1.Email,
+					},
+					Returns: []interface{}{
+						(*user.User)(nil),
+						nil,
+					},
+				},
+				{
+					FunctionName: "SaveUser",
+					Params: []interface{}{
+						savedReviewInput1e,
+					},
+					Returns: []interface{}{
+						nil,
+					},
+				},
+			},
+			hasherCalls: []tmock.Call{hashCall},
+			verifierCalls: []tmock.Call{
+				{
+					FunctionName: "SendVerification",
+					Params: []interface{}{
+						input1.Email,
+					},
+					Returns: []interface{}{
+						nil,
+					},
+				},
+			},
+			expectedError: nil,
+		},
+		"unavailable pld service should return error": {
+			input: input1,
+			pldServiceCalls: []tmock.Call{
+				{
+					FunctionName: "CheckBlacklist",
+					Params: []interface{}{
+						screenedInput1,
+					},
+					Returns: []interface{}{
+						(*pld.ScreeningResult)(nil),
+						pld.ErrUnavailable,
+					},
+				},
+			},
+			expectedError: pld.ErrUnavailable,
+		},
+		"verification delivery error should not return error": {
+			input: input1,
+			pldServiceCalls: []tmock.Call{
+				{
+					FunctionName: "CheckBlacklist",
+					Params: []interface{}{
+						screenedInput1,
 					},
 					Returns: []interface{}{
+						clearScreening,
 						nil,
 					},
 				},
@@ -99,18 +379,50 @@ func TestCreateUser(t *testing.T) {
 						nil,
 					},
 				},
+				{
+					FunctionName: "GetDeletedUserByEmail",
+					Params: []interface{}{
+						input1.Email,
+					},
+					Returns: []interface{}{
+						(*user.User)(nil),
+						nil,
+					},
+				},
 				{
 					FunctionName: "SaveUser",
 					Params: []interface{}{
-						input1e,
+						savedInput1e,
 					},
 					Returns: []interface{}{
 						nil,
 					},
 				},
 			},
+			hasherCalls: []tmock.Call{hashCall},
+			verifierCalls: []tmock.Call{
+				{
+					FunctionName: "SendVerification",
+					Params: []interface{}{
+						input1.Email,
+					},
+					Returns: []interface{}{
+						fmt.Errorf("notifier error"),
+					},
+				},
+			},
 			expectedError: nil,
 		},
+		"unknown role should return error": {
+			input: user.User{
+				FirstName: "Dua",
+				LastName:  "Lipa",
+				Email:     "dua@lipa.com",
+				Password:  "dua123lipa",
+				Role:      "root",
+			},
+			expectedError: user.ErrInvalidRole,
+		},
 		"empty first name should return error": {
 			input:         user.User{},
 			expectedError: fmt.Errorf("user's first name should not be empty"),
@@ -142,18 +454,31 @@ func TestCreateUser(t *testing.T) {
 				{
 					FunctionName: "CheckBlacklist",
 					Params: []interface{}{
-						pld.Request{
-							FirstName: input1.FirstName,
-							LastName:  input1.LastName,
-							Email:     input1.Email,
-						},
+						screenedInput1,
+					},
+					Returns: []interface{}{
+						(*pld.ScreeningResult)(nil),
+						fmt.Errorf("pld server returned 500 status code"),
+					},
+				},
+			},
+			expectedError: fmt.Errorf("pld server returned 500 status code"),
+		},
+		"blacklisted user should return error": {
+			input: input1,
+			pldServiceCalls: []tmock.Call{
+				{
+					FunctionName: "CheckBlacklist",
+					Params: []interface{}{
+						screenedInput1,
 					},
 					Returns: []interface{}{
-						fmt.Errorf("user found in pld blacklist"),
+						hitScreening,
+						nil,
 					},
 				},
 			},
-			expectedError: fmt.Errorf("user found in pld blacklist"),
+			expectedError: &user.ScreeningError{Result: *hitScreening},
 		},
 		"user repository error while GetUserByEmail should propagate": {
 			input: input1,
@@ -161,13 +486,10 @@ func TestCreateUser(t *testing.T) {
 				{
 					FunctionName: "CheckBlacklist",
 					Params: []interface{}{
-						pld.Request{
-							FirstName: input1.FirstName,
-							LastName:  input1.LastName,
-							Email:     input1.Email,
-						},
+						screenedInput1,
 					},
 					Returns: []interface{}{
+						clearScreening,
 						nil,
 					},
 				},
@@ -192,13 +514,10 @@ func TestCreateUser(t *testing.T) {
 				{
 					FunctionName: "CheckBlacklist",
 					Params: []interface{}{
-						pld.Request{
-							FirstName: input1.FirstName,
-							LastName:  input1.LastName,
-							Email:     input1.Email,
-						},
+						screenedInput1,
 					},
 					Returns: []interface{}{
+						clearScreening,
 						nil,
 					},
 				},
@@ -217,19 +536,16 @@ func TestCreateUser(t *testing.T) {
 			},
 			expectedError: fmt.Errorf("user with email %s already exists", input1.Email),
 		},
-		"user repository error while SaveUser should propagate": {
+		"email of soft deleted user should return error": {
 			input: input1,
 			pldServiceCalls: []tmock.Call{
 				{
 					FunctionName: "CheckBlacklist",
 					Params: []interface{}{
-						pld.Request{
-							FirstName: input1.FirstName,
-							LastName:  input1.LastName,
-							Email:     input1.Email,
-						},
+						screenedInput1,
 					},
 					Returns: []interface{}{
+						clearScreening,
 						nil,
 					},
 				},
@@ -246,120 +562,143 @@ func TestCreateUser(t *testing.T) {
 					},
 				},
 				{
-					FunctionName: "SaveUser",
+					FunctionName: "GetDeletedUserByEmail",
 					Params: []interface{}{
-						input1e,
+						input1.Email,
 					},
 					Returns: []interface{}{
-						fmt.Errorf("user repository error"),
+						&input1e,
+						nil,
 					},
 				},
 			},
-			expectedError: fmt.Errorf("user repository error"),
+			expectedError: fmt.Errorf("user with email %s already exists", input1.Email),
 		},
-	}
-
-	for name, tc := range testCases {
-		ctx := context.Background()
-		pldService := tmock.NewPLDService().AddCall(t, tc.pldServiceCalls)
-		userRepo := tmock.NewUserRepository().AddCall(t, tc.userRepoCalls)
-
-		userService, err := user.NewService(pldService, userRepo)
-		assert.NoError(t, err)
-
-		input := tc.input
-		expectedError := tc.expectedError
-
-		t.Run(name, func(t *testing.T) {
-			got, err := userService.CreateUser(ctx, input)
-
-			if expectedError == nil {
-				assert.NotNil(t, got)
-				assert.NoError(t, err)
-			} else {
-				assert.Equal(t, expectedError, err)
-				assert.Nil(t, got)
-			}
-		})
-	}
-}
-
-func TestLogin(t *testing.T) {
-	params1 := struct {
-		email    string
-		password string
-	}{
-		email:    "dua@lipa.com",
-		password: "dua123lipa",
-	}
-
-	user1 := &user.User{
-		FirstName: "Dua",
-		LastName:  "Lipa",
-		Email:     "dua@lipa.com",
-		Password:  "dua123lipa",
-	}
-
-	hash := sha256.Sum256([]byte(params1.password))
-	ePassword := hex.EncodeToString(hash[:])
-
-	testCases := map[string]struct {
-		email         string
-		password      string
-		userRepoCalls []tmock.Call
-		expectedError error
-	}{
-		"success": {
-			email:    params1.email,
-			password: params1.password,
+		"user repository error while SaveUser should propagate": {
+			input: input1,
+			pldServiceCalls: []tmock.Call{
+				{
+					FunctionName: "CheckBlacklist",
+					Params: []interface{}{
+						screenedInput1,
+					},
+					Returns: []interface{}{
+						clearScreening,
+						nil,
+					},
+				},
+			},
 			userRepoCalls: []tmock.Call{
 				{
-					FunctionName: "GetUserByEmailAndPassword",
+					FunctionName: "GetUserByEmail",
 					Params: []interface{}{
-						params1.email,
-						ePassword,
+						input1.Email,
 					},
 					Returns: []interface{}{
-						user1,
+						(*user.User)(nil),
+						nil,
+					},
+				},
+				{
+					FunctionName: "GetDeletedUserByEmail",
+					Params: []interface{}{
+						input1.Email,
+					},
+					Returns: []interface{}{
+						(*user.User)(nil),
 						nil,
 					},
 				},
+				{
+					FunctionName: "SaveUser",
+					Params: []interface{}{
+						savedInput1e,
+					},
+					Returns: []interface{}{
+						fmt.Errorf("user repository error"),
+					},
+				},
 			},
+			hasherCalls:   []tmock.Call{hashCall},
+			expectedError: fmt.Errorf("user repository error"),
 		},
-		"empty email should return error": {
-			expectedError: fmt.Errorf("user's email should not be empty"),
-		},
-		"empty password should return error": {
-			email:         params1.email,
-			expectedError: fmt.Errorf("user's password should not be empty"),
-		},
-		"user repo error should propagate": {
-			email:    params1.email,
-			password: params1.password,
+		"email registered concurrently should return error": {
+			input: input1,
+			pldServiceCalls: []tmock.Call{
+				{
+					FunctionName: "CheckBlacklist",
+					Params: []interface{}{
+						screenedInput1,
+					},
+					Returns: []interface{}{
+						clearScreening,
+						nil,
+					},
+				},
+			},
 			userRepoCalls: []tmock.Call{
 				{
-					FunctionName: "GetUserByEmailAndPassword",
+					FunctionName: "GetUserByEmail",
 					Params: []interface{}{
-						params1.email,
-						ePassword,
+						input1.Email,
 					},
 					Returns: []interface{}{
 						(*user.User)(nil),
-						fmt.Errorf("user repo error"),
+						nil,
+					},
+				},
+				{
+					FunctionName: "GetDeletedUserByEmail",
+					Params: []interface{}{
+						input1.Email,
+					},
+					Returns: []interface{}{
+						(*user.User)(nil),
+						nil,
+					},
+				},
+				{
+					FunctionName: "SaveUser",
+					Params: []interface{}{
+						savedInput1e,
+					},
+					Returns: []interface{}{
+						user.ErrDuplicateEmail,
 					},
 				},
 			},
-			expectedError: fmt.Errorf("user repo error"),
+			hasherCalls:   []tmock.Call{hashCall},
+			expectedError: fmt.Errorf("user with email %s already exists", input1.Email),
 		},
-		"user not found should return error": {
-			email:    params1.email,
-			password: params1.password,
+		"password hasher error should propagate": {
+			input: input1,
+			pldServiceCalls: []tmock.Call{
+				{
+					FunctionName: "CheckBlacklist",
+					Params: []interface{}{
+						screenedInput1,
+					},
+					Returns: []interface{}{
+						clearScreening,
+						nil,
+					},
+				},
+			},
 			userRepoCalls: []tmock.Call{
 				{
-					FunctionName: "GetUserByEmailAndPassword",
+					FunctionName: "GetUserByEmail",
 					Params: []interface{}{
-						params1.email,
-						ePassword,
+						input1.Email,
+					},
+					Returns: []interface{}{
+						(*user.User)(nil),
+						nil,
+					},
+				},
+				{
+					FunctionName: "GetDeletedUserByEmail",
+					Params: []interface{}{
+						input1.Email,
 					},
 					Returns: []interface{}{
 						(*user.User)(nil),
@@ -367,24 +706,44 @@ func TestLogin(t *testing.T) {
 					},
 				},
 			},
-			expectedError: fmt.Errorf("user not exists or invalid credentials"),
+			hasherCalls: []tmock.Call{
+				{
+					FunctionName: "Hash",
+					Params: []interface{}{
+						input1.Password,
+					},
+					Returns: []interface{}{
+						"",
+						fmt.Errorf("hasher error"),
+					},
+				},
+			},
+			expectedError: fmt.Errorf("hasher error"),
 		},
 	}
 
 	for name, tc := range testCases {
 		ctx := context.Background()
-		pldService := tmock.NewPLDService()
+		pldService := tmock.NewPLDService().AddCall(t, tc.pldServiceCalls)
 		userRepo := tmock.NewUserRepository().AddCall(t, tc.userRepoCalls)
+		hasher := tmock.NewPasswordHasher().AddCall(t, tc.hasherCalls)
+		verifier := tmock.NewEmailVerifier().AddCall(t, tc.verifierCalls)
 
-		userService, err := user.NewService(pldService, userRepo)
+		userService, err := user.NewService(pldService, userRepo, hasher, user.LockoutPolicy{}, user.PasswordPolicy{}, verifier, tmock.NewSessionRevoker())
 		assert.NoError(t, err)
 
-		email := tc.email
-		password := tc.password
+		input := tc.input
 		expectedError := tc.expectedError
 
 		t.Run(name, func(t *testing.T) {
-			got, err := userService.Login(ctx, email, password)
+			got, err := userService.CreateUser(ctx, input)
+
+			// rejected applicants get the id their screenings are recorded under
+			var screeningErr *user.ScreeningError
+			if errors.As(err, &screeningErr) {
+				assert.NotEmpty(t, screeningErr.UserID)
+				screeningErr.UserID = ""
+			}
 
 			if expectedError == nil {
 				assert.NotNil(t, got)
@@ -393,90 +752,401 @@ func TestLogin(t *testing.T) {
 				assert.Equal(t, expectedError, err)
 				assert.Nil(t, got)
 			}
+			verifier.AssertExpectations(t)
 		})
 	}
 }
 
-func TestGetUser(t *testing.T) {
-	email1 := "dua@lipa.com"
+func TestLogin(t *testing.T) {
+	params1 := struct {
+		email    string
+		password string
+	}{
+		email:    "dua@lipa.com",
+		password: "dua123lipa",
+	}
+
+	ePassword := "$argon2id$v=19$m=65536,t=3,p=2$c2FsdA$aGFzaA"
+	legacyPassword := "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
 
 	user1 := &user.User{
 		FirstName: "Dua",
 		LastName:  "Lipa",
 		Email:     "dua@lipa.com",
-		Password:  "dua123lipa",
+		Password:  ePassword,
+	}
+
+	legacyUser1 := &user.User{
+		FirstName: "Dua",
+		LastName:  "Lipa",
+		Email:     "dua@lipa.com",
+		Password:  legacyPassword,
+	}
+
+	pendingUser1 := &user.User{
+		FirstName: "Dua",
+		LastName:  "Lipa",
+		Email:     "dua@lipa.com",
+		Password:  ePassword,
+		Status:    user.StatusPendingVerification,
+	}
+
+	unscreenedUser1 := &user.User{
+		FirstName: "Dua",
+		LastName:  "Lipa",
+		Email:     "dua@lipa.com",
+		Password:  ePassword,
+		Status:    user.StatusActive,
+		Screening: user.ScreeningPending,
+	}
+
+	getUserCall := tmock.Call{
+		FunctionName: "GetUserByEmail",
+		Params: []interface{}{
+			params1.email,
+		},
+		Returns: []interface{}{
+			user1,
+			nil,
+		},
 	}
 
 	testCases := map[string]struct {
 		email         string
+		password      string
 		userRepoCalls []tmock.Call
+		hasherCalls   []tmock.Call
 		expectedError error
 	}{
 		"success": {
-			email: email1,
+			email:         params1.email,
+			password:      params1.password,
+			userRepoCalls: []tmock.Call{getUserCall},
+			hasherCalls: []tmock.Call{
+				{
+					FunctionName: "Verify",
+					Params: []interface{}{
+						params1.password,
+						ePassword,
+					},
+					Returns: []interface{}{
+						true,
+						nil,
+					},
+				},
+				{
+					FunctionName: "NeedsRehash",
+					Params: []interface{}{
+						ePassword,
+					},
+					Returns: []interface{}{
+						false,
+					},
+				},
+			},
+		},
+		"unverified email should return error": {
+			email:    params1.email,
+			password: params1.password,
 			userRepoCalls: []tmock.Call{
 				{
 					FunctionName: "GetUserByEmail",
 					Params: []interface{}{
-						email1,
+						params1.email,
 					},
 					Returns: []interface{}{
-						user1,
+						pendingUser1,
 						nil,
 					},
 				},
 			},
+			hasherCalls: []tmock.Call{
+				{
+					FunctionName: "Verify",
+					Params: []interface{}{
+						params1.password,
+						ePassword,
+					},
+					Returns: []interface{}{
+						true,
+						nil,
+					},
+				},
+			},
+			expectedError: user.ErrEmailNotVerified,
 		},
-		"empty email should return error": {
-			expectedError: fmt.Errorf("user's email should not be empty"),
-		},
-		"user repo error should propagate": {
-			email: email1,
+		"pending pld screening should return error": {
+			email:    params1.email,
+			password: params1.password,
 			userRepoCalls: []tmock.Call{
 				{
 					FunctionName: "GetUserByEmail",
 					Params: []interface{}{
-						email1,
+						params1.email,
 					},
 					Returns: []interface{}{
-						(*user.User)(nil),
-						fmt.Errorf("user repo error"),
+						unscreenedUser1,
+						nil,
 					},
 				},
 			},
-			expectedError: fmt.Errorf("user repo error"),
+			hasherCalls: []tmock.Call{
+				{
+					FunctionName: "Verify",
+					Params: []interface{}{
+						params1.password,
+						ePassword,
+					},
+					Returns: []interface{}{
+						true,
+						nil,
+					},
+				},
+			},
+			expectedError: user.ErrScreeningPending,
 		},
-		"user not found should return error": {
-			email: email1,
+		"legacy hash should be upgraded": {
+			email:    params1.email,
+			password: params1.password,
 			userRepoCalls: []tmock.Call{
 				{
 					FunctionName: "GetUserByEmail",
 					Params: []interface{}{
-						email1,
+						params1.email,
+					},
+					Returns: []interface{}{
+						legacyUser1,
+						nil,
+					},
+				},
+				{
+					FunctionName: "UpdatePassword",
+					Params: []interface{}{
+						params1.email,
+						ePassword,
 					},
 					Returns: []interface{}{
-						(*user.User)(nil),
 						nil,
 					},
 				},
 			},
-			expectedError: fmt.Errorf("user not exists"),
-		},
+			hasherCalls: []tmock.Call{
+				{
+					FunctionName: "Verify",
+					Params: []interface{}{
+						params1.password,
+						legacyPassword,
+					},
+					Returns: []interface{}{
+						true,
+						nil,
+					},
+				},
+				{
+					FunctionName: "NeedsRehash",
+					Params: []interface{}{
+						legacyPassword,
+					},
+					Returns: []interface{}{
+						true,
+					},
+				},
+				{
+					FunctionName: "Hash",
+					Params: []interface{}{
+						params1.password,
+					},
+					Returns: []interface{}{
+						ePassword,
+						nil,
+					},
+				},
+			},
+		},
+		"empty email should return error": {
+			expectedError: fmt.Errorf("user's email should not be empty"),
+		},
+		"empty password should return error": {
+			email:         params1.email,
+			expectedError: fmt.Errorf("user's password should not be empty"),
+		},
+		"user repo error should propagate": {
+			email:    params1.email,
+			password: params1.password,
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetUserByEmail",
+					Params: []interface{}{
+						params1.email,
+					},
+					Returns: []interface{}{
+						(*user.User)(nil),
+						fmt.Errorf("user repo error"),
+					},
+				},
+			},
+			expectedError: fmt.Errorf("user repo error"),
+		},
+		"user not found should return error": {
+			email:    params1.email,
+			password: params1.password,
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetUserByEmail",
+					Params: []interface{}{
+						params1.email,
+					},
+					Returns: []interface{}{
+						(*user.User)(nil),
+						nil,
+					},
+				},
+			},
+			// unknown emails are checked against a dummy hash
+			hasherCalls: []tmock.Call{
+				{
+					FunctionName: "Hash",
+					Params:       []interface{}{mock.AnythingOfType("string")},
+					Returns:      []interface{}{ePassword, nil},
+				},
+				{
+					FunctionName: "Verify",
+					Params:       []interface{}{params1.password, ePassword},
+					Returns:      []interface{}{false, nil},
+				},
+			},
+			expectedError: user.ErrInvalidCredentials,
+		},
+		"wrong password should return error": {
+			email:         params1.email,
+			password:      params1.password,
+			userRepoCalls: []tmock.Call{getUserCall},
+			hasherCalls: []tmock.Call{
+				{
+					FunctionName: "Verify",
+					Params: []interface{}{
+						params1.password,
+						ePassword,
+					},
+					Returns: []interface{}{
+						false,
+						nil,
+					},
+				},
+			},
+			expectedError: user.ErrInvalidCredentials,
+		},
 	}
 
 	for name, tc := range testCases {
 		ctx := context.Background()
 		pldService := tmock.NewPLDService()
 		userRepo := tmock.NewUserRepository().AddCall(t, tc.userRepoCalls)
+		hasher := tmock.NewPasswordHasher().AddCall(t, tc.hasherCalls)
 
-		userService, err := user.NewService(pldService, userRepo)
+		userService, err := user.NewService(pldService, userRepo, hasher, user.LockoutPolicy{}, user.PasswordPolicy{}, tmock.NewEmailVerifier(), tmock.NewSessionRevoker())
 		assert.NoError(t, err)
 
 		email := tc.email
+		password := tc.password
+		expectedError := tc.expectedError
+
+		t.Run(name, func(t *testing.T) {
+			got, err := userService.Login(ctx, email, password)
+
+			if expectedError == nil {
+				assert.NotNil(t, got)
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, expectedError, err)
+				assert.Nil(t, got)
+			}
+			hasher.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetUser(t *testing.T) {
+	id1 := "user1"
+
+	user1 := &user.User{
+		FirstName: "Dua",
+		LastName:  "Lipa",
+		Email:     "dua@lipa.com",
+		Password:  "dua123lipa",
+	}
+
+	testCases := map[string]struct {
+		id            string
+		userRepoCalls []tmock.Call
+		expectedError error
+	}{
+		"success": {
+			id: id1,
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetUserByID",
+					Params: []interface{}{
+						id1,
+					},
+					Returns: []interface{}{
+						user1,
+						nil,
+					},
+				},
+			},
+		},
+		"empty id should return error": {
+			expectedError: fmt.Errorf("user's id should not be empty"),
+		},
+		"user repo error should propagate": {
+			id: id1,
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetUserByID",
+					Params: []interface{}{
+						id1,
+					},
+					Returns: []interface{}{
+						(*user.User)(nil),
+						fmt.Errorf("user repo error"),
+					},
+				},
+			},
+			expectedError: fmt.Errorf("user repo error"),
+		},
+		"user not found should return error": {
+			id: id1,
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetUserByID",
+					Params: []interface{}{
+						id1,
+					},
+					Returns: []interface{}{
+						(*user.User)(nil),
+						nil,
+					},
+				},
+			},
+			expectedError: fmt.Errorf("user not exists"),
+		},
+	}
+
+	for name, tc := range testCases {
+		ctx := context.Background()
+		pldService := tmock.NewPLDService()
+		userRepo := tmock.NewUserRepository().AddCall(t, tc.userRepoCalls)
+		hasher := tmock.NewPasswordHasher()
+
+		userService, err := user.NewService(pldService, userRepo, hasher, user.LockoutPolicy{}, user.PasswordPolicy{}, tmock.NewEmailVerifier(), tmock.NewSessionRevoker())
+		assert.NoError(t, err)
+
+		id := tc.id
 		expectedError := tc.expectedError
 
 		t.Run(name, func(t *testing.T) {
-			got, err := userService.GetUser(ctx, email)
+			got, err := userService.GetUser(ctx, id)
 
 			if expectedError == nil {
 				assert.NotNil(t, got)
@@ -488,3 +1158,687 @@ func TestGetUser(t *testing.T) {
 		})
 	}
 }
+
+func TestRevokeSessions(t *testing.T) {
+	id1 := "user1"
+	email1 := "dua@lipa.com"
+
+	user1 := &user.User{
+		FirstName: "Dua",
+		LastName:  "Lipa",
+		Email:     "dua@lipa.com",
+	}
+
+	testCases := map[string]struct {
+		id            string
+		userRepoCalls []tmock.Call
+		expectedError error
+	}{
+		"success": {
+			id: id1,
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetUserByID",
+					Params: []interface{}{
+						id1,
+					},
+					Returns: []interface{}{
+						user1,
+						nil,
+					},
+				},
+				{
+					FunctionName: "UpdateTokensValidAfter",
+					Params: []interface{}{
+						email1,
+						mock.AnythingOfType("time.Time"),
+					},
+					Returns: []interface{}{
+						nil,
+					},
+				},
+			},
+		},
+		"empty id should return error": {
+			expectedError: fmt.Errorf("user's id should not be empty"),
+		},
+		"user not found should return error": {
+			id: id1,
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetUserByID",
+					Params: []interface{}{
+						id1,
+					},
+					Returns: []interface{}{
+						(*user.User)(nil),
+						nil,
+					},
+				},
+			},
+			expectedError: fmt.Errorf("user not exists"),
+		},
+	}
+
+	for name, tc := range testCases {
+		ctx := context.Background()
+		pldService := tmock.NewPLDService()
+		userRepo := tmock.NewUserRepository().AddCall(t, tc.userRepoCalls)
+		hasher := tmock.NewPasswordHasher()
+
+		userService, err := user.NewService(pldService, userRepo, hasher, user.LockoutPolicy{}, user.PasswordPolicy{}, tmock.NewEmailVerifier(), tmock.NewSessionRevoker())
+		assert.NoError(t, err)
+
+		id := tc.id
+		expectedError := tc.expectedError
+
+		t.Run(name, func(t *testing.T) {
+			err := userService.RevokeSessions(ctx, id)
+			assert.Equal(t, expectedError, err)
+			userRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAssignRole(t *testing.T) {
+	email1 := "dua@lipa.com"
+
+	testCases := map[string]struct {
+		role          user.Role
+		userRepoCalls []tmock.Call
+		expectedError error
+	}{
+		"success": {
+			role: user.RoleAdmin,
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetUserByEmail",
+					Params:       []interface{}{email1},
+					Returns:      []interface{}{&user.User{Email: email1, Role: user.RoleCustomer}, nil},
+				},
+				{
+					FunctionName: "UpdateRole",
+					Params:       []interface{}{email1, user.RoleAdmin},
+					Returns:      []interface{}{nil},
+				},
+			},
+		},
+		"same role should not update": {
+			role: user.RoleAdmin,
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetUserByEmail",
+					Params:       []interface{}{email1},
+					Returns:      []interface{}{&user.User{Email: email1, Role: user.RoleAdmin}, nil},
+				},
+			},
+		},
+		"unknown user should return error": {
+			role: user.RoleAdmin,
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetUserByEmail",
+					Params:       []interface{}{email1},
+					Returns:      []interface{}{(*user.User)(nil), nil},
+				},
+			},
+			expectedError: fmt.Errorf("user not exists"),
+		},
+		"unknown role should return error": {
+			role:          "root",
+			expectedError: user.ErrInvalidRole,
+		},
+	}
+
+	for name, tc := range testCases {
+		ctx := context.Background()
+		userRepo := tmock.NewUserRepository().AddCall(t, tc.userRepoCalls)
+
+		userService, err := user.NewService(
+			tmock.NewPLDService(),
+			userRepo,
+			tmock.NewPasswordHasher(),
+			user.LockoutPolicy{},
+			user.PasswordPolicy{},
+			tmock.NewEmailVerifier(),
+			tmock.NewSessionRevoker(),
+		)
+		assert.NoError(t, err)
+
+		role := tc.role
+		expectedError := tc.expectedError
+
+		t.Run(name, func(t *testing.T) {
+			err := userService.AssignRole(ctx, email1, role)
+			assert.Equal(t, expectedError, err)
+			userRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUpdateUser(t *testing.T) {
+	id1 := "user1"
+	email1 := "dua@lipa.com"
+	first := "Dua"
+	last := "Lipa-Ahmeti"
+	empty := ""
+
+	user1 := &user.User{
+		ID:        id1,
+		FirstName: "Dua",
+		LastName:  "Lipa",
+		Email:     email1,
+		Role:      user.RoleCustomer,
+	}
+
+	user2 := user.User{
+		ID:        id1,
+		FirstName: "Dua",
+		LastName:  "Lipa-Ahmeti",
+		Email:     email1,
+		Role:      user.RoleCustomer,
+	}
+
+	testCases := map[string]struct {
+		update        user.ProfileUpdate
+		userRepoCalls []tmock.Call
+		pldCalls      []tmock.Call
+		expected      *user.User
+		expectedError error
+	}{
+		"name change should check pld": {
+			update: user.ProfileUpdate{LastName: &last},
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetUserByID",
+					Params:       []interface{}{id1},
+					Returns:      []interface{}{user1, nil},
+				},
+				{
+					FunctionName: "UpdateUser",
+					Params:       []interface{}{user2},
+					Returns:      []interface{}{nil},
+				},
+			},
+			pldCalls: []tmock.Call{
+				{
+					FunctionName: "CheckBlacklist",
+					Params:       []interface{}{pld.Request{UserID: id1, FirstName: "Dua", LastName: "Lipa-Ahmeti", Email: email1}},
+					Returns:      []interface{}{clearScreening, nil},
+				},
+			},
+			expected: &user2,
+		},
+		"unchanged name should not check pld": {
+			update: user.ProfileUpdate{FirstName: &first},
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetUserByID",
+					Params:       []interface{}{id1},
+					Returns:      []interface{}{user1, nil},
+				},
+			},
+			expected: user1,
+		},
+		"blacklisted name should return error": {
+			update: user.ProfileUpdate{LastName: &last},
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetUserByID",
+					Params:       []interface{}{id1},
+					Returns:      []interface{}{user1, nil},
+				},
+			},
+			pldCalls: []tmock.Call{
+				{
+					FunctionName: "CheckBlacklist",
+					Params:       []interface{}{pld.Request{UserID: id1, FirstName: "Dua", LastName: "Lipa-Ahmeti", Email: email1}},
+					Returns:      []interface{}{hitScreening, nil},
+				},
+			},
+			expectedError: &user.ScreeningError{UserID: id1, Result: *hitScreening},
+		},
+		"removed name should return error": {
+			update: user.ProfileUpdate{FirstName: &empty},
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetUserByID",
+					Params:       []interface{}{id1},
+					Returns:      []interface{}{user1, nil},
+				},
+			},
+			expectedError: fmt.Errorf("user's first name should not be empty"),
+		},
+	}
+
+	for name, tc := range testCases {
+		ctx := context.Background()
+		userRepo := tmock.NewUserRepository().AddCall(t, tc.userRepoCalls)
+		pldService := tmock.NewPLDService().AddCall(t, tc.pldCalls)
+
+		userService, err := user.NewService(
+			pldService,
+			userRepo,
+			tmock.NewPasswordHasher(),
+			user.LockoutPolicy{},
+			user.PasswordPolicy{},
+			tmock.NewEmailVerifier(),
+			tmock.NewSessionRevoker(),
+		)
+		assert.NoError(t, err)
+
+		update := tc.update
+		expected := tc.expected
+		expectedError := tc.expectedError
+
+		t.Run(name, func(t *testing.T) {
+			got, err := userService.UpdateUser(ctx, id1, update)
+			assert.Equal(t, expectedError, err)
+			assert.Equal(t, expected, got)
+			userRepo.AssertExpectations(t)
+			pldService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	id1 := "user1"
+	email1 := "dua@lipa.com"
+	ePassword := "$argon2id$v=19$m=65536,t=3,p=2$c2FsdA$aGFzaA"
+	nPassword := "$argon2id$v=19$m=65536,t=3,p=2$c2FsdA$bmV3"
+
+	user1 := &user.User{
+		FirstName: "Dua",
+		LastName:  "Lipa",
+		Email:     email1,
+		Password:  ePassword,
+	}
+
+	testCases := map[string]struct {
+		current       string
+		userRepoCalls []tmock.Call
+		hasherCalls   []tmock.Call
+		sessionsCalls []tmock.Call
+		expectedError error
+	}{
+		"success": {
+			current: "nectarine-orbit-42",
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetUserByID",
+					Params:       []interface{}{id1},
+					Returns:      []interface{}{user1, nil},
+				},
+				{
+					FunctionName: "UpdatePassword",
+					Params:       []interface{}{email1, nPassword},
+					Returns:      []interface{}{nil},
+				},
+				{
+					FunctionName: "UpdateTokensValidAfter",
+					Params:       []interface{}{email1, mock.AnythingOfType("time.Time")},
+					Returns:      []interface{}{nil},
+				},
+			},
+			hasherCalls: []tmock.Call{
+				{
+					FunctionName: "Verify",
+					Params:       []interface{}{"nectarine-orbit-42", ePassword},
+					Returns:      []interface{}{true, nil},
+				},
+				{
+					FunctionName: "Hash",
+					Params:       []interface{}{"tangerine-comet-7"},
+					Returns:      []interface{}{nPassword, nil},
+				},
+			},
+			sessionsCalls: []tmock.Call{
+				{
+					FunctionName: "DeleteSessions",
+					Params:       []interface{}{email1},
+					Returns:      []interface{}{nil},
+				},
+			},
+		},
+		"session revocation error should propagate": {
+			current: "nectarine-orbit-42",
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetUserByID",
+					Params:       []interface{}{id1},
+					Returns:      []interface{}{user1, nil},
+				},
+				{
+					FunctionName: "UpdatePassword",
+					Params:       []interface{}{email1, nPassword},
+					Returns:      []interface{}{nil},
+				},
+				{
+					FunctionName: "UpdateTokensValidAfter",
+					Params:       []interface{}{email1, mock.AnythingOfType("time.Time")},
+					Returns:      []interface{}{nil},
+				},
+			},
+			hasherCalls: []tmock.Call{
+				{
+					FunctionName: "Verify",
+					Params:       []interface{}{"nectarine-orbit-42", ePassword},
+					Returns:      []interface{}{true, nil},
+				},
+				{
+					FunctionName: "Hash",
+					Params:       []interface{}{"tangerine-comet-7"},
+					Returns:      []interface{}{nPassword, nil},
+				},
+			},
+			sessionsCalls: []tmock.Call{
+				{
+					FunctionName: "DeleteSessions",
+					Params:       []interface{}{email1},
+					Returns:      []interface{}{fmt.Errorf("session repo error")},
+				},
+			},
+			expectedError: fmt.Errorf("session repo error"),
+		},
+		"wrong current password should return error": {
+			current: "wrong-password",
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetUserByID",
+					Params:       []interface{}{id1},
+					Returns:      []interface{}{user1, nil},
+				},
+			},
+			hasherCalls: []tmock.Call{
+				{
+					FunctionName: "Verify",
+					Params:       []interface{}{"wrong-password", ePassword},
+					Returns:      []interface{}{false, nil},
+				},
+			},
+			expectedError: user.ErrInvalidCredentials,
+		},
+		"empty current password should return error": {
+			expectedError: fmt.Errorf("user's current password should not be empty"),
+		},
+	}
+
+	for name, tc := range testCases {
+		ctx := context.Background()
+		userRepo := tmock.NewUserRepository().AddCall(t, tc.userRepoCalls)
+		hasher := tmock.NewPasswordHasher().AddCall(t, tc.hasherCalls)
+		sessions := tmock.NewSessionRevoker().AddCall(t, tc.sessionsCalls)
+
+		userService, err := user.NewService(
+			tmock.NewPLDService(),
+			userRepo,
+			hasher,
+			user.LockoutPolicy{},
+			user.PasswordPolicy{},
+			tmock.NewEmailVerifier(),
+			sessions,
+		)
+		assert.NoError(t, err)
+
+		current := tc.current
+		expectedError := tc.expectedError
+
+		t.Run(name, func(t *testing.T) {
+			err := userService.ChangePassword(ctx, id1, current, "tangerine-comet-7")
+			assert.Equal(t, expectedError, err)
+			userRepo.AssertExpectations(t)
+			sessions.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDeleteUser(t *testing.T) {
+	id1 := "user1"
+	email1 := "dua@lipa.com"
+
+	user1 := &user.User{
+		FirstName: "Dua",
+		LastName:  "Lipa",
+		Email:     email1,
+	}
+
+	testCases := map[string]struct {
+		id            string
+		userRepoCalls []tmock.Call
+		expectedError error
+	}{
+		"success": {
+			id: id1,
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetUserByID",
+					Params:       []interface{}{id1},
+					Returns:      []interface{}{user1, nil},
+				},
+				{
+					FunctionName: "DeleteUser",
+					Params:       []interface{}{email1, mock.AnythingOfType("time.Time")},
+					Returns:      []interface{}{nil},
+				},
+			},
+		},
+		"empty id should return error": {
+			expectedError: fmt.Errorf("user's id should not be empty"),
+		},
+		"user not found should return error": {
+			id: id1,
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetUserByID",
+					Params:       []interface{}{id1},
+					Returns:      []interface{}{(*user.User)(nil), nil},
+				},
+			},
+			expectedError: fmt.Errorf("user not exists"),
+		},
+		"user repo error should propagate": {
+			id: id1,
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetUserByID",
+					Params:       []interface{}{id1},
+					Returns:      []interface{}{user1, nil},
+				},
+				{
+					FunctionName: "DeleteUser",
+					Params:       []interface{}{email1, mock.AnythingOfType("time.Time")},
+					Returns:      []interface{}{fmt.Errorf("user repo error")},
+				},
+			},
+			expectedError: fmt.Errorf("user repo error"),
+		},
+	}
+
+	for name, tc := range testCases {
+		ctx := context.Background()
+		userRepo := tmock.NewUserRepository().AddCall(t, tc.userRepoCalls)
+
+		userService, err := user.NewService(
+			tmock.NewPLDService(),
+			userRepo,
+			tmock.NewPasswordHasher(),
+			user.LockoutPolicy{},
+			user.PasswordPolicy{},
+			tmock.NewEmailVerifier(),
+			tmock.NewSessionRevoker(),
+		)
+		assert.NoError(t, err)
+
+		id := tc.id
+		expectedError := tc.expectedError
+
+		t.Run(name, func(t *testing.T) {
+			err := userService.DeleteUser(ctx, id)
+			assert.Equal(t, expectedError, err)
+			userRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRestoreUser(t *testing.T) {
+	id1 := "user1"
+	email1 := "dua@lipa.com"
+	deletedAt := time.Now().UTC().Add(-time.Hour)
+
+	testCases := map[string]struct {
+		id            string
+		userRepoCalls []tmock.Call
+		expectedError error
+	}{
+		"success": {
+			id: id1,
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetDeletedUserByID",
+					Params:       []interface{}{id1},
+					Returns: []interface{}{
+						&user.User{Email: email1, DeletedAt: &deletedAt},
+						nil,
+					},
+				},
+				{
+					FunctionName: "RestoreUser",
+					Params:       []interface{}{id1},
+					Returns:      []interface{}{nil},
+				},
+			},
+		},
+		"empty id should return error": {
+			expectedError: fmt.Errorf("user's id should not be empty"),
+		},
+		"user not deleted should return error": {
+			id: id1,
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetDeletedUserByID",
+					Params:       []interface{}{id1},
+					Returns:      []interface{}{(*user.User)(nil), nil},
+				},
+			},
+			expectedError: fmt.Errorf("deleted user not exists"),
+		},
+		"user repo error should propagate": {
+			id: id1,
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetDeletedUserByID",
+					Params:       []interface{}{id1},
+					Returns: []interface{}{
+						&user.User{Email: email1, DeletedAt: &deletedAt},
+						nil,
+					},
+				},
+				{
+					FunctionName: "RestoreUser",
+					Params:       []interface{}{id1},
+					Returns:      []interface{}{fmt.Errorf("user repo error")},
+				},
+			},
+			expectedError: fmt.Errorf("user repo error"),
+		},
+	}
+
+	for name, tc := range testCases {
+		ctx := context.Background()
+		userRepo := tmock.NewUserRepository().AddCall(t, tc.userRepoCalls)
+
+		userService, err := user.NewService(
+			tmock.NewPLDService(),
+			userRepo,
+			tmock.NewPasswordHasher(),
+			user.LockoutPolicy{},
+			user.PasswordPolicy{},
+			tmock.NewEmailVerifier(),
+			tmock.NewSessionRevoker(),
+		)
+		assert.NoError(t, err)
+
+		id := tc.id
+		expectedError := tc.expectedError
+
+		t.Run(name, func(t *testing.T) {
+			got, err := userService.RestoreUser(ctx, id)
+
+			if expectedError == nil {
+				assert.NoError(t, err)
+				assert.Nil(t, got.DeletedAt)
+			} else {
+				assert.Equal(t, expectedError, err)
+				assert.Nil(t, got)
+			}
+			userRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPurgeDeletedUsers(t *testing.T) {
+	retention := 30 * 24 * time.Hour
+
+	testCases := map[string]struct {
+		retention     time.Duration
+		userRepoCalls []tmock.Call
+		expected      int64
+		expectedError error
+	}{
+		"success": {
+			retention: retention,
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "PurgeUsers",
+					Params: []interface{}{
+						mock.MatchedBy(func(deletedBefore time.Time) bool {
+							cutoff := time.Now().UTC().Add(-retention)
+							return !deletedBefore.After(cutoff) && cutoff.Sub(deletedBefore) < time.Minute
+						}),
+					},
+					Returns: []interface{}{int64(2), nil},
+				},
+			},
+			expected: 2,
+		},
+		"zero retention should return error": {
+			expectedError: fmt.Errorf("deleted users retention should be greater than zero"),
+		},
+		"user repo error should propagate": {
+			retention: retention,
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "PurgeUsers",
+					Params:       []interface{}{mock.AnythingOfType("time.Time")},
+					Returns:      []interface{}{int64(0), fmt.Errorf("user repo error")},
+				},
+			},
+			expectedError: fmt.Errorf("user repo error"),
+		},
+	}
+
+	for name, tc := range testCases {
+		ctx := context.Background()
+		userRepo := tmock.NewUserRepository().AddCall(t, tc.userRepoCalls)
+
+		userService, err := user.NewService(
+			tmock.NewPLDService(),
+			userRepo,
+			tmock.NewPasswordHasher(),
+			user.LockoutPolicy{},
+			user.PasswordPolicy{},
+			tmock.NewEmailVerifier(),
+			tmock.NewSessionRevoker(),
+		)
+		assert.NoError(t, err)
+
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			got, err := userService.PurgeDeletedUsers(ctx, tc.retention)
+			assert.Equal(t, tc.expectedError, err)
+			assert.Equal(t, tc.expected, got)
+			userRepo.AssertExpectations(t)
+		})
+	}
+}
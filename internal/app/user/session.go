@@ -0,0 +1,10 @@
+package user
+
+import (
+	"context"
+)
+
+// SessionRevoker contract for ending every session of a user
+type SessionRevoker interface {
+	DeleteSessions(ctx context.Context, email string) error
+}
@@ -0,0 +1,40 @@
+package user_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/alamrios/crabi-solution/internal/app/user"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 appendix B SHA1 vectors truncated to 6 digits
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+	testCases := map[string]struct {
+		at       int64
+		expected string
+	}{
+		"59":          {at: 59, expected: "287082"},
+		"1111111109":  {at: 1111111109, expected: "081804"},
+		"1111111111":  {at: 1111111111, expected: "050471"},
+		"1234567890":  {at: 1234567890, expected: "005924"},
+		"2000000000":  {at: 2000000000, expected: "279037"},
+		"20000000000": {at: 20000000000, expected: "353130"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got, err := user.TOTPCode(secret, time.Unix(tc.at, 0))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestTOTPCodeInvalidSecret(t *testing.T) {
+	_, err := user.TOTPCode("not base32!", time.Now())
+	assert.Error(t, err)
+}
@@ -1,9 +1,62 @@
 package user
 
+import (
+	"time"
+)
+
+// Status of a user account
+type Status string
+
+const (
+	// StatusPendingVerification user has not confirmed its email yet
+	StatusPendingVerification Status = "pending_verification"
+	// StatusActive user can log in
+	StatusActive Status = "active"
+)
+
+// ScreeningStatus of a user's PLD blacklist check, empty once the user
+// passed it
+type ScreeningStatus string
+
+const (
+	// ScreeningPending user registered while PLD service was unavailable
+	ScreeningPending ScreeningStatus = "pending"
+	// ScreeningRejected user found in PLD blacklist after registering
+	ScreeningRejected ScreeningStatus = "rejected"
+	// ScreeningReview user partially matched PLD blacklist and waits for a
+	// manual review
+	ScreeningReview ScreeningStatus = "review"
+)
+
 // User struct
 type User struct {
+	// ID immutable opaque identifier of the user
+	ID        string `bson:"_id"`
 	FirstName string `bson:"first_name"`
 	LastName  string `bson:"last_name"`
 	Email     string `bson:"email"`
 	Password  string `bson:"password"`
+	Status    Status `bson:"status"`
+	Role      Role   `bson:"role"`
+	// Screening pending, rejected or in review PLD check, users can not log
+	// in until they pass it
+	Screening ScreeningStatus `bson:"screening,omitempty"`
+	// EmailDomain lower cased domain of the email, used to filter listings
+	EmailDomain string    `bson:"email_domain"`
+	CreatedAt   time.Time `bson:"created_at"`
+	// PasswordHistory hashes of previous passwords, most recent first
+	PasswordHistory []string `bson:"password_history,omitempty"`
+	// TokensValidAfter access tokens issued before this date are rejected
+	TokensValidAfter *time.Time    `bson:"tokens_valid_after,omitempty"`
+	LoginAttempts    LoginAttempts `bson:"login_attempts"`
+	MFA              MFA           `bson:"mfa"`
+	// DeletedAt date the user was soft deleted, nil for existing users
+	DeletedAt *time.Time `bson:"deleted_at,omitempty"`
+}
+
+// ProfileUpdate struct for partial changes of a user's profile, nil fields
+// are left unchanged
+type ProfileUpdate struct {
+	FirstName *string
+	LastName  *string
 }
@@ -0,0 +1,154 @@
+package breached
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// bloomMagic identifies bloom filter files written by BloomFilter.WriteTo
+var bloomMagic = [4]byte{'C', 'R', 'B', 'F'}
+
+// BloomFilter struct for a probabilistic set of compromised passwords,
+// lookups never miss a listed password and may report unlisted ones with
+// the false positive rate given on build
+type BloomFilter struct {
+	bits   []uint64
+	size   uint64
+	hashes uint32
+}
+
+// NewBloomFilter returns an empty bloom filter sized for given number of
+// passwords and false positive rate
+func NewBloomFilter(passwords int, falsePositiveRate float64) (*BloomFilter, error) {
+	if passwords <= 0 {
+		return nil, fmt.Errorf("bloom filter passwords should be greater than zero")
+	}
+
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		return nil, fmt.Errorf("bloom filter false positive rate should be between 0 and 1")
+	}
+
+	n := float64(passwords)
+	size := uint64(math.Ceil(-n * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	hashes := uint32(math.Max(1, math.Round(float64(size)/n*math.Ln2)))
+
+	return &BloomFilter{
+		bits:   make([]uint64, (size+63)/64),
+		size:   size,
+		hashes: hashes,
+	}, nil
+}
+
+// LoadBloomFilter reads bloom filter from file written by WriteTo
+func LoadBloomFilter(path string) (*BloomFilter, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	filter := &BloomFilter{}
+	_, err = filter.ReadFrom(bufio.NewReader(file))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bloom filter %s: %w", path, err)
+	}
+
+	return filter, nil
+}
+
+// Add inserts given password in the filter
+func (f *BloomFilter) Add(password string) {
+	h1, h2 := bloomHashes(password)
+	for i := uint64(0); i < uint64(f.hashes); i++ {
+		bit := (h1 + i*h2) % f.size
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// Contains reports whether given password was probably added to the filter
+func (f *BloomFilter) Contains(password string) (bool, error) {
+	h1, h2 := bloomHashes(password)
+	for i := uint64(0); i < uint64(f.hashes); i++ {
+		bit := (h1 + i*h2) % f.size
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// WriteTo writes the filter as magic, hash count, bit count and bits
+func (f *BloomFilter) WriteTo(w io.Writer) (int64, error) {
+	header := make([]byte, 16)
+	copy(header, bloomMagic[:])
+	binary.BigEndian.PutUint32(header[4:], f.hashes)
+	binary.BigEndian.PutUint64(header[8:], f.size)
+
+	written, err := w.Write(header)
+	if err != nil {
+		return int64(written), err
+	}
+
+	buf := make([]byte, 8)
+	for _, word := range f.bits {
+		binary.BigEndian.PutUint64(buf, word)
+		n, err := w.Write(buf)
+		written += n
+		if err != nil {
+			return int64(written), err
+		}
+	}
+
+	return int64(written), nil
+}
+
+// ReadFrom replaces the filter with the one read from given reader
+func (f *BloomFilter) ReadFrom(r io.Reader) (int64, error) {
+	header := make([]byte, 16)
+	read, err := io.ReadFull(r, header)
+	if err != nil {
+		return int64(read), err
+	}
+
+	if string(header[:4]) != string(bloomMagic[:]) {
+		return int64(read), fmt.Errorf("invalid bloom filter header")
+	}
+
+	hashes := binary.BigEndian.Uint32(header[4:])
+	size := binary.BigEndian.Uint64(header[8:])
+	if hashes == 0 || size == 0 {
+		return int64(read), fmt.Errorf("invalid bloom filter header")
+	}
+
+	bits := make([]uint64, (size+63)/64)
+	buf := make([]byte, 8)
+	for i := range bits {
+		n, err := io.ReadFull(r, buf)
+		read += n
+		if err != nil {
+			return int64(read), err
+		}
+		bits[i] = binary.BigEndian.Uint64(buf)
+	}
+
+	f.bits = bits
+	f.size = size
+	f.hashes = hashes
+
+	return int64(read), nil
+}
+
+// bloomHashes returns the two hashes combined to derive every bit position
+func bloomHashes(password string) (uint64, uint64) {
+	sum := sha256.Sum256([]byte(password))
+	h1 := binary.BigEndian.Uint64(sum[0:8])
+	h2 := binary.BigEndian.Uint64(sum[8:16]) | 1
+
+	return h1, h2
+}
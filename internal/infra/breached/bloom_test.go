@@ -0,0 +1,52 @@
+package breached
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBloomFilter(t *testing.T) {
+	filter, err := NewBloomFilter(1000, 0.001)
+	assert.NoError(t, err)
+
+	for i := 0; i < 1000; i++ {
+		filter.Add(fmt.Sprintf("password%d", i))
+	}
+
+	var buf bytes.Buffer
+	_, err = filter.WriteTo(&buf)
+	assert.NoError(t, err)
+
+	loaded := &BloomFilter{}
+	_, err = loaded.ReadFrom(&buf)
+	assert.NoError(t, err)
+
+	for i := 0; i < 1000; i++ {
+		found, err := loaded.Contains(fmt.Sprintf("password%d", i))
+		assert.NoError(t, err)
+		assert.True(t, found, "added passwords should always be found")
+	}
+
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		found, _ := loaded.Contains(fmt.Sprintf("unlisted%d", i))
+		if found {
+			falsePositives++
+		}
+	}
+	assert.Less(t, falsePositives, 50)
+}
+
+func TestNewBloomFilter(t *testing.T) {
+	_, err := NewBloomFilter(0, 0.001)
+	assert.EqualError(t, err, "bloom filter passwords should be greater than zero")
+
+	_, err = NewBloomFilter(10, 1)
+	assert.EqualError(t, err, "bloom filter false positive rate should be between 0 and 1")
+
+	_, err = (&BloomFilter{}).ReadFrom(bytes.NewReader([]byte("not a bloom filter")))
+	assert.EqualError(t, err, "invalid bloom filter header")
+}
@@ -0,0 +1,75 @@
+package breached
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// prefixLength number of SHA-1 hex characters naming each range file
+const prefixLength = 5
+
+// PrefixDirectory struct for k-anonymity style SHA-1 range files, one file
+// per 5 characters hash prefix with SUFFIX:COUNT lines, as published by
+// Have I Been Pwned. Only the range file of the checked hash is read.
+type PrefixDirectory struct {
+	dir string
+}
+
+// NewPrefixDirectory returns an instance of prefix directory
+func NewPrefixDirectory(dir string) (*PrefixDirectory, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("prefix directory should not be empty")
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	return &PrefixDirectory{
+		dir: dir,
+	}, nil
+}
+
+// Contains reports whether SHA-1 hash of given password is listed in its
+// range file, missing range files mean no listed hash has that prefix
+func (d *PrefixDirectory) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+
+	file, err := os.Open(filepath.Join(d.dir, prefix))
+	if os.IsNotExist(err) {
+		file, err = os.Open(filepath.Join(d.dir, prefix+".txt"))
+	}
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			line = line[:i]
+		}
+
+		if strings.EqualFold(line, suffix) {
+			return true, nil
+		}
+	}
+
+	return false, scanner.Err()
+}
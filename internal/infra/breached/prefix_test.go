@@ -0,0 +1,35 @@
+package breached
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrefixDirectory(t *testing.T) {
+	dir := t.TempDir()
+
+	// SHA-1 of "password" is 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+	err := os.WriteFile(
+		filepath.Join(dir, "5BAA6"),
+		[]byte("003D68EB55068C33ACE09247EE4C639306B:3\r\n1E4C9B93F3F0682250B6CF8331B7EE68FD8:9545824\r\n"),
+		0600,
+	)
+	assert.NoError(t, err)
+
+	directory, err := NewPrefixDirectory(dir)
+	assert.NoError(t, err)
+
+	found, err := directory.Contains("password")
+	assert.NoError(t, err)
+	assert.True(t, found)
+
+	found, err = directory.Contains("password1")
+	assert.NoError(t, err)
+	assert.False(t, found, "missing range file should not be listed")
+
+	_, err = NewPrefixDirectory(filepath.Join(dir, "5BAA6"))
+	assert.Error(t, err)
+}
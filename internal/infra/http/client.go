@@ -2,21 +2,62 @@ package http
 
 import (
 	"bytes"
+	"context"
+	"fmt"
+	"net"
 	"net/http"
+	"time"
+
+	"github.com/alamrios/crabi-solution/config"
 )
 
-// Client struct for http
-type Client struct{}
+// Client struct for http, safe for concurrent use. Connections are pooled
+// by a single transport shared by every request of the client.
+type Client struct {
+	httpClient *http.Client
+}
 
 // NewClient constructor for htttp client
-func NewClient() (*Client, error) {
-	return &Client{}, nil
+func NewClient(cfg *config.HTTPClient) (*Client, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("http client config is nil")
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   cfg.ConnectTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   cfg.ConnectTimeout,
+		ResponseHeaderTimeout: cfg.ReadTimeout,
+		ExpectContinueTimeout: time.Second,
+	}
+
+	return &Client{
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   cfg.Timeout,
+		},
+	}, nil
 }
 
-// Post send a post request
-func (c *Client) Post(URL string, data []byte, contentType string) (*http.Response, error) {
-	bytesBuffer := bytes.NewBuffer(data)
-	response, err := http.Post(URL, contentType, bytesBuffer)
+// Post send a post request, it is canceled when given context is done.
+// Callers must close the response body.
+func (c *Client) Post(ctx context.Context, URL string, data []byte, contentType string) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, URL, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", contentType)
+
+	response, err := c.httpClient.Do(request)
 	if err != nil {
 		return nil, err
 	}
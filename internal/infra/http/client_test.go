@@ -0,0 +1,87 @@
+package http_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/alamrios/crabi-solution/config"
+	chttp "github.com/alamrios/crabi-solution/internal/infra/http"
+)
+
+func TestNewClient(t *testing.T) {
+	got, err := chttp.NewClient(nil)
+	assert.EqualError(t, err, "http client config is nil")
+	assert.Nil(t, got)
+}
+
+func TestPost(t *testing.T) {
+	cfg := config.HTTPClient{
+		ConnectTimeout:      time.Second,
+		ReadTimeout:         50 * time.Millisecond,
+		Timeout:             time.Second,
+		MaxIdleConnsPerHost: 1,
+	}
+
+	t.Run("request should be sent with body and content type", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			assert.Equal(t, `{"email":"dua@lipa.com"}`, string(body))
+			w.WriteHeader(http.StatusCreated)
+		}))
+		defer server.Close()
+
+		client, err := chttp.NewClient(&cfg)
+		assert.NoError(t, err)
+
+		response, err := client.Post(context.Background(), server.URL, []byte(`{"email":"dua@lipa.com"}`), "application/json")
+		assert.NoError(t, err)
+		defer response.Body.Close()
+		assert.Equal(t, http.StatusCreated, response.StatusCode)
+	})
+
+	t.Run("slow response should time out", func(t *testing.T) {
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer server.Close()
+		defer close(release)
+
+		client, err := chttp.NewClient(&cfg)
+		assert.NoError(t, err)
+
+		response, err := client.Post(context.Background(), server.URL, nil, "application/json")
+		assert.Error(t, err)
+		assert.Nil(t, response)
+	})
+
+	t.Run("canceled context should stop the request", func(t *testing.T) {
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer server.Close()
+		defer close(release)
+
+		slowCfg := cfg
+		slowCfg.ReadTimeout = time.Minute
+		client, err := chttp.NewClient(&slowCfg)
+		assert.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		response, err := client.Post(ctx, server.URL, nil, "application/json")
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+		assert.Nil(t, response)
+	})
+}
@@ -0,0 +1,125 @@
+package pld
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/alamrios/crabi-solution/config"
+)
+
+// retryableError failure of a PLD request that may succeed if it is sent
+// again
+type retryableError struct {
+	err error
+	// retryAfter wait requested by PLD server, zero when not requested
+	retryAfter time.Duration
+}
+
+// Error returns message of the underlying error
+func (e *retryableError) Error() string {
+	return e.err.Error()
+}
+
+// retryPolicy exponential backoff with full jitter between PLD requests
+type retryPolicy struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+
+	mu     sync.Mutex
+	random *rand.Rand
+}
+
+// newRetryPolicy returns policy of given config, a single attempt is made
+// when attempts are not configured
+func newRetryPolicy(cfg config.Retry) *retryPolicy {
+	maxAttempts := cfg.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	return &retryPolicy{
+		maxAttempts: maxAttempts,
+		baseDelay:   cfg.BaseDelay,
+		maxDelay:    cfg.MaxDelay,
+		random:      rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// delay returns wait before retrying given failed attempt, a random duration
+// up to the base delay doubled on every attempt and capped by the max delay.
+// A wait requested by the server is honored as is, and no retry is made when
+// it exceeds the max delay.
+func (p *retryPolicy) delay(attempt int, retryAfter time.Duration) (time.Duration, bool) {
+	if retryAfter > 0 {
+		return retryAfter, retryAfter <= p.maxDelay
+	}
+
+	ceiling := p.maxDelay
+	if attempt < 32 && p.baseDelay<<(attempt-1) < ceiling {
+		ceiling = p.baseDelay << (attempt - 1)
+	}
+	if ceiling <= 0 {
+		return 0, true
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return time.Duration(p.random.Int63n(int64(ceiling) + 1)), true
+}
+
+// wait blocks for given delay, returns false without waiting when the delay
+// would outlast the deadline of given context or when it is done first
+func wait(ctx context.Context, delay time.Duration) bool {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+		return false
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// isRetryableStatus reports whether given status code is a transient PLD
+// server failure
+func isRetryableStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout, http.StatusTooManyRequests:
+		return true
+	default:
+		return false
+	}
+}
+
+// parseRetryAfter reads Retry-After header value, either seconds or an HTTP
+// date, returns zero when it is empty, malformed or already passed
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil || !date.After(now) {
+		return 0
+	}
+
+	return date.Sub(now)
+}
@@ -4,25 +4,41 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"log"
 	"net/http"
+	"time"
 
 	"github.com/alamrios/crabi-solution/config"
 	"github.com/alamrios/crabi-solution/internal/app/pld"
 )
 
+// maxResponseSize bytes of PLD responses read, larger bodies are truncated
+const maxResponseSize = 1 << 20
+
+// auditTimeout time given to store a screening attempt, it does not depend on
+// the request context so canceled checks are recorded too
+const auditTimeout = 5 * time.Second
+
+// HttpClient contract for http requests to PLD service
 type HttpClient interface {
-	Post(URL string, data []byte, contentType string) (*http.Response, error)
+	// Post sends a post request canceled when given context is done, callers
+	// must close the response body
+	Post(ctx context.Context, URL string, data []byte, contentType string) (*http.Response, error)
 }
 
 // Service struct for PLD service
 type Service struct {
-	URL        string
-	httpClient HttpClient
+	URL           string
+	provider      string
+	httpClient    HttpClient
+	screeningRepo pld.ScreeningRepository
+	retry         *retryPolicy
 }
 
 // NewService PLD service constructor
-func NewService(cfg *config.PLD, httpClient HttpClient) (*Service, error) {
+func NewService(cfg *config.PLD, httpClient HttpClient, screeningRepo pld.ScreeningRepository) (*Service, error) {
 	if cfg == nil {
 		return nil, fmt.Errorf("pld config is nil")
 	}
@@ -31,9 +47,16 @@ func NewService(cfg *config.PLD, httpClient HttpClient) (*Service, error) {
 		return nil, fmt.Errorf("http client is nil")
 	}
 
+	if screeningRepo == nil {
+		return nil, fmt.Errorf("screening repo is nil")
+	}
+
 	return &Service{
-		URL:        cfg.Protocol + cfg.Host + ":" + cfg.Port + cfg.URI,
-		httpClient: httpClient,
+		URL:           cfg.Protocol + cfg.Host + ":" + cfg.Port + cfg.URI,
+		provider:      cfg.Provider,
+		httpClient:    httpClient,
+		screeningRepo: screeningRepo,
+		retry:         newRetryPolicy(cfg.Retry),
 	}, nil
 }
 
@@ -44,14 +67,22 @@ type CheckBlacklistRequest struct {
 	Email     string `json:"email"`
 }
 
-// CheckBlacklistResponse struct for CheckBlacklist response
+// CheckBlacklistResponse struct for CheckBlacklist response, only
+// is_in_blacklist is always sent
 type CheckBlacklistResponse struct {
 	IsInBlacklist bool `json:"is_in_blacklist"`
+	// Decision clear, hit or review, derived from IsInBlacklist when empty
+	Decision    string   `json:"decision,omitempty"`
+	Lists       []string `json:"lists,omitempty"`
+	Score       float64  `json:"score,omitempty"`
+	ReferenceID string   `json:"reference_id,omitempty"`
 }
 
 // CheckBlacklist goes to PLD Service to ckeck if data is in black list
-// Returns error if user found in pld blacklist, nil otherwise
-func (s *Service) CheckBlacklist(ctx context.Context, request pld.Request) error {
+// Returns screening result, error if the check could not be made. Every
+// attempt is recorded in the screenings audit trail before going on, the
+// check fails with ErrScreeningNotRecorded when an attempt can not be.
+func (s *Service) CheckBlacklist(ctx context.Context, request pld.Request) (*pld.ScreeningResult, error) {
 	requestBody := CheckBlacklistRequest{
 		FirstName: request.FirstName,
 		LastName:  request.LastName,
@@ -60,30 +91,158 @@ func (s *Service) CheckBlacklist(ctx context.Context, request pld.Request) error
 
 	data, err := json.Marshal(requestBody)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	response, err := s.httpClient.Post(s.URL, data, "application/json")
+	var result *pld.ScreeningResult
+	for attempt := 1; ; attempt++ {
+		screening := pld.Screening{
+			UserID:   request.UserID,
+			Provider: s.provider,
+			Request:  request,
+		}
+		result, err = s.checkBlacklist(ctx, data, &screening)
+
+		auditErr := s.saveScreening(newScreening(screening, result, err))
+		if auditErr != nil {
+			return nil, auditErr
+		}
+
+		retryable, ok := err.(*retryableError)
+		if !ok {
+			break
+		}
+
+		err = retryable.err
+		if attempt >= s.retry.maxAttempts {
+			break
+		}
+
+		delay, ok := s.retry.delay(attempt, retryable.retryAfter)
+		if !ok || !wait(ctx, delay) {
+			break
+		}
+	}
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	fmt.Println(response)
+	return result, nil
+}
+
+// checkBlacklist sends a single check request to PLD service and fills given
+// screening with the exchange, failures worth sending again are returned as
+// retryableError
+func (s *Service) checkBlacklist(ctx context.Context, data []byte, screening *pld.Screening) (*pld.ScreeningResult, error) {
+	start := time.Now()
+	defer func() {
+		screening.Latency = time.Since(start)
+		screening.CheckedAt = start.UTC()
+	}()
+
+	response, err := s.httpClient.Post(ctx, s.URL, data, "application/json")
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return nil, &retryableError{err: err}
+	}
+	defer closeBody(response)
+
+	screening.StatusCode = response.StatusCode
+	var bodyBytes []byte
+	if response.Body != nil {
+		bodyBytes, err = ioutil.ReadAll(io.LimitReader(response.Body, maxResponseSize))
+		screening.ResponseBody = string(bodyBytes)
+	}
 
 	if response.StatusCode != 201 {
-		return fmt.Errorf("pld server returned %d status code", response.StatusCode)
+		err = &pld.ProviderError{StatusCode: response.StatusCode}
+		if isRetryableStatus(response.StatusCode) {
+			return nil, &retryableError{
+				err:        err,
+				retryAfter: parseRetryAfter(response.Header.Get("Retry-After"), time.Now()),
+			}
+		}
+		return nil, err
 	}
 
-	bodyBytes, err := ioutil.ReadAll(response.Body)
 	if err != nil {
-		return err
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return nil, &retryableError{err: err}
 	}
 	var responseObject CheckBlacklistResponse
-	json.Unmarshal(bodyBytes, &responseObject)
+	err = json.Unmarshal(bodyBytes, &responseObject)
+	if err != nil {
+		return nil, pld.ErrInvalidResponse
+	}
+
+	return newScreeningResult(&responseObject, start.UTC())
+}
+
+// newScreening returns given screening attempt with its result or error
+func newScreening(screening pld.Screening, result *pld.ScreeningResult, err error) pld.Screening {
+	if err != nil {
+		screening.Error = err.Error()
+	}
+	if result != nil {
+		screening.Decision = result.Decision
+		screening.Lists = result.Lists
+		screening.Score = result.Score
+		screening.ReferenceID = result.ReferenceID
+	}
+
+	return screening
+}
+
+// saveScreening records given screening attempt, returns
+// ErrScreeningNotRecorded when it could not be stored
+func (s *Service) saveScreening(screening pld.Screening) error {
+	ctx, cancel := context.WithTimeout(context.Background(), auditTimeout)
+	defer cancel()
 
-	if responseObject.IsInBlacklist {
-		return fmt.Errorf("user was found in pld blacklist")
+	err := s.screeningRepo.SaveScreening(ctx, screening)
+	if err != nil {
+		log.Printf("error saving pld screening of user %s: %v", screening.UserID, err)
+		return pld.ErrScreeningNotRecorded
 	}
 
 	return nil
 }
+
+// newScreeningResult returns screening result of given PLD response checked
+// at given date
+func newScreeningResult(response *CheckBlacklistResponse, checkedAt time.Time) (*pld.ScreeningResult, error) {
+	decision := pld.Decision(response.Decision)
+	if decision == "" {
+		decision = pld.DecisionClear
+		if response.IsInBlacklist {
+			decision = pld.DecisionHit
+		}
+	}
+
+	if !decision.Valid() {
+		return nil, pld.ErrInvalidResponse
+	}
+
+	return &pld.ScreeningResult{
+		Decision:    decision,
+		Lists:       response.Lists,
+		Score:       response.Score,
+		ReferenceID: response.ReferenceID,
+		CheckedAt:   checkedAt,
+	}, nil
+}
+
+// closeBody drains and closes body of given response so its connection can
+// be reused
+func closeBody(response *http.Response) {
+	if response.Body == nil {
+		return
+	}
+
+	io.Copy(ioutil.Discard, io.LimitReader(response.Body, maxResponseSize))
+	response.Body.Close()
+}
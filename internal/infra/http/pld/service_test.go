@@ -8,8 +8,10 @@ import (
 	"io"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 
 	"github.com/alamrios/crabi-solution/config"
 	model "github.com/alamrios/crabi-solution/internal/app/pld"
@@ -17,25 +19,40 @@ import (
 	tmock "github.com/alamrios/crabi-solution/test/mock"
 )
 
+// saveScreeningCall accepts every recorded screening attempt
+var saveScreeningCall = tmock.Call{
+	FunctionName: "SaveScreening",
+	Params:       []interface{}{mock.Anything},
+	Returns:      []interface{}{nil},
+}
+
 func TestNewService(t *testing.T) {
 	testCases := map[string]struct {
-		cfg        *config.PLD
-		httpClient *tmock.HttpClient
-		err        string
+		cfg           *config.PLD
+		httpClient    *tmock.HttpClient
+		screeningRepo model.ScreeningRepository
+		err           string
 	}{
 		"success": {
-			cfg:        &config.PLD{},
-			httpClient: &tmock.HttpClient{},
+			cfg:           &config.PLD{},
+			httpClient:    &tmock.HttpClient{},
+			screeningRepo: &tmock.ScreeningRepository{},
 		},
 		"nil config should return error": {
+			httpClient:    &tmock.HttpClient{},
+			screeningRepo: &tmock.ScreeningRepository{},
+			err:           "pld config is nil",
+		},
+		"nil screening repo should return error": {
+			cfg:        &config.PLD{},
 			httpClient: &tmock.HttpClient{},
-			err:        "pld config is nil",
+			err:        "screening repo is nil",
 		},
 	}
 
 	for name, tc := range testCases {
 		t.Run(name, func(t *testing.T) {
-			got, err := pld.NewService(tc.cfg, tc.httpClient)
+			got, err := pld.NewService(tc.cfg, tc.httpClient, tc.screeningRepo)
 			if tc.err != "" {
 				assert.EqualError(t, err, tc.err)
 				assert.Nil(t, got)
@@ -82,9 +99,21 @@ func TestCheckBlacklist(t *testing.T) {
 	data2, _ := json.Marshal(response2)
 	reader2 := io.NopCloser(bytes.NewReader(data2))
 
+	response3 := pld.CheckBlacklistResponse{
+		IsInBlacklist: true,
+		Decision:      "review",
+		Lists:         []string{"OFAC"},
+		Score:         72.5,
+		ReferenceID:   "scr_1",
+	}
+
+	data3, _ := json.Marshal(response3)
+	reader3 := io.NopCloser(bytes.NewReader(data3))
+
 	tests := map[string]struct {
 		httpClientCalls []tmock.Call
 		request         model.Request
+		expected        *model.ScreeningResult
 		err             error
 	}{
 		"success": {
@@ -104,9 +133,10 @@ func TestCheckBlacklist(t *testing.T) {
 					},
 				},
 			},
-			request: request1,
+			request:  request1,
+			expected: &model.ScreeningResult{Decision: model.DecisionClear},
 		},
-		"data found in pld service should return error": {
+		"data found in pld service should return hit": {
 			httpClientCalls: []tmock.Call{
 				{
 					FunctionName: "Post",
@@ -123,8 +153,53 @@ func TestCheckBlacklist(t *testing.T) {
 					},
 				},
 			},
+			request:  request2,
+			expected: &model.ScreeningResult{Decision: model.DecisionHit},
+		},
+		"provider decision should be returned": {
+			httpClientCalls: []tmock.Call{
+				{
+					FunctionName: "Post",
+					Params: []interface{}{
+						cfg.Protocol + cfg.Host + ":" + cfg.Port + cfg.URI,
+						"application/json",
+					},
+					Returns: []interface{}{
+						&http.Response{
+							StatusCode: 201,
+							Body:       reader3,
+						},
+						nil,
+					},
+				},
+			},
 			request: request2,
-			err:     fmt.Errorf("user was found in pld blacklist"),
+			expected: &model.ScreeningResult{
+				Decision:    model.DecisionReview,
+				Lists:       []string{"OFAC"},
+				Score:       72.5,
+				ReferenceID: "scr_1",
+			},
+		},
+		"unknown decision should return error": {
+			httpClientCalls: []tmock.Call{
+				{
+					FunctionName: "Post",
+					Params: []interface{}{
+						cfg.Protocol + cfg.Host + ":" + cfg.Port + cfg.URI,
+						"application/json",
+					},
+					Returns: []interface{}{
+						&http.Response{
+							StatusCode: 201,
+							Body:       io.NopCloser(bytes.NewReader([]byte(`{"decision":"maybe"}`))),
+						},
+						nil,
+					},
+				},
+			},
+			request: request1,
+			err:     model.ErrInvalidResponse,
 		},
 		"http client error should propagate": {
 			httpClientCalls: []tmock.Call{
@@ -160,22 +235,313 @@ func TestCheckBlacklist(t *testing.T) {
 				},
 			},
 			request: request1,
-			err:     fmt.Errorf("pld server returned 400 status code"),
+			err:     &model.ProviderError{StatusCode: 400},
+		},
+		"invalid response should return error": {
+			httpClientCalls: []tmock.Call{
+				{
+					FunctionName: "Post",
+					Params: []interface{}{
+						cfg.Protocol + cfg.Host + ":" + cfg.Port + cfg.URI,
+						"application/json",
+					},
+					Returns: []interface{}{
+						&http.Response{
+							StatusCode: 201,
+							Body:       io.NopCloser(bytes.NewReader([]byte("not json"))),
+						},
+						nil,
+					},
+				},
+			},
+			request: request1,
+			err:     model.ErrInvalidResponse,
 		},
 	}
 	for name, tt := range tests {
 
 		request := tt.request
-		expected := tt.err
+		expected := tt.expected
+		expectedErr := tt.err
 		httpClientCalls := tt.httpClientCalls
 
 		t.Run(name, func(t *testing.T) {
 			httpClient := tmock.NewHttpClient().AddCall(t, httpClientCalls)
-			pldService, err := pld.NewService(&cfg, httpClient)
+			screeningRepo := tmock.NewScreeningRepository().AddCall(t, []tmock.Call{saveScreeningCall})
+			pldService, err := pld.NewService(&cfg, httpClient, screeningRepo)
+			assert.NoError(t, err)
+
+			got, err := pldService.CheckBlacklist(ctx, request)
+			assert.Equal(t, expectedErr, err)
+			if expected == nil {
+				assert.Nil(t, got)
+				return
+			}
+
+			assert.False(t, got.CheckedAt.IsZero())
+			got.CheckedAt = time.Time{}
+			assert.Equal(t, expected, got)
+		})
+	}
+}
+
+func TestCheckBlacklistRetry(t *testing.T) {
+	cfg := config.PLD{
+		Protocol: "http://",
+		Host:     "crabi-pld",
+		Port:     "3000",
+		URI:      "/check-blacklist",
+		Retry: config.Retry{
+			MaxAttempts: 3,
+			BaseDelay:   time.Millisecond,
+			MaxDelay:    2 * time.Second,
+		},
+	}
+	url := cfg.Protocol + cfg.Host + ":" + cfg.Port + cfg.URI
+
+	request := model.Request{
+		FirstName: "Dua",
+		LastName:  "Lipa",
+		Email:     "dua@lipa.com",
+	}
+
+	newResponse := func(statusCode int, retryAfter string) *http.Response {
+		data, _ := json.Marshal(pld.CheckBlacklistResponse{})
+		response := &http.Response{
+			StatusCode: statusCode,
+			Header:     http.Header{},
+			Body:       io.NopCloser(bytes.NewReader(data)),
+		}
+		if retryAfter != "" {
+			response.Header.Set("Retry-After", retryAfter)
+		}
+		return response
+	}
+
+	tests := map[string]struct {
+		httpClientCalls []tmock.Call
+		timeout         time.Duration
+		calls           int
+		err             error
+	}{
+		"unavailable server should be retried": {
+			httpClientCalls: []tmock.Call{
+				{
+					FunctionName: "Post",
+					Params:       []interface{}{url, "application/json"},
+					Returns:      []interface{}{newResponse(503, ""), nil},
+					Times:        1,
+				},
+				{
+					FunctionName: "Post",
+					Params:       []interface{}{url, "application/json"},
+					Returns:      []interface{}{newResponse(201, ""), nil},
+					Times:        1,
+				},
+			},
+			calls: 2,
+		},
+		"network error should be retried until attempts run out": {
+			httpClientCalls: []tmock.Call{
+				{
+					FunctionName: "Post",
+					Params:       []interface{}{url, "application/json"},
+					Returns:      []interface{}{(*http.Response)(nil), fmt.Errorf("connection reset by peer")},
+				},
+			},
+			calls: 3,
+			err:   fmt.Errorf("connection reset by peer"),
+		},
+		"client error should not be retried": {
+			httpClientCalls: []tmock.Call{
+				{
+					FunctionName: "Post",
+					Params:       []interface{}{url, "application/json"},
+					Returns:      []interface{}{newResponse(400, ""), nil},
+				},
+			},
+			calls: 1,
+			err:   &model.ProviderError{StatusCode: 400},
+		},
+		"retry after over max delay should not be retried": {
+			httpClientCalls: []tmock.Call{
+				{
+					FunctionName: "Post",
+					Params:       []interface{}{url, "application/json"},
+					Returns:      []interface{}{newResponse(429, "120"), nil},
+				},
+			},
+			calls: 1,
+			err:   &model.ProviderError{StatusCode: 429},
+		},
+		"retry after past context deadline should not be retried": {
+			httpClientCalls: []tmock.Call{
+				{
+					FunctionName: "Post",
+					Params:       []interface{}{url, "application/json"},
+					Returns:      []interface{}{newResponse(504, "1"), nil},
+				},
+			},
+			timeout: 100 * time.Millisecond,
+			calls:   1,
+			err:     &model.ProviderError{StatusCode: 504},
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+
+			httpClient := tmock.NewHttpClient().AddCall(t, tt.httpClientCalls)
+			screeningRepo := tmock.NewScreeningRepository().AddCall(t, []tmock.Call{saveScreeningCall})
+			pldService, err := pld.NewService(&cfg, httpClient, screeningRepo)
 			assert.NoError(t, err)
 
-			got := pldService.CheckBlacklist(ctx, request)
-			assert.Equal(t, got, expected)
+			_, got := pldService.CheckBlacklist(ctx, request)
+			assert.Equal(t, tt.err, got)
+			httpClient.AssertNumberOfCalls(t, "Post", tt.calls)
+			screeningRepo.AssertNumberOfCalls(t, "SaveScreening", tt.calls)
 		})
 	}
 }
+
+func TestCheckBlacklistAudit(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.PLD{
+		Protocol: "http://",
+		Host:     "crabi-pld",
+		Port:     "3000",
+		URI:      "/check-blacklist",
+		Provider: "crabi-pld",
+		Retry: config.Retry{
+			MaxAttempts: 2,
+			BaseDelay:   time.Millisecond,
+			MaxDelay:    time.Second,
+		},
+	}
+	url := cfg.Protocol + cfg.Host + ":" + cfg.Port + cfg.URI
+
+	request := model.Request{
+		UserID:    "user1",
+		FirstName: "Dua",
+		LastName:  "Lipa",
+		Email:     "dua@lipa.com",
+	}
+
+	httpClient := tmock.NewHttpClient().AddCall(t, []tmock.Call{
+		{
+			FunctionName: "Post",
+			Params:       []interface{}{url, "application/json"},
+			Returns: []interface{}{
+				&http.Response{
+					StatusCode: 503,
+					Header:     http.Header{},
+					Body:       io.NopCloser(bytes.NewReader([]byte("unavailable"))),
+				},
+				nil,
+			},
+			Times: 1,
+		},
+		{
+			FunctionName: "Post",
+			Params:       []interface{}{url, "application/json"},
+			Returns: []interface{}{
+				&http.Response{
+					StatusCode: 201,
+					Body:       io.NopCloser(bytes.NewReader([]byte(`{"is_in_blacklist":true,"reference_id":"scr_1"}`))),
+				},
+				nil,
+			},
+			Times: 1,
+		},
+	})
+	screeningRepo := tmock.NewScreeningRepository().AddCall(t, []tmock.Call{saveScreeningCall})
+
+	pldService, err := pld.NewService(&cfg, httpClient, screeningRepo)
+	assert.NoError(t, err)
+
+	got, err := pldService.CheckBlacklist(ctx, request)
+	assert.NoError(t, err)
+	assert.Equal(t, model.DecisionHit, got.Decision)
+	screeningRepo.AssertNumberOfCalls(t, "SaveScreening", 2)
+
+	expected := []model.Screening{
+		{
+			UserID:       "user1",
+			Provider:     "crabi-pld",
+			Request:      request,
+			StatusCode:   503,
+			ResponseBody: "unavailable",
+			Error:        "pld server returned 503 status code",
+		},
+		{
+			UserID:       "user1",
+			Provider:     "crabi-pld",
+			Request:      request,
+			StatusCode:   201,
+			ResponseBody: `{"is_in_blacklist":true,"reference_id":"scr_1"}`,
+			Decision:     model.DecisionHit,
+			ReferenceID:  "scr_1",
+		},
+	}
+	for i, call := range screeningRepo.Calls {
+		screening := call.Arguments.Get(0).(model.Screening)
+		assert.False(t, screening.CheckedAt.IsZero())
+		assert.True(t, screening.Latency >= 0)
+		screening.CheckedAt, screening.Latency = time.Time{}, 0
+		assert.Equal(t, expected[i], screening)
+	}
+}
+
+func TestCheckBlacklistAuditFailure(t *testing.T) {
+	cfg := config.PLD{
+		Protocol: "http://",
+		Host:     "crabi-pld",
+		Port:     "3000",
+		URI:      "/check-blacklist",
+		Provider: "crabi-pld",
+		Retry: config.Retry{
+			MaxAttempts: 2,
+			BaseDelay:   time.Millisecond,
+			MaxDelay:    time.Second,
+		},
+	}
+	url := cfg.Protocol + cfg.Host + ":" + cfg.Port + cfg.URI
+
+	httpClient := tmock.NewHttpClient().AddCall(t, []tmock.Call{
+		{
+			FunctionName: "Post",
+			Params:       []interface{}{url, "application/json"},
+			Returns: []interface{}{
+				&http.Response{
+					StatusCode: 503,
+					Header:     http.Header{},
+					Body:       io.NopCloser(bytes.NewReader([]byte("unavailable"))),
+				},
+				nil,
+			},
+		},
+	})
+	screeningRepo := tmock.NewScreeningRepository().AddCall(t, []tmock.Call{
+		{
+			FunctionName: "SaveScreening",
+			Params:       []interface{}{mock.Anything},
+			Returns:      []interface{}{fmt.Errorf("db error")},
+		},
+	})
+
+	pldService, err := pld.NewService(&cfg, httpClient, screeningRepo)
+	assert.NoError(t, err)
+
+	// unrecorded attempts fail the check and are not retried
+	_, err = pldService.CheckBlacklist(context.Background(), model.Request{UserID: "user1"})
+	assert.Equal(t, model.ErrScreeningNotRecorded, err)
+	httpClient.AssertNumberOfCalls(t, "Post", 1)
+	screeningRepo.AssertNumberOfCalls(t, "SaveScreening", 1)
+}
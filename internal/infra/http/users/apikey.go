@@ -0,0 +1,204 @@
+package users
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/alamrios/crabi-solution/internal/app/auth"
+	"github.com/alamrios/crabi-solution/internal/app/user"
+)
+
+// verifyCredentials authenticates machine callers with an API key sent in
+// the X-API-Key header or as Bearer token, any other request goes through
+// verifyJWT
+func (h *Router) verifyCredentials(handler func(w http.ResponseWriter, r *http.Request)) http.HandlerFunc {
+	jwtHandler := h.verifyJWT(handler)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, ok := h.apiKeyFromRequest(r)
+		if !ok {
+			jwtHandler(w, r)
+			return
+		}
+
+		apiKey, err := h.apiKeyService.AuthenticateAPIKey(r.Context(), key)
+		if errors.Is(err, auth.ErrInvalidAPIKey) {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			errMsg := "you're Unauthorized due to invalid api key"
+			http.Error(w, errMsg, http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			log.Println("error while api key authentication: ", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		claims := &Claims{
+			Scope:    strings.Join(apiKey.Scopes, " "),
+			APIKeyID: apiKey.ID,
+		}
+		claims.Subject = apiKey.Owner
+
+		ctx := context.WithValue(r.Context(), claimsContextKey, claims)
+		handler(w, r.WithContext(ctx))
+	})
+}
+
+// apiKeyFromRequest reads API key from X-API-Key header or from an
+// Authorization Bearer value carrying the API key prefix
+func (h *Router) apiKeyFromRequest(r *http.Request) (string, bool) {
+	key := r.Header.Get("X-API-Key")
+	if key != "" {
+		return key, true
+	}
+
+	token, ok := h.tokenFromRequest(r)
+	if ok && strings.HasPrefix(token, auth.APIKeyPrefix) {
+		return token, true
+	}
+
+	return "", false
+}
+
+type createAPIKeyRequest struct {
+	Name      string     `json:"name" validate:"required" example:"back-office"`
+	Owner     string     `json:"owner" validate:"required" example:"back-office-service"`
+	Scopes    []string   `json:"scopes" validate:"required" example:"users:create"`
+	ExpiresAt *time.Time `json:"expires_at" example:"2027-01-01T00:00:00Z"`
+}
+
+type apiKeyResponse struct {
+	ID         string     `json:"id" validate:"required" example:"3f9a1c2b7d4e8f60"`
+	Key        string     `json:"key,omitempty" example:"crb_3f9a1c2b7d4e8f60_k3S0zN9rT6xQ..."`
+	Name       string     `json:"name" validate:"required" example:"back-office"`
+	Owner      string     `json:"owner" validate:"required" example:"back-office-service"`
+	Scopes     []string   `json:"scopes" validate:"required" example:"users:create"`
+	CreatedBy  string     `json:"created_by" validate:"required" example:"joaquin@guzman.com"`
+	CreatedAt  time.Time  `json:"created_at" validate:"required" example:"2026-01-01T00:00:00Z"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty" example:"2027-01-01T00:00:00Z"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" example:"2026-01-02T00:00:00Z"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" example:"2026-01-03T00:00:00Z"`
+}
+
+func newAPIKeyResponse(apiKey auth.APIKey) apiKeyResponse {
+	return apiKeyResponse{
+		ID:         apiKey.ID,
+		Name:       apiKey.Name,
+		Owner:      apiKey.Owner,
+		Scopes:     apiKey.Scopes,
+		CreatedBy:  apiKey.CreatedBy,
+		CreatedAt:  apiKey.CreatedAt,
+		ExpiresAt:  apiKey.ExpiresAt,
+		LastUsedAt: apiKey.LastUsedAt,
+		RevokedAt:  apiKey.RevokedAt,
+	}
+}
+
+// createAPIKey godoc
+// @Description Creates an API key for a machine caller, the secret key is only returned in this response.
+// @Param name query string false "API key name"
+// @Param owner query string false "Service that owns the API key"
+// @Param scopes query []string false "Permissions granted to the API key"
+// @Param expires_at query string false "Optional expiration date, RFC 3339"
+// @Success 201 {object} jsonapi.Response{apiKeyResponse}
+func (h *Router) createAPIKey(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	ctx := r.Context()
+
+	claims, _ := ClaimsFromContext(ctx)
+
+	var request createAPIKeyRequest
+	reqBody, _ := ioutil.ReadAll(r.Body)
+	json.Unmarshal(reqBody, &request)
+
+	for _, scope := range request.Scopes {
+		if !user.Permission(scope).Valid() {
+			http.Error(w, fmt.Sprintf("unknown scope %q", scope), http.StatusBadRequest)
+			return
+		}
+	}
+
+	apiKey, key, err := h.apiKeyService.CreateAPIKey(ctx, auth.APIKey{
+		Name:      request.Name,
+		Owner:     request.Owner,
+		Scopes:    request.Scopes,
+		CreatedBy: claims.Email,
+		ExpiresAt: request.ExpiresAt,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := newAPIKeyResponse(*apiKey)
+	response.Key = key
+
+	payload, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(payload)
+}
+
+// listAPIKeys godoc
+// @Description Lists every API key without its secret, including expired and revoked ones.
+// @Success 200 {object} jsonapi.Response{[]apiKeyResponse}
+func (h *Router) listAPIKeys(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	ctx := r.Context()
+
+	apiKeys, err := h.apiKeyService.ListAPIKeys(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]apiKeyResponse, 0, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		response = append(response, newAPIKeyResponse(apiKey))
+	}
+
+	payload, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(payload)
+}
+
+// revokeAPIKey godoc
+// @Description Revokes the API key with given id.
+// @Param id query string false "API key id"
+// @Success 204
+func (h *Router) revokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	ctx := r.Context()
+
+	id := mux.Vars(r)["id"]
+
+	err := h.apiKeyService.RevokeAPIKey(ctx, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
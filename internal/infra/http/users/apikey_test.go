@@ -0,0 +1,94 @@
+package users
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/alamrios/crabi-solution/internal/app/auth"
+	"github.com/alamrios/crabi-solution/internal/app/user"
+	tmock "github.com/alamrios/crabi-solution/test/mock"
+)
+
+func TestVerifyCredentialsAPIKey(t *testing.T) {
+	id := "3f9a1c2b7d4e8f60"
+	key := auth.APIKeyPrefix + id + "_k3S0zN9rT6xQ"
+	sum := sha256.Sum256([]byte(key))
+
+	apiKey := &auth.APIKey{
+		ID:     id,
+		Hash:   hex.EncodeToString(sum[:]),
+		Owner:  "back-office-service",
+		Scopes: []string{string(user.PermissionUsersCreate)},
+	}
+
+	testCases := map[string]struct {
+		headers    map[string]string
+		permission user.Permission
+		expected   int
+	}{
+		"x-api-key header with granted scope": {
+			headers:    map[string]string{"X-API-Key": key},
+			permission: user.PermissionUsersCreate,
+			expected:   http.StatusOK,
+		},
+		"bearer api key with granted scope": {
+			headers:    map[string]string{"Authorization": "Bearer " + key},
+			permission: user.PermissionUsersCreate,
+			expected:   http.StatusOK,
+		},
+		"missing scope should be forbidden": {
+			headers:    map[string]string{"X-API-Key": key},
+			permission: user.PermissionUsersUnlock,
+			expected:   http.StatusForbidden,
+		},
+		"wrong key should be unauthorized": {
+			headers:    map[string]string{"X-API-Key": auth.APIKeyPrefix + id + "_other"},
+			permission: user.PermissionUsersCreate,
+			expected:   http.StatusUnauthorized,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			repo := tmock.NewAPIKeyRepository().AddCall(t, []tmock.Call{
+				{
+					FunctionName: "GetAPIKey",
+					Params:       []interface{}{id},
+					Returns:      []interface{}{apiKey, nil},
+				},
+				{
+					FunctionName: "UpdateAPIKeyLastUsed",
+					Params:       []interface{}{id, mock.AnythingOfType("time.Time")},
+					Returns:      []interface{}{nil},
+				},
+			})
+			apiKeyService, err := auth.NewAPIKeyService(repo)
+			assert.NoError(t, err)
+
+			router := newTestRouter(t)
+			router.apiKeyService = apiKeyService
+
+			handler := router.verifyCredentials(router.authorize(tc.permission, func(w http.ResponseWriter, r *http.Request) {
+				claims, ok := ClaimsFromContext(r.Context())
+				assert.True(t, ok)
+				assert.Equal(t, id, claims.APIKeyID)
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/users/", nil)
+			for header, value := range tc.headers {
+				r.Header.Set(header, value)
+			}
+			w := httptest.NewRecorder()
+
+			handler(w, r)
+			assert.Equal(t, tc.expected, w.Code)
+		})
+	}
+}
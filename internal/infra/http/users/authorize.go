@@ -0,0 +1,66 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/alamrios/crabi-solution/internal/app/user"
+)
+
+// errForbidden returned when an authenticated user lacks the required permission
+var errForbidden = errors.New("you're Forbidden due to insufficient permissions")
+
+// authorize allows the request only if the role of the verified token
+// grants given permission, must be wrapped by verifyJWT
+func (h *Router) authorize(permission user.Permission, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !hasPermission(r.Context(), permission) {
+			http.Error(w, errForbidden.Error(), http.StatusForbidden)
+			return
+		}
+
+		handler(w, r)
+	}
+}
+
+// authorizeOrSelf is like authorize but also allows users acting on their
+// own record, identified by the id route variable. Only user access tokens
+// carry the user id as subject, API keys and client tokens never act as self.
+func (h *Router) authorizeOrSelf(permission user.Permission, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := ClaimsFromContext(r.Context())
+		isSelf := ok && claims.Email != "" && claims.Subject == mux.Vars(r)["id"]
+
+		if !isSelf && !hasPermission(r.Context(), permission) {
+			http.Error(w, errForbidden.Error(), http.StatusForbidden)
+			return
+		}
+
+		handler(w, r)
+	}
+}
+
+// hasPermission reports whether the role or scopes of the verified
+// credentials in given context grant given permission
+func hasPermission(ctx context.Context, permission user.Permission) bool {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return false
+	}
+
+	if user.Role(claims.Role).Can(permission) {
+		return true
+	}
+
+	for _, scope := range strings.Fields(claims.Scope) {
+		if user.Permission(scope) == permission {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,110 @@
+package users
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/alamrios/crabi-solution/internal/app/user"
+)
+
+func TestAuthorize(t *testing.T) {
+	router := newTestRouter(t)
+
+	testCases := map[string]struct {
+		role       user.Role
+		email      string
+		subject    string
+		target     string
+		allowSelf  bool
+		permission user.Permission
+		expected   int
+	}{
+		"admin can unlock users": {
+			role:       user.RoleAdmin,
+			email:      "admin@crabi.com",
+			target:     "user1",
+			permission: user.PermissionUsersUnlock,
+			expected:   http.StatusOK,
+		},
+		"operator can not unlock users": {
+			role:       user.RoleOperator,
+			email:      "operator@crabi.com",
+			target:     "user1",
+			permission: user.PermissionUsersUnlock,
+			expected:   http.StatusForbidden,
+		},
+		"operator can read any user": {
+			role:       user.RoleOperator,
+			email:      "operator@crabi.com",
+			target:     "user1",
+			allowSelf:  true,
+			permission: user.PermissionUsersRead,
+			expected:   http.StatusOK,
+		},
+		"customer can read its own record": {
+			role:       user.RoleCustomer,
+			email:      "dua@lipa.com",
+			subject:    "user1",
+			target:     "user1",
+			allowSelf:  true,
+			permission: user.PermissionUsersRead,
+			expected:   http.StatusOK,
+		},
+		"customer can not read other users": {
+			role:       user.RoleCustomer,
+			email:      "dua@lipa.com",
+			subject:    "user1",
+			target:     "user2",
+			allowSelf:  true,
+			permission: user.PermissionUsersRead,
+			expected:   http.StatusForbidden,
+		},
+		"api key owner should not act as self": {
+			subject:    "user1",
+			target:     "user1",
+			allowSelf:  true,
+			permission: user.PermissionUsersRead,
+			expected:   http.StatusForbidden,
+		},
+		"customer can not create users": {
+			role:       user.RoleCustomer,
+			email:      "dua@lipa.com",
+			permission: user.PermissionUsersCreate,
+			expected:   http.StatusForbidden,
+		},
+		"unknown role should be forbidden": {
+			role:       "root",
+			email:      "dua@lipa.com",
+			permission: user.PermissionUsersCreate,
+			expected:   http.StatusForbidden,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			next := func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}
+
+			handler := router.authorize(tc.permission, next)
+			if tc.allowSelf {
+				handler = router.authorizeOrSelf(tc.permission, next)
+			}
+
+			claims := &Claims{Email: tc.email, Role: string(tc.role)}
+			claims.Subject = tc.subject
+			ctx := context.WithValue(context.Background(), claimsContextKey, claims)
+			r := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+			r = mux.SetURLVars(r, map[string]string{"id": tc.target})
+			w := httptest.NewRecorder()
+
+			handler(w, r)
+			assert.Equal(t, tc.expected, w.Code)
+		})
+	}
+}
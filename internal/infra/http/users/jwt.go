@@ -0,0 +1,344 @@
+package users
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+
+	"github.com/alamrios/crabi-solution/internal/app/auth"
+	"github.com/alamrios/crabi-solution/internal/app/user"
+)
+
+// errTokenRevoked returned when a well formed token was revoked
+var errTokenRevoked = errors.New("token was revoked")
+
+type contextKey string
+
+const claimsContextKey contextKey = "claims"
+
+// tokenUseMFAChallenge marks tokens that only prove the password step of an
+// MFA login, access tokens carry no token_use claim
+const tokenUseMFAChallenge = "mfa_challenge"
+
+// Claims struct for access token claims
+type Claims struct {
+	Email    string `json:"email"`
+	Role     string `json:"role,omitempty"`
+	TokenUse string `json:"token_use,omitempty"`
+	// ClientID set on tokens issued to OAuth2 clients
+	ClientID string `json:"client_id,omitempty"`
+	// Scope space separated permissions granted to machine callers
+	Scope string `json:"scope,omitempty"`
+	// SessionID login session of user access tokens
+	SessionID string `json:"sid,omitempty"`
+	// APIKeyID set when the request was authenticated with an API key
+	APIKeyID string `json:"-"`
+	jwt.StandardClaims
+}
+
+// ClaimsFromContext returns verified access token claims stored by verifyJWT
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsContextKey).(*Claims)
+	return claims, ok
+}
+
+func (h *Router) verifyJWT(handler func(w http.ResponseWriter, r *http.Request)) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenString, ok := h.tokenFromRequest(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			errMsg := "you're Unauthorized due to No token in the header"
+			http.Error(w, errMsg, http.StatusUnauthorized)
+			return
+		}
+
+		claims, err := h.parseJWT(tokenString)
+		if err != nil {
+			log.Println("error while jwt parse: ", err)
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			errMsg := "you're Unauthorized due to invalid token"
+			http.Error(w, errMsg, http.StatusUnauthorized)
+			return
+		}
+
+		err = h.checkRevocation(r.Context(), claims)
+		if errors.Is(err, errTokenRevoked) {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			errMsg := "you're Unauthorized due to revoked token"
+			http.Error(w, errMsg, http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			log.Println("error while jwt revocation check: ", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), claimsContextKey, claims)
+		handler(w, r.WithContext(ctx))
+	})
+}
+
+// tokenFromRequest reads token from Authorization Bearer header, falling
+// back to legacy Token header while it is enabled
+func (h *Router) tokenFromRequest(r *http.Request) (string, bool) {
+	authorization := r.Header.Get("Authorization")
+	if authorization != "" {
+		parts := strings.SplitN(authorization, " ", 2)
+		if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+			return "", false
+		}
+
+		token := strings.TrimSpace(parts[1])
+		return token, token != ""
+	}
+
+	if h.legacyTokenHeader {
+		token := r.Header.Get("Token")
+		return token, token != ""
+	}
+
+	return "", false
+}
+
+// parseJWT checks access token signature and registered claims
+func (h *Router) parseJWT(tokenString string) (*Claims, error) {
+	return h.parseToken(tokenString, "")
+}
+
+// parseMFAChallenge checks MFA challenge token signature and registered claims
+func (h *Router) parseMFAChallenge(tokenString string) (*Claims, error) {
+	return h.parseToken(tokenString, tokenUseMFAChallenge)
+}
+
+// parseToken checks token signature, registered claims and intended use
+func (h *Router) parseToken(tokenString, use string) (*Claims, error) {
+	parser := jwt.Parser{
+		// registered claims are validated by validateClaims to apply leeway
+		SkipClaimsValidation: true,
+	}
+
+	claims := &Claims{}
+	token, err := parser.ParseWithClaims(tokenString, claims, h.keys.keyFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	err = h.validateClaims(claims, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.TokenUse != use {
+		return nil, fmt.Errorf("token use %q is not accepted", claims.TokenUse)
+	}
+
+	return claims, nil
+}
+
+// revokedUntil returns when the revocation of token with given claims can be
+// forgotten, tokens are accepted until leeway after they expire
+func (h *Router) revokedUntil(claims *Claims) time.Time {
+	return time.Unix(claims.ExpiresAt, 0).Add(h.leeway)
+}
+
+// validateClaims checks registered claims allowing configured clock skew
+func (h *Router) validateClaims(claims *Claims, now time.Time) error {
+	leeway := int64(h.leeway.Seconds())
+	unix := now.Unix()
+
+	if claims.ExpiresAt == 0 {
+		return fmt.Errorf("token has no expiration")
+	}
+	if unix > claims.ExpiresAt+leeway {
+		return fmt.Errorf("token is expired")
+	}
+	if claims.NotBefore != 0 && unix < claims.NotBefore-leeway {
+		return fmt.Errorf("token is not valid yet")
+	}
+	if claims.IssuedAt != 0 && unix < claims.IssuedAt-leeway {
+		return fmt.Errorf("token used before issued")
+	}
+	if claims.Issuer != h.issuer {
+		return fmt.Errorf("token issuer %q is not accepted", claims.Issuer)
+	}
+	if claims.Audience != h.audience {
+		return fmt.Errorf("token audience %q is not accepted", claims.Audience)
+	}
+	if claims.Subject == "" {
+		return fmt.Errorf("token has no subject")
+	}
+	if claims.Id == "" {
+		return fmt.Errorf("token has no id")
+	}
+
+	return nil
+}
+
+// checkRevocation rejects tokens revoked by jti, issued to disabled clients,
+// issued before the user's sessions were revoked, carrying a role the user
+// no longer has or not belonging to an active session. Only client tokens
+// are issued without session.
+func (h *Router) checkRevocation(ctx context.Context, claims *Claims) error {
+	revoked, err := h.authService.IsTokenRevoked(ctx, claims.Id)
+	if err != nil {
+		return err
+	}
+
+	if revoked {
+		return errTokenRevoked
+	}
+
+	if claims.ClientID != "" {
+		client, err := h.clientService.GetClient(ctx, claims.ClientID)
+		if err != nil {
+			return err
+		}
+
+		if client == nil || client.DisabledAt != nil {
+			return errTokenRevoked
+		}
+
+		return nil
+	}
+
+	// tokens issued before user ids existed carry the email as subject
+	// and are rejected, clients get a new one with their refresh token
+	if claims.Subject == "" {
+		return errTokenRevoked
+	}
+
+	subject, err := h.userService.GetUser(ctx, claims.Subject)
+	if errors.Is(err, user.ErrUserNotFound) {
+		return errTokenRevoked
+	}
+	if err != nil {
+		return err
+	}
+
+	if subject.Email != claims.Email {
+		return errTokenRevoked
+	}
+
+	if subject.TokensValidAfter != nil && claims.IssuedAt < subject.TokensValidAfter.Unix() {
+		return errTokenRevoked
+	}
+
+	if claims.Role != string(subject.Role) {
+		return errTokenRevoked
+	}
+
+	// user tokens issued before sessions were recorded carry no session and
+	// are rejected, clients get a new one with their refresh token
+	if claims.SessionID == "" {
+		return errTokenRevoked
+	}
+
+	session, err := h.authService.GetSession(ctx, claims.SessionID)
+	if err != nil {
+		return err
+	}
+
+	if session == nil || session.Email != claims.Email {
+		return errTokenRevoked
+	}
+
+	h.authService.TouchSession(ctx, session)
+
+	return nil
+}
+
+func (h *Router) generateJWT(user *user.User, sessionID string) (string, error) {
+	claims := Claims{
+		Email:     user.Email,
+		Role:      string(user.Role),
+		SessionID: sessionID,
+	}
+	claims.Subject = user.ID
+
+	return h.generateToken(claims, h.accessTokenTTL)
+}
+
+// generateMFAChallenge returns a short lived single use token proving the
+// password step of an MFA login, not accepted as access token
+func (h *Router) generateMFAChallenge(user *user.User) (string, error) {
+	claims := Claims{
+		Email:    user.Email,
+		TokenUse: tokenUseMFAChallenge,
+	}
+	claims.Subject = user.ID
+
+	return h.generateToken(claims, h.mfaChallengeTTL)
+}
+
+// generateClientToken returns an access token for an OAuth2 client limited
+// to given space separated scope
+func (h *Router) generateClientToken(client *auth.Client, scope string) (string, error) {
+	claims := Claims{
+		ClientID: client.ID,
+		Scope:    scope,
+	}
+	claims.Subject = client.ID
+
+	return h.generateToken(claims, h.accessTokenTTL)
+}
+
+// generateToken signs given claims after setting the registered claims
+// shared by every token
+func (h *Router) generateToken(claims Claims, ttl time.Duration) (string, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims.Audience = h.audience
+	claims.ExpiresAt = now.Add(ttl).Unix()
+	claims.Id = jti
+	claims.IssuedAt = now.Unix()
+	claims.Issuer = h.issuer
+	claims.NotBefore = now.Unix()
+
+	return h.keys.sign(claims)
+}
+
+// newTokenID returns a random identifier for the jti claim
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// getJWKS godoc
+// @Description Public keys able to verify access tokens, as a JSON Web Key Set.
+// @Success 200 {object} jwks
+func (h *Router) getJWKS(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+
+	payload, err := json.Marshal(h.keys.publicJWKS())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	w.Write(payload)
+}
@@ -0,0 +1,308 @@
+package users
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/alamrios/crabi-solution/config"
+	"github.com/alamrios/crabi-solution/internal/app/auth"
+	"github.com/alamrios/crabi-solution/internal/app/user"
+	tmock "github.com/alamrios/crabi-solution/test/mock"
+)
+
+func newTestRouter(t *testing.T) *Router {
+	t.Helper()
+
+	keys, err := newKeySet(&config.JWT{SecretKey: "secret"})
+	assert.NoError(t, err)
+
+	return &Router{
+		keys:            keys,
+		issuer:          "crabi-solution",
+		audience:        "crabi-solution",
+		accessTokenTTL:  time.Hour,
+		leeway:          30 * time.Second,
+		mfaChallengeTTL: 5 * time.Minute,
+	}
+}
+
+func TestGenerateJWT(t *testing.T) {
+	router := newTestRouter(t)
+
+	token, err := router.generateJWT(&user.User{ID: "user1", Email: "dua@lipa.com", Role: user.RoleOperator}, "session1")
+	assert.NoError(t, err)
+
+	claims, err := router.parseJWT(token)
+	assert.NoError(t, err)
+	assert.Equal(t, "dua@lipa.com", claims.Email)
+	assert.Equal(t, "operator", claims.Role)
+	assert.Equal(t, "session1", claims.SessionID)
+	assert.Equal(t, "user1", claims.Subject)
+	assert.Equal(t, "crabi-solution", claims.Issuer)
+	assert.Equal(t, "crabi-solution", claims.Audience)
+	assert.NotEmpty(t, claims.Id)
+}
+
+func TestMFAChallengeToken(t *testing.T) {
+	router := newTestRouter(t)
+
+	challenge, err := router.generateMFAChallenge(&user.User{ID: "user1", Email: "dua@lipa.com"})
+	assert.NoError(t, err)
+
+	claims, err := router.parseMFAChallenge(challenge)
+	assert.NoError(t, err)
+	assert.Equal(t, "user1", claims.Subject)
+	assert.Equal(t, "dua@lipa.com", claims.Email)
+
+	_, err = router.parseJWT(challenge)
+	assert.Error(t, err, "mfa challenge should not be accepted as access token")
+
+	token, err := router.generateJWT(&user.User{Email: "dua@lipa.com", Role: user.RoleCustomer}, "session1")
+	assert.NoError(t, err)
+
+	_, err = router.parseMFAChallenge(token)
+	assert.Error(t, err, "access token should not be accepted as mfa challenge")
+}
+
+func TestParseJWT(t *testing.T) {
+	router := newTestRouter(t)
+	now := time.Now()
+
+	validClaims := func() Claims {
+		return Claims{
+			Email: "dua@lipa.com",
+			StandardClaims: jwt.StandardClaims{
+				Audience:  "crabi-solution",
+				ExpiresAt: now.Add(time.Hour).Unix(),
+				Id:        "jti1",
+				IssuedAt:  now.Unix(),
+				Issuer:    "crabi-solution",
+				NotBefore: now.Unix(),
+				Subject:   "dua@lipa.com",
+			},
+		}
+	}
+
+	testCases := map[string]struct {
+		claims func() Claims
+		err    string
+	}{
+		"success": {
+			claims: validClaims,
+		},
+		"expired within leeway should be accepted": {
+			claims: func() Claims {
+				claims := validClaims()
+				claims.ExpiresAt = now.Add(-10 * time.Second).Unix()
+				return claims
+			},
+		},
+		"expired token should return error": {
+			claims: func() Claims {
+				claims := validClaims()
+				claims.ExpiresAt = now.Add(-time.Minute).Unix()
+				return claims
+			},
+			err: "token is expired",
+		},
+		"missing expiration should return error": {
+			claims: func() Claims {
+				claims := validClaims()
+				claims.ExpiresAt = 0
+				return claims
+			},
+			err: "token has no expiration",
+		},
+		"future not before should return error": {
+			claims: func() Claims {
+				claims := validClaims()
+				claims.NotBefore = now.Add(time.Minute).Unix()
+				return claims
+			},
+			err: "token is not valid yet",
+		},
+		"unknown issuer should return error": {
+			claims: func() Claims {
+				claims := validClaims()
+				claims.Issuer = "other"
+				return claims
+			},
+			err: `token issuer "other" is not accepted`,
+		},
+		"unknown audience should return error": {
+			claims: func() Claims {
+				claims := validClaims()
+				claims.Audience = "other"
+				return claims
+			},
+			err: `token audience "other" is not accepted`,
+		},
+		"missing jti should return error": {
+			claims: func() Claims {
+				claims := validClaims()
+				claims.Id = ""
+				return claims
+			},
+			err: "token has no id",
+		},
+	}
+
+	for name, tc := range testCases {
+		claims := tc.claims()
+		expected := tc.err
+
+		t.Run(name, func(t *testing.T) {
+			token, err := router.keys.sign(claims)
+			assert.NoError(t, err)
+
+			got, err := router.parseJWT(token)
+			if expected != "" {
+				assert.EqualError(t, err, expected)
+				assert.Nil(t, got)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, claims.Email, got.Email)
+			}
+		})
+	}
+}
+
+func TestTokenFromRequest(t *testing.T) {
+	testCases := map[string]struct {
+		headers           map[string]string
+		legacyTokenHeader bool
+		expected          string
+		ok                bool
+	}{
+		"bearer header": {
+			headers:  map[string]string{"Authorization": "Bearer abc.def.ghi"},
+			expected: "abc.def.ghi",
+			ok:       true,
+		},
+		"bearer scheme is case insensitive": {
+			headers:  map[string]string{"Authorization": "bearer abc.def.ghi"},
+			expected: "abc.def.ghi",
+			ok:       true,
+		},
+		"other scheme should be rejected": {
+			headers:           map[string]string{"Authorization": "Basic ZHVhOmxpcGE="},
+			legacyTokenHeader: true,
+		},
+		"legacy header when enabled": {
+			headers:           map[string]string{"Token": "abc.def.ghi"},
+			legacyTokenHeader: true,
+			expected:          "abc.def.ghi",
+			ok:                true,
+		},
+		"legacy header when disabled should be rejected": {
+			headers: map[string]string{"Token": "abc.def.ghi"},
+		},
+		"missing header": {
+			legacyTokenHeader: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		headers := tc.headers
+		expected := tc.expected
+		ok := tc.ok
+		router := &Router{legacyTokenHeader: tc.legacyTokenHeader}
+
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/users/dua@lipa.com", nil)
+			for key, value := range headers {
+				r.Header.Set(key, value)
+			}
+
+			got, found := router.tokenFromRequest(r)
+			assert.Equal(t, ok, found)
+			assert.Equal(t, expected, got)
+		})
+	}
+}
+
+func TestVerifyJWTUserLookup(t *testing.T) {
+	user1 := &user.User{
+		ID:    "user1",
+		Email: "dua@lipa.com",
+		Role:  user.RoleCustomer,
+	}
+
+	testCases := map[string]struct {
+		userRepoCalls []tmock.Call
+		expectedCode  int
+	}{
+		"missing user should be unauthorized": {
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetUserByID",
+					Params:       []interface{}{"user1"},
+					Returns:      []interface{}{(*user.User)(nil), nil},
+				},
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+		"user repo error should fail": {
+			userRepoCalls: []tmock.Call{
+				{
+					FunctionName: "GetUserByID",
+					Params:       []interface{}{"user1"},
+					Returns:      []interface{}{(*user.User)(nil), fmt.Errorf("user repo error")},
+				},
+			},
+			expectedCode: http.StatusInternalServerError,
+		},
+	}
+
+	for name, tc := range testCases {
+		userRepo := tmock.NewUserRepository().AddCall(t, tc.userRepoCalls)
+		userService, err := user.NewService(
+			tmock.NewPLDService(),
+			userRepo,
+			tmock.NewPasswordHasher(),
+			user.LockoutPolicy{},
+			user.PasswordPolicy{},
+			tmock.NewEmailVerifier(),
+			tmock.NewSessionRevoker(),
+		)
+		assert.NoError(t, err)
+
+		revokedTokenRepo := tmock.NewRevokedTokenRepository().AddCall(t, []tmock.Call{
+			{
+				FunctionName: "IsTokenRevoked",
+				Params:       []interface{}{mock.Anything},
+				Returns:      []interface{}{false, nil},
+			},
+		})
+		authService, err := auth.NewService(tmock.NewRefreshTokenRepository(), revokedTokenRepo, tmock.NewSessionRepository(), time.Hour)
+		assert.NoError(t, err)
+
+		router := newTestRouter(t)
+		router.userService = userService
+		router.authService = authService
+
+		expectedCode := tc.expectedCode
+
+		t.Run(name, func(t *testing.T) {
+			token, err := router.generateJWT(user1, "session1")
+			assert.NoError(t, err)
+
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/users/user1", nil)
+			r.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+
+			router.verifyJWT(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})(w, r)
+			assert.Equal(t, expectedCode, w.Code)
+			userRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package users
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+
+	"github.com/golang-jwt/jwt"
+
+	"github.com/alamrios/crabi-solution/config"
+)
+
+// verificationKey public key (or hmac secret) able to verify a signing method
+type verificationKey struct {
+	method jwt.SigningMethod
+	key    interface{}
+}
+
+// keySet holds the active signing key and every key accepted for
+// verification, indexed by kid so keys can be rotated without downtime
+type keySet struct {
+	signingMethod    jwt.SigningMethod
+	signingKeyID     string
+	signingKey       interface{}
+	verificationKeys map[string]verificationKey
+}
+
+// newKeySet loads signing and verification keys described in config. Public
+// keys of previous signing keys are accepted with every signing method, and
+// the hmac secret keeps verifying tokens signed before switching to RS256 or
+// ES256 under its legacy kid.
+func newKeySet(cfg *config.JWT) (*keySet, error) {
+	keys := &keySet{
+		signingKeyID:     cfg.KeyID,
+		verificationKeys: map[string]verificationKey{},
+	}
+
+	var err error
+	switch cfg.SigningMethod {
+	case "", jwt.SigningMethodHS256.Alg():
+		if cfg.SecretKey == "" {
+			return nil, fmt.Errorf("jwt secret key is empty")
+		}
+		keys.signingMethod = jwt.SigningMethodHS256
+		keys.signingKey = []byte(cfg.SecretKey)
+		keys.verificationKeys[cfg.KeyID] = verificationKey{
+			method: jwt.SigningMethodHS256,
+			key:    keys.signingKey,
+		}
+	case jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg():
+		err = keys.loadPrivateKey(cfg)
+	default:
+		return nil, fmt.Errorf("unsupported jwt signing method %s", cfg.SigningMethod)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for kid, file := range cfg.PublicKeyFiles {
+		if kid == cfg.KeyID {
+			continue
+		}
+
+		key, err := loadPublicKey(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load jwt public key %s: %v", kid, err)
+		}
+		keys.verificationKeys[kid] = *key
+	}
+
+	if keys.signingMethod != jwt.SigningMethodHS256 && cfg.SecretKey != "" {
+		if _, ok := keys.verificationKeys[cfg.LegacyKeyID]; ok {
+			return nil, fmt.Errorf("jwt legacy key id %q is already used", cfg.LegacyKeyID)
+		}
+		keys.verificationKeys[cfg.LegacyKeyID] = verificationKey{
+			method: jwt.SigningMethodHS256,
+			key:    []byte(cfg.SecretKey),
+		}
+	}
+
+	return keys, nil
+}
+
+// loadPrivateKey sets the RS256 or ES256 signing key read from config
+func (k *keySet) loadPrivateKey(cfg *config.JWT) error {
+	if cfg.KeyID == "" {
+		return fmt.Errorf("jwt key id is empty")
+	}
+
+	data, err := os.ReadFile(cfg.PrivateKeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to read jwt private key: %v", err)
+	}
+
+	if cfg.SigningMethod == jwt.SigningMethodRS256.Alg() {
+		privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse jwt private key: %v", err)
+		}
+		k.signingMethod = jwt.SigningMethodRS256
+		k.signingKey = privateKey
+		k.verificationKeys[cfg.KeyID] = verificationKey{
+			method: jwt.SigningMethodRS256,
+			key:    &privateKey.PublicKey,
+		}
+		return nil
+	}
+
+	privateKey, err := jwt.ParseECPrivateKeyFromPEM(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse jwt private key: %v", err)
+	}
+	if privateKey.Curve != elliptic.P256() {
+		return fmt.Errorf("ES256 requires a P-256 private key")
+	}
+	k.signingMethod = jwt.SigningMethodES256
+	k.signingKey = privateKey
+	k.verificationKeys[cfg.KeyID] = verificationKey{
+		method: jwt.SigningMethodES256,
+		key:    &privateKey.PublicKey,
+	}
+
+	return nil
+}
+
+// loadPublicKey reads a PEM encoded RSA or P-256 EC public key
+func loadPublicKey(file string) (*verificationKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if rsaKey, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+		return &verificationKey{
+			method: jwt.SigningMethodRS256,
+			key:    rsaKey,
+		}, nil
+	}
+
+	ecKey, err := jwt.ParseECPublicKeyFromPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("key is neither RSA nor EC")
+	}
+	if ecKey.Curve != elliptic.P256() {
+		return nil, fmt.Errorf("EC keys should use P-256 curve")
+	}
+
+	return &verificationKey{
+		method: jwt.SigningMethodES256,
+		key:    ecKey,
+	}, nil
+}
+
+// sign returns signed token for given claims with the active key
+func (k *keySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.signingMethod, claims)
+	if k.signingKeyID != "" {
+		token.Header["kid"] = k.signingKeyID
+	}
+
+	return token.SignedString(k.signingKey)
+}
+
+// keyFunc selects verification key by kid header and checks token algorithm
+func (k *keySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	key, ok := k.verificationKeys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("signing method error")
+	}
+
+	return key.key, nil
+}
+
+// jwk struct for a JSON Web Key as defined by RFC 7517
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// jwks struct for a JSON Web Key Set
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+// publicJWKS returns asymmetric verification keys, hmac secrets are never published
+func (k *keySet) publicJWKS() jwks {
+	kids := make([]string, 0, len(k.verificationKeys))
+	for kid := range k.verificationKeys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	set := jwks{
+		Keys: []jwk{},
+	}
+
+	for _, kid := range kids {
+		key := k.verificationKeys[kid]
+
+		switch publicKey := key.key.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, jwk{
+				Kty: "RSA",
+				Kid: kid,
+				Use: "sig",
+				Alg: key.method.Alg(),
+				N:   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+			})
+		case *ecdsa.PublicKey:
+			size := (publicKey.Curve.Params().BitSize + 7) / 8
+			set.Keys = append(set.Keys, jwk{
+				Kty: "EC",
+				Kid: kid,
+				Use: "sig",
+				Alg: key.method.Alg(),
+				Crv: publicKey.Curve.Params().Name,
+				X:   base64.RawURLEncoding.EncodeToString(publicKey.X.FillBytes(make([]byte, size))),
+				Y:   base64.RawURLEncoding.EncodeToString(publicKey.Y.FillBytes(make([]byte, size))),
+			})
+		}
+	}
+
+	return set
+}
@@ -0,0 +1,216 @@
+package users
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/alamrios/crabi-solution/config"
+)
+
+func writePEM(t *testing.T, name, blockType string, der []byte) string {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), name)
+	err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600)
+	assert.NoError(t, err)
+
+	return file
+}
+
+func writeRSAKey(t *testing.T) (string, string) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	public, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	assert.NoError(t, err)
+
+	return writePEM(t, "rsa.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key)),
+		writePEM(t, "rsa.pub", "PUBLIC KEY", public)
+}
+
+func writeECKey(t *testing.T) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	private, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	public, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	assert.NoError(t, err)
+
+	return writePEM(t, "ec.pem", "EC PRIVATE KEY", private),
+		writePEM(t, "ec.pub", "PUBLIC KEY", public)
+}
+
+func TestKeySetSignAndVerify(t *testing.T) {
+	rsaPrivate, _ := writeRSAKey(t)
+	ecPrivate, _ := writeECKey(t)
+
+	testCases := map[string]struct {
+		cfg *config.JWT
+		alg string
+	}{
+		"HS256": {
+			cfg: &config.JWT{SigningMethod: "HS256", SecretKey: "secret"},
+			alg: "HS256",
+		},
+		"RS256": {
+			cfg: &config.JWT{SigningMethod: "RS256", KeyID: "rsa1", PrivateKeyFile: rsaPrivate},
+			alg: "RS256",
+		},
+		"ES256": {
+			cfg: &config.JWT{SigningMethod: "ES256", KeyID: "ec1", PrivateKeyFile: ecPrivate},
+			alg: "ES256",
+		},
+	}
+
+	for name, tc := range testCases {
+		cfg := tc.cfg
+		alg := tc.alg
+
+		t.Run(name, func(t *testing.T) {
+			keys, err := newKeySet(cfg)
+			assert.NoError(t, err)
+
+			signed, err := keys.sign(jwt.StandardClaims{Subject: "dua@lipa.com"})
+			assert.NoError(t, err)
+
+			token, err := jwt.Parse(signed, keys.keyFunc)
+			assert.NoError(t, err)
+			assert.True(t, token.Valid)
+			assert.Equal(t, alg, token.Method.Alg())
+			if cfg.KeyID != "" {
+				assert.Equal(t, cfg.KeyID, token.Header["kid"])
+			}
+		})
+	}
+}
+
+func TestKeySetRotation(t *testing.T) {
+	oldPrivate, oldPublic := writeRSAKey(t)
+	newPrivate, _ := writeECKey(t)
+
+	oldKeys, err := newKeySet(&config.JWT{SigningMethod: "RS256", KeyID: "old", PrivateKeyFile: oldPrivate})
+	assert.NoError(t, err)
+
+	newKeys, err := newKeySet(&config.JWT{
+		SigningMethod:  "ES256",
+		KeyID:          "new",
+		PrivateKeyFile: newPrivate,
+		PublicKeyFiles: map[string]string{"old": oldPublic},
+	})
+	assert.NoError(t, err)
+
+	signed, err := oldKeys.sign(jwt.StandardClaims{Subject: "dua@lipa.com"})
+	assert.NoError(t, err)
+
+	token, err := jwt.Parse(signed, newKeys.keyFunc)
+	assert.NoError(t, err)
+	assert.True(t, token.Valid)
+
+	set := newKeys.publicJWKS()
+	assert.Len(t, set.Keys, 2)
+	assert.Equal(t, "new", set.Keys[0].Kid)
+	assert.Equal(t, "EC", set.Keys[0].Kty)
+	assert.Equal(t, "P-256", set.Keys[0].Crv)
+	assert.Equal(t, "old", set.Keys[1].Kid)
+	assert.Equal(t, "RSA", set.Keys[1].Kty)
+	assert.Equal(t, "AQAB", set.Keys[1].E)
+}
+
+func TestKeySetRejectsUnknownKeys(t *testing.T) {
+	rsaPrivate, _ := writeRSAKey(t)
+
+	hmacKeys, err := newKeySet(&config.JWT{SecretKey: "secret"})
+	assert.NoError(t, err)
+	assert.Empty(t, hmacKeys.publicJWKS().Keys)
+
+	rsaKeys, err := newKeySet(&config.JWT{SigningMethod: "RS256", KeyID: "rsa1", PrivateKeyFile: rsaPrivate})
+	assert.NoError(t, err)
+
+	signed, err := hmacKeys.sign(jwt.StandardClaims{Subject: "dua@lipa.com"})
+	assert.NoError(t, err)
+
+	_, err = jwt.Parse(signed, rsaKeys.keyFunc)
+	assert.Error(t, err)
+}
+
+func TestKeySetSigningMethodSwitch(t *testing.T) {
+	rsaPrivate, rsaPublic := writeRSAKey(t)
+
+	testCases := map[string]struct {
+		old *config.JWT
+		new *config.JWT
+		err string
+	}{
+		"HS256 tokens should be accepted after switching to RS256": {
+			old: &config.JWT{SigningMethod: "HS256", SecretKey: "secret"},
+			new: &config.JWT{SigningMethod: "RS256", SecretKey: "secret", KeyID: "rsa1", PrivateKeyFile: rsaPrivate},
+		},
+		"HS256 tokens with legacy kid should be accepted after switching to RS256": {
+			old: &config.JWT{SigningMethod: "HS256", SecretKey: "secret", KeyID: "hmac1"},
+			new: &config.JWT{SigningMethod: "RS256", SecretKey: "secret", KeyID: "rsa1", LegacyKeyID: "hmac1", PrivateKeyFile: rsaPrivate},
+		},
+		"HS256 tokens should be rejected once the secret is removed": {
+			old: &config.JWT{SigningMethod: "HS256", SecretKey: "secret"},
+			new: &config.JWT{SigningMethod: "RS256", KeyID: "rsa1", PrivateKeyFile: rsaPrivate},
+			err: `unknown key id ""`,
+		},
+		"RS256 tokens should be accepted after switching to HS256": {
+			old: &config.JWT{SigningMethod: "RS256", KeyID: "rsa1", PrivateKeyFile: rsaPrivate},
+			new: &config.JWT{SigningMethod: "HS256", SecretKey: "secret", PublicKeyFiles: map[string]string{"rsa1": rsaPublic}},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			oldKeys, err := newKeySet(tc.old)
+			assert.NoError(t, err)
+
+			newKeys, err := newKeySet(tc.new)
+			assert.NoError(t, err)
+
+			signed, err := oldKeys.sign(jwt.StandardClaims{Subject: "dua@lipa.com"})
+			assert.NoError(t, err)
+
+			token, err := jwt.Parse(signed, newKeys.keyFunc)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.True(t, token.Valid)
+
+			// hmac secrets are never published
+			assert.Len(t, newKeys.publicJWKS().Keys, 1)
+		})
+	}
+}
+
+func TestKeySetLegacyKeyIDConflict(t *testing.T) {
+	rsaPrivate, rsaPublic := writeRSAKey(t)
+
+	_, err := newKeySet(&config.JWT{
+		SigningMethod:  "RS256",
+		SecretKey:      "secret",
+		KeyID:          "rsa2",
+		LegacyKeyID:    "rsa1",
+		PrivateKeyFile: rsaPrivate,
+		PublicKeyFiles: map[string]string{"rsa1": rsaPublic},
+	})
+	assert.EqualError(t, err, `jwt legacy key id "rsa1" is already used`)
+}
@@ -0,0 +1,131 @@
+package users
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/alamrios/crabi-solution/internal/app/user"
+)
+
+type listUsersResponse struct {
+	Users []getUserResponse `json:"users" validate:"required"`
+	// NextCursor position of the next page, empty on the last page
+	NextCursor string `json:"next_cursor,omitempty" example:"eyJzIjoiLWNyZWF0ZWRfYXQiLCJlIjoiZHVhQGxpcGEuY29tIn0"`
+	// Total users matching the filters, only when requested with total=true
+	Total *int64 `json:"total,omitempty" example:"42"`
+}
+
+// listUsers godoc
+// @Description Lists users page by page, newest first unless another sort is requested.
+// @Param email query string false "Exact email, looks a user up by email"
+// @Param status query string false "Account status"
+// @Param created_from query string false "RFC 3339 date, users created at or after it"
+// @Param created_to query string false "RFC 3339 date, users created before it"
+// @Param name_prefix query string false "Case sensitive prefix of first or last name"
+// @Param email_domain query string false "Email domain"
+// @Param sort query string false "created_at, email or last_name, prefixed with - for descending order"
+// @Param limit query int false "Users per page, 20 by default and 100 at most"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param total query bool false "Count every user matching the filters"
+// @Success 200 {object} jsonapi.Response{listUsersResponse}
+func (h *Router) listUsers(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	ctx := r.Context()
+
+	query, err := parseListQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := h.userService.ListUsers(ctx, query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := listUsersResponse{
+		Users:      make([]getUserResponse, 0, len(page.Users)),
+		NextCursor: page.NextCursor,
+		Total:      page.Total,
+	}
+	for i := range page.Users {
+		response.Users = append(response.Users, newGetUserResponse(&page.Users[i]))
+	}
+
+	payload, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(payload)
+}
+
+// parseListQuery reads user listing filters, sort and page from given
+// query string
+func parseListQuery(values url.Values) (user.ListQuery, error) {
+	query := user.ListQuery{
+		Filter: user.Filter{
+			Email:       values.Get("email"),
+			Status:      user.Status(values.Get("status")),
+			NamePrefix:  values.Get("name_prefix"),
+			EmailDomain: values.Get("email_domain"),
+		},
+		Cursor: values.Get("cursor"),
+	}
+
+	var err error
+	query.Filter.CreatedFrom, err = parseQueryTime(values, "created_from")
+	if err != nil {
+		return query, err
+	}
+
+	query.Filter.CreatedTo, err = parseQueryTime(values, "created_to")
+	if err != nil {
+		return query, err
+	}
+
+	query.Sort, err = user.ParseSort(values.Get("sort"))
+	if err != nil {
+		return query, err
+	}
+
+	if value := values.Get("limit"); value != "" {
+		query.Limit, err = strconv.Atoi(value)
+		if err != nil || query.Limit <= 0 {
+			return query, fmt.Errorf("limit should be a positive number")
+		}
+	}
+
+	if value := values.Get("total"); value != "" {
+		query.IncludeTotal, err = strconv.ParseBool(value)
+		if err != nil {
+			return query, fmt.Errorf("total should be true or false")
+		}
+	}
+
+	return query, nil
+}
+
+// parseQueryTime reads RFC 3339 date of given query parameter, nil when
+// it is not set
+func parseQueryTime(values url.Values, key string) (*time.Time, error) {
+	value := values.Get(key)
+	if value == "" {
+		return nil, nil
+	}
+
+	date, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("%s should be an RFC 3339 date", key)
+	}
+
+	return &date, nil
+}
@@ -0,0 +1,72 @@
+package users
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/alamrios/crabi-solution/internal/app/user"
+)
+
+func TestParseListQuery(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := map[string]struct {
+		query    string
+		expected user.ListQuery
+		err      string
+	}{
+		"empty query should use defaults": {
+			expected: user.ListQuery{Sort: user.DefaultSort},
+		},
+		"every parameter should be read": {
+			query: "status=active&created_from=2026-01-01T00:00:00Z&name_prefix=Li&email_domain=lipa.com&sort=-email&limit=50&cursor=abc&total=true",
+			expected: user.ListQuery{
+				Filter: user.Filter{
+					Status:      user.StatusActive,
+					CreatedFrom: &from,
+					NamePrefix:  "Li",
+					EmailDomain: "lipa.com",
+				},
+				Sort:         user.Sort{Field: user.SortEmail, Descending: true},
+				Limit:        50,
+				Cursor:       "abc",
+				IncludeTotal: true,
+			},
+		},
+		"invalid date should return error": {
+			query: "created_to=yesterday",
+			err:   "created_to should be an RFC 3339 date",
+		},
+		"invalid sort should return error": {
+			query: "sort=password",
+			err:   "users can not be sorted by password",
+		},
+		"invalid limit should return error": {
+			query: "limit=0",
+			err:   "limit should be a positive number",
+		},
+		"invalid total should return error": {
+			query: "total=maybe",
+			err:   "total should be true or false",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			values, err := url.ParseQuery(tc.query)
+			assert.NoError(t, err)
+
+			got, err := parseListQuery(values)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
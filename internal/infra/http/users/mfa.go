@@ -0,0 +1,191 @@
+package users
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/alamrios/crabi-solution/internal/app/user"
+)
+
+type mfaChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required" validate:"required" example:"true"`
+	MFAToken    string `json:"mfa_token" validate:"required" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	ExpiresIn   int64  `json:"expires_in" validate:"required" example:"300"`
+}
+
+// writeMFAChallenge responds to the password step of an MFA login
+func (h *Router) writeMFAChallenge(w http.ResponseWriter, user *user.User) {
+	token, err := h.generateMFAChallenge(user)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	payload, err := json.Marshal(mfaChallengeResponse{
+		MFARequired: true,
+		MFAToken:    token,
+		ExpiresIn:   int64(h.mfaChallengeTTL.Seconds()),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(payload)
+}
+
+// errInvalidMFAToken returned for unknown, expired or already used MFA
+// challenge tokens
+var errInvalidMFAToken = errors.New("invalid or expired mfa token")
+
+type loginMFARequest struct {
+	MFAToken string `json:"mfa_token" validate:"required" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	Code     string `json:"code" validate:"required" example:"123456"`
+}
+
+// loginMFA godoc
+// @Description Second step of MFA login, exchanges the MFA challenge token and a TOTP or recovery code for an access token.
+// @Param mfa_token query string false "MFA challenge token returned by login"
+// @Param code query string false "TOTP code or recovery code"
+// @Success 200 {object} jsonapi.Response{loginResponse}
+func (h *Router) loginMFA(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	ctx := r.Context()
+
+	var request loginMFARequest
+	reqBody, _ := ioutil.ReadAll(r.Body)
+	json.Unmarshal(reqBody, &request)
+
+	claims, err := h.parseMFAChallenge(request.MFAToken)
+	if err != nil {
+		http.Error(w, errInvalidMFAToken.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	used, err := h.authService.IsTokenRevoked(ctx, claims.Id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if used {
+		http.Error(w, errInvalidMFAToken.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	user, err := h.mfaService.Verify(ctx, claims.Email, request.Code)
+	if err != nil {
+		writeLoginError(w, err)
+		return
+	}
+
+	if user.ID != claims.Subject {
+		http.Error(w, errInvalidMFAToken.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	// challenges are single use, of concurrent exchanges only one succeeds
+	consumed, err := h.authService.ConsumeToken(ctx, claims.Id, claims.Email, h.revokedUntil(claims))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !consumed {
+		http.Error(w, errInvalidMFAToken.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	h.writeLoginResponse(w, r, user)
+}
+
+type enrollMFAResponse struct {
+	Secret     string `json:"secret" validate:"required" example:"JBSWY3DPEHPK3PXP"`
+	OTPAuthURI string `json:"otpauth_uri" validate:"required" example:"otpauth://totp/crabi-solution:joaquin@guzman.com?secret=JBSWY3DPEHPK3PXP"`
+}
+
+// enrollMFA godoc
+// @Description Starts TOTP enrollment of the authenticated user, returns the secret as an otpauth URI.
+// @Success 200 {object} jsonapi.Response{enrollMFAResponse}
+func (h *Router) enrollMFA(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	ctx := r.Context()
+
+	claims, _ := ClaimsFromContext(ctx)
+
+	enrollment, err := h.mfaService.Enroll(ctx, claims.Email)
+	if errors.Is(err, user.ErrMFAAlreadyEnabled) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	payload, err := json.Marshal(enrollMFAResponse{
+		Secret:     enrollment.Secret,
+		OTPAuthURI: enrollment.URI,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(payload)
+}
+
+type confirmMFARequest struct {
+	Code string `json:"code" validate:"required" example:"123456"`
+}
+
+type confirmMFAResponse struct {
+	RecoveryCodes []string `json:"recovery_codes" validate:"required" example:"k3s0z-n9rt6"`
+}
+
+// confirmMFA godoc
+// @Description Enables MFA of the authenticated user with a code from the enrolled secret, returns one-time recovery codes.
+// @Param code query string false "TOTP code"
+// @Success 200 {object} jsonapi.Response{confirmMFAResponse}
+func (h *Router) confirmMFA(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	ctx := r.Context()
+
+	claims, _ := ClaimsFromContext(ctx)
+
+	var request confirmMFARequest
+	reqBody, _ := ioutil.ReadAll(r.Body)
+	json.Unmarshal(reqBody, &request)
+
+	codes, err := h.mfaService.Confirm(ctx, claims.Email, request.Code)
+	switch {
+	case errors.Is(err, user.ErrMFAAlreadyEnabled):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, user.ErrInvalidMFACode):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	payload, err := json.Marshal(confirmMFAResponse{
+		RecoveryCodes: codes,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(payload)
+}
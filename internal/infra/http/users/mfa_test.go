@@ -0,0 +1,116 @@
+package users
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/alamrios/crabi-solution/internal/app/auth"
+	"github.com/alamrios/crabi-solution/internal/app/user"
+	tmock "github.com/alamrios/crabi-solution/test/mock"
+)
+
+func TestLoginMFA(t *testing.T) {
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	code, err := user.TOTPCode(secret, time.Now())
+	assert.NoError(t, err)
+
+	testCases := map[string]struct {
+		subject string
+		// used whether the challenge was exchanged before the request
+		used bool
+		// verified whether the code reaches the MFA service
+		verified bool
+		// consumed whether the challenge is consumed by this request
+		consumed *bool
+	}{
+		"used challenge should be rejected": {
+			subject: "user1",
+			used:    true,
+		},
+		"challenge exchanged concurrently should be rejected": {
+			subject:  "user1",
+			verified: true,
+			consumed: new(bool),
+		},
+		"challenge of another user should be rejected": {
+			subject:  "user2",
+			verified: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			router := newTestRouter(t)
+
+			challenge, err := router.generateMFAChallenge(&user.User{ID: tc.subject, Email: "dua@lipa.com"})
+			assert.NoError(t, err)
+			claims, err := router.parseMFAChallenge(challenge)
+			assert.NoError(t, err)
+
+			revokedCalls := []tmock.Call{
+				{
+					FunctionName: "IsTokenRevoked",
+					Params:       []interface{}{claims.Id},
+					Returns:      []interface{}{tc.used, nil},
+				},
+			}
+			if tc.consumed != nil {
+				revokedCalls = append(revokedCalls, tmock.Call{
+					FunctionName: "InsertRevokedToken",
+					Params: []interface{}{
+						mock.MatchedBy(func(token auth.RevokedToken) bool {
+							return token.JTI == claims.Id && token.ExpiresAt.After(time.Unix(claims.ExpiresAt, 0))
+						}),
+					},
+					Returns: []interface{}{*tc.consumed, nil},
+				})
+			}
+			revokedTokenRepo := tmock.NewRevokedTokenRepository().AddCall(t, revokedCalls)
+			authService, err := auth.NewService(tmock.NewRefreshTokenRepository(), revokedTokenRepo, tmock.NewSessionRepository(), time.Hour)
+			assert.NoError(t, err)
+
+			var userCalls []tmock.Call
+			if tc.verified {
+				userCalls = []tmock.Call{
+					{
+						FunctionName: "GetUserByEmail",
+						Params:       []interface{}{"dua@lipa.com"},
+						Returns: []interface{}{&user.User{
+							ID:    "user1",
+							Email: "dua@lipa.com",
+							MFA:   user.MFA{Enabled: true, Secret: secret},
+						}, nil},
+					},
+					{
+						FunctionName: "UseMFAStep",
+						Params:       []interface{}{"dua@lipa.com", mock.AnythingOfType("int64")},
+						Returns:      []interface{}{true, nil},
+					},
+				}
+			}
+			userRepo := tmock.NewUserRepository().AddCall(t, userCalls)
+			mfaService, err := user.NewMFAService(userRepo, user.LockoutPolicy{}, "crabi-solution")
+			assert.NoError(t, err)
+
+			router.authService = authService
+			router.mfaService = mfaService
+			router.leeway = time.Minute
+
+			body, _ := json.Marshal(loginMFARequest{MFAToken: challenge, Code: code})
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/login/mfa", bytes.NewReader(body))
+			w := httptest.NewRecorder()
+
+			router.loginMFA(w, r)
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+			revokedTokenRepo.AssertExpectations(t)
+			userRepo.AssertExpectations(t)
+		})
+	}
+}
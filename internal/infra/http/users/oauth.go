@@ -0,0 +1,199 @@
+package users
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/alamrios/crabi-solution/internal/app/auth"
+	"github.com/alamrios/crabi-solution/internal/app/user"
+)
+
+// grantTypeClientCredentials only OAuth2 grant supported by the token endpoint
+const grantTypeClientCredentials = "client_credentials"
+
+type oauthTokenResponse struct {
+	AccessToken string `json:"access_token" validate:"required" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	TokenType   string `json:"token_type" validate:"required" example:"Bearer"`
+	ExpiresIn   int64  `json:"expires_in" validate:"required" example:"3600"`
+	Scope       string `json:"scope" validate:"required" example:"users:create users:read"`
+}
+
+type oauthErrorResponse struct {
+	Error            string `json:"error" validate:"required" example:"invalid_client"`
+	ErrorDescription string `json:"error_description,omitempty" example:"invalid client"`
+}
+
+// oauthToken godoc
+// @Description OAuth2 token endpoint, issues access tokens with the client_credentials grant.
+// @Description Client credentials are read from HTTP Basic auth or the client_id and client_secret form params.
+// @Param grant_type formData string true "Must be client_credentials"
+// @Param scope formData string false "Space separated scopes, defaults to every scope of the client"
+// @Success 200 {object} jsonapi.Response{oauthTokenResponse}
+func (h *Router) oauthToken(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	ctx := r.Context()
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+
+	err := r.ParseForm()
+	if err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	grantType := r.PostForm.Get("grant_type")
+	if grantType != grantTypeClientCredentials {
+		writeOAuthError(w, http.StatusBadRequest, "unsupported_grant_type", fmt.Sprintf("grant type %q is not supported", grantType))
+		return
+	}
+
+	clientID, clientSecret, basic := r.BasicAuth()
+	if !basic {
+		clientID = r.PostForm.Get("client_id")
+		clientSecret = r.PostForm.Get("client_secret")
+	}
+
+	client, scopes, err := h.clientService.AuthenticateClient(ctx, clientID, clientSecret, strings.Fields(r.PostForm.Get("scope")))
+	switch {
+	case errors.Is(err, auth.ErrInvalidClient):
+		if basic {
+			w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_client", err.Error())
+		return
+	case errors.Is(err, auth.ErrInvalidScope):
+		writeOAuthError(w, http.StatusBadRequest, "invalid_scope", err.Error())
+		return
+	case err != nil:
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+
+	scope := strings.Join(scopes, " ")
+
+	token, err := h.generateClientToken(client, scope)
+	if err != nil {
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+
+	payload, err := json.Marshal(oauthTokenResponse{
+		AccessToken: token,
+		TokenType:   tokenType,
+		ExpiresIn:   int64(h.accessTokenTTL.Seconds()),
+		Scope:       scope,
+	})
+	if err != nil {
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(payload)
+}
+
+// writeOAuthError writes an RFC 6749 error response
+func writeOAuthError(w http.ResponseWriter, status int, code, description string) {
+	payload, _ := json.Marshal(oauthErrorResponse{
+		Error:            code,
+		ErrorDescription: description,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(payload)
+}
+
+type createClientRequest struct {
+	Name   string   `json:"name" validate:"required" example:"partner-integration"`
+	Scopes []string `json:"scopes" validate:"required" example:"users:create"`
+}
+
+type clientResponse struct {
+	ClientID     string     `json:"client_id" validate:"required" example:"3f9a1c2b7d4e8f60"`
+	ClientSecret string     `json:"client_secret,omitempty" example:"k3S0zN9rT6xQ..."`
+	Name         string     `json:"name" validate:"required" example:"partner-integration"`
+	Scopes       []string   `json:"scopes" validate:"required" example:"users:create"`
+	CreatedBy    string     `json:"created_by" validate:"required" example:"joaquin@guzman.com"`
+	CreatedAt    time.Time  `json:"created_at" validate:"required" example:"2026-01-01T00:00:00Z"`
+	DisabledAt   *time.Time `json:"disabled_at,omitempty" example:"2026-01-02T00:00:00Z"`
+}
+
+// createClient godoc
+// @Description Registers an OAuth2 client for the client_credentials grant, the secret is only returned in this response.
+// @Param name query string false "Client name"
+// @Param scopes query []string false "Scopes the client may request"
+// @Success 201 {object} jsonapi.Response{clientResponse}
+func (h *Router) createClient(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	ctx := r.Context()
+
+	claims, _ := ClaimsFromContext(ctx)
+
+	var request createClientRequest
+	reqBody, _ := ioutil.ReadAll(r.Body)
+	json.Unmarshal(reqBody, &request)
+
+	for _, scope := range request.Scopes {
+		if !user.Permission(scope).Valid() {
+			http.Error(w, fmt.Sprintf("unknown scope %q", scope), http.StatusBadRequest)
+			return
+		}
+	}
+
+	client, secret, err := h.clientService.CreateClient(ctx, auth.Client{
+		Name:      request.Name,
+		Scopes:    request.Scopes,
+		CreatedBy: claims.Email,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	payload, err := json.Marshal(clientResponse{
+		ClientID:     client.ID,
+		ClientSecret: secret,
+		Name:         client.Name,
+		Scopes:       client.Scopes,
+		CreatedBy:    client.CreatedBy,
+		CreatedAt:    client.CreatedAt,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(payload)
+}
+
+// disableClient godoc
+// @Description Disables the OAuth2 client with given id, its tokens stop being accepted.
+// @Param id query string false "Client id"
+// @Success 204
+func (h *Router) disableClient(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	ctx := r.Context()
+
+	id := mux.Vars(r)["id"]
+
+	err := h.clientService.DisableClient(ctx, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,122 @@
+package users
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/alamrios/crabi-solution/internal/app/auth"
+	tmock "github.com/alamrios/crabi-solution/test/mock"
+)
+
+func TestOAuthToken(t *testing.T) {
+	id := "3f9a1c2b7d4e8f60"
+	secret := "k3S0zN9rT6xQ"
+	sum := sha256.Sum256([]byte(secret))
+
+	client := &auth.Client{
+		ID:         id,
+		SecretHash: hex.EncodeToString(sum[:]),
+		Scopes:     []string{"users:create", "users:read"},
+	}
+
+	testCases := map[string]struct {
+		form          url.Values
+		basicAuth     bool
+		secret        string
+		expected      int
+		expectedError string
+		expectedScope string
+	}{
+		"basic auth should issue token": {
+			form:          url.Values{"grant_type": {"client_credentials"}},
+			basicAuth:     true,
+			secret:        secret,
+			expected:      http.StatusOK,
+			expectedScope: "users:create users:read",
+		},
+		"form credentials with scope should issue token": {
+			form: url.Values{
+				"grant_type":    {"client_credentials"},
+				"client_id":     {id},
+				"client_secret": {secret},
+				"scope":         {"users:read"},
+			},
+			expected:      http.StatusOK,
+			expectedScope: "users:read",
+		},
+		"wrong secret should return invalid_client": {
+			form:          url.Values{"grant_type": {"client_credentials"}},
+			basicAuth:     true,
+			secret:        "other",
+			expected:      http.StatusUnauthorized,
+			expectedError: "invalid_client",
+		},
+		"scope not allowed should return invalid_scope": {
+			form:          url.Values{"grant_type": {"client_credentials"}, "scope": {"users:unlock"}},
+			basicAuth:     true,
+			secret:        secret,
+			expected:      http.StatusBadRequest,
+			expectedError: "invalid_scope",
+		},
+		"other grant should return unsupported_grant_type": {
+			form:          url.Values{"grant_type": {"password"}},
+			basicAuth:     true,
+			secret:        secret,
+			expected:      http.StatusBadRequest,
+			expectedError: "unsupported_grant_type",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			repo := tmock.NewClientRepository().AddCall(t, []tmock.Call{
+				{
+					FunctionName: "GetClient",
+					Params:       []interface{}{id},
+					Returns:      []interface{}{client, nil},
+				},
+			})
+			clientService, err := auth.NewClientService(repo)
+			assert.NoError(t, err)
+
+			router := newTestRouter(t)
+			router.clientService = clientService
+
+			r := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(tc.form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tc.basicAuth {
+				r.SetBasicAuth(id, tc.secret)
+			}
+			w := httptest.NewRecorder()
+
+			router.oauthToken(w, r)
+			assert.Equal(t, tc.expected, w.Code)
+			assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+
+			if tc.expectedError != "" {
+				var response oauthErrorResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, tc.expectedError, response.Error)
+				return
+			}
+
+			var response oauthTokenResponse
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tc.expectedScope, response.Scope)
+
+			claims, err := router.parseJWT(response.AccessToken)
+			assert.NoError(t, err)
+			assert.Equal(t, id, claims.ClientID)
+			assert.Equal(t, id, claims.Subject)
+			assert.Equal(t, tc.expectedScope, claims.Scope)
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package users
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/alamrios/crabi-solution/internal/app/user"
+)
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" validate:"required" example:"joaquin@guzman.com"`
+}
+
+// forgotPassword godoc
+// @Description Sends a password reset link to the given email if it belongs to a user.
+// @Param email query string false "User's email"
+// @Success 202
+func (h *Router) forgotPassword(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	ctx := r.Context()
+
+	var request forgotPasswordRequest
+	reqBody, _ := ioutil.ReadAll(r.Body)
+	json.Unmarshal(reqBody, &request)
+
+	err := h.passwordResetService.RequestReset(ctx, request.Email)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// same response whether the email is registered or not
+	w.WriteHeader(http.StatusAccepted)
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token" validate:"required" example:"k3S0zN9rT6xQ..."`
+	Password string `json:"password" validate:"required" example:"tangerine-comet-7"`
+}
+
+// resetPassword godoc
+// @Description Sets a new password using a reset token and revokes existing sessions.
+// @Param token query string false "Password reset token"
+// @Param password query string false "User's new password"
+// @Success 204
+func (h *Router) resetPassword(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	ctx := r.Context()
+
+	var request resetPasswordRequest
+	reqBody, _ := ioutil.ReadAll(r.Body)
+	json.Unmarshal(reqBody, &request)
+
+	err := h.passwordResetService.ResetPassword(ctx, request.Token, request.Password)
+	if errors.Is(err, user.ErrWeakPassword) {
+		writePasswordPolicyError(w, err)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required" example:"nectarine-orbit-42"`
+	NewPassword     string `json:"new_password" validate:"required" example:"tangerine-comet-7"`
+}
+
+// changePassword godoc
+// @Description Changes a user's password after checking the current one and revokes every session.
+// @Param id query string false "User's ID"
+// @Param current_password query string false "User's current password"
+// @Param new_password query string false "User's new password"
+// @Success 204
+func (h *Router) changePassword(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	ctx := r.Context()
+
+	target, ok := h.userFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var request changePasswordRequest
+	reqBody, _ := ioutil.ReadAll(r.Body)
+	json.Unmarshal(reqBody, &request)
+
+	err := h.userService.ChangePassword(ctx, target.ID, request.CurrentPassword, request.NewPassword)
+	switch {
+	case errors.Is(err, user.ErrWeakPassword):
+		writePasswordPolicyError(w, err)
+		return
+	case errors.Is(err, user.ErrInvalidCredentials):
+		// 401 would tell clients to log in again, the token itself is valid
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	case err != nil:
+		writeLoginError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type passwordViolationResponse struct {
+	Rule    string `json:"rule" validate:"required" example:"min_length"`
+	Message string `json:"message" validate:"required" example:"password should have at least 8 characters"`
+}
+
+type passwordPolicyErrorResponse struct {
+	Error      string                      `json:"error" validate:"required" example:"password does not satisfy password policy"`
+	Violations []passwordViolationResponse `json:"violations" validate:"required"`
+}
+
+// writePasswordPolicyError responds 422 with every password policy rule
+// the password failed
+func writePasswordPolicyError(w http.ResponseWriter, err error) {
+	response := passwordPolicyErrorResponse{
+		Error:      user.ErrWeakPassword.Error(),
+		Violations: []passwordViolationResponse{},
+	}
+
+	var policyErr *user.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		for _, violation := range policyErr.Violations {
+			response.Violations = append(response.Violations, passwordViolationResponse{
+				Rule:    violation.Rule,
+				Message: violation.Message,
+			})
+		}
+	}
+
+	payload, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	w.Write(payload)
+}
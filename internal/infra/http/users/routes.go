@@ -3,15 +3,17 @@ package users
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
-	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
-	"github.com/golang-jwt/jwt"
-
 	"github.com/alamrios/crabi-solution/config"
+	"github.com/alamrios/crabi-solution/internal/app/auth"
+	"github.com/alamrios/crabi-solution/internal/app/pld"
 	"github.com/alamrios/crabi-solution/internal/app/user"
 	"github.com/gorilla/mux"
 )
@@ -19,101 +21,221 @@ import (
 type userService interface {
 	CreateUser(ctx context.Context, user user.User) (*user.User, error)
 	Login(ctx context.Context, email, password string) (*user.User, error)
-	GetUser(ctx context.Context, email string) (*user.User, error)
+	GetUser(ctx context.Context, id string) (*user.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*user.User, error)
+	UpdateUser(ctx context.Context, id string, update user.ProfileUpdate) (*user.User, error)
+	ChangePassword(ctx context.Context, id, currentPassword, newPassword string) error
+	DeleteUser(ctx context.Context, id string) error
+	RestoreUser(ctx context.Context, id string) (*user.User, error)
+	ListUsers(ctx context.Context, query user.ListQuery) (*user.UserPage, error)
+	RevokeSessions(ctx context.Context, id string) error
+	UnlockUser(ctx context.Context, id string) error
+}
+
+type authService interface {
+	CreateSession(ctx context.Context, email, userAgent, ip string) (*auth.Session, string, error)
+	RefreshSession(ctx context.Context, token, userAgent, ip string) (*auth.Session, string, error)
+	GetSession(ctx context.Context, id string) (*auth.Session, error)
+	ListSessions(ctx context.Context, email string) ([]auth.Session, error)
+	TouchSession(ctx context.Context, session *auth.Session)
+	DeleteSession(ctx context.Context, email, id string) error
+	DeleteSessions(ctx context.Context, email string) error
+	RevokeRefreshToken(ctx context.Context, token string) error
+	RevokeToken(ctx context.Context, jti, email string, expiresAt time.Time) error
+	ConsumeToken(ctx context.Context, jti, email string, expiresAt time.Time) (bool, error)
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+type passwordResetService interface {
+	RequestReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, password string) error
+}
+
+type emailVerificationService interface {
+	VerifyEmail(ctx context.Context, token string) (string, error)
+}
+
+type apiKeyService interface {
+	CreateAPIKey(ctx context.Context, apiKey auth.APIKey) (*auth.APIKey, string, error)
+	ListAPIKeys(ctx context.Context) ([]auth.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id string) error
+	AuthenticateAPIKey(ctx context.Context, key string) (*auth.APIKey, error)
+}
+
+type clientService interface {
+	CreateClient(ctx context.Context, client auth.Client) (*auth.Client, string, error)
+	DisableClient(ctx context.Context, id string) error
+	GetClient(ctx context.Context, id string) (*auth.Client, error)
+	AuthenticateClient(ctx context.Context, id, secret string, scopes []string) (*auth.Client, []string, error)
+}
+
+type mfaService interface {
+	Enroll(ctx context.Context, email string) (*user.MFAEnrollment, error)
+	Confirm(ctx context.Context, email, code string) ([]string, error)
+	Verify(ctx context.Context, email, code string) (*user.User, error)
+}
+
+type screeningService interface {
+	ListScreenings(ctx context.Context, userID string) ([]pld.Screening, error)
+	ListScreeningsByEmail(ctx context.Context, email string) ([]pld.Screening, error)
 }
 
 // Router infraestructure
 type Router struct {
-	userService userService
-	secretKey   []byte
+	userService          userService
+	authService          authService
+	passwordResetService passwordResetService
+	verificationService  emailVerificationService
+	mfaService           mfaService
+	apiKeyService        apiKeyService
+	clientService        clientService
+	screeningService     screeningService
+	keys                 *keySet
+	issuer               string
+	audience             string
+	accessTokenTTL       time.Duration
+	leeway               time.Duration
+	mfaChallengeTTL      time.Duration
+	legacyTokenHeader    bool
 }
 
 // New Router constructor
-func New(userService userService, config *config.JWT) (*Router, error) {
+func New(
+	userService userService,
+	authService authService,
+	passwordResetService passwordResetService,
+	verificationService emailVerificationService,
+	mfaService mfaService,
+	apiKeyService apiKeyService,
+	clientService clientService,
+	screeningService screeningService,
+	config *config.JWT,
+) (*Router, error) {
 	if userService == nil {
 		return nil, fmt.Errorf("user service is nil")
 	}
 
+	if authService == nil {
+		return nil, fmt.Errorf("auth service is nil")
+	}
+
+	if passwordResetService == nil {
+		return nil, fmt.Errorf("password reset service is nil")
+	}
+
+	if verificationService == nil {
+		return nil, fmt.Errorf("email verification service is nil")
+	}
+
+	if mfaService == nil {
+		return nil, fmt.Errorf("mfa service is nil")
+	}
+
+	if apiKeyService == nil {
+		return nil, fmt.Errorf("api key service is nil")
+	}
+
+	if clientService == nil {
+		return nil, fmt.Errorf("oauth client service is nil")
+	}
+
+	if screeningService == nil {
+		return nil, fmt.Errorf("screening service is nil")
+	}
+
 	if config == nil {
 		return nil, fmt.Errorf("jwt config is nil")
 	}
 
+	if config.AccessTokenTTL <= 0 {
+		return nil, fmt.Errorf("jwt access token ttl should be greater than zero")
+	}
+
+	if config.MFAChallengeTTL <= 0 {
+		return nil, fmt.Errorf("jwt mfa challenge ttl should be greater than zero")
+	}
+
+	keys, err := newKeySet(config)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Router{
-		userService: userService,
-		secretKey:   []byte(config.SecretKey),
+		userService:          userService,
+		authService:          authService,
+		passwordResetService: passwordResetService,
+		verificationService:  verificationService,
+		mfaService:           mfaService,
+		apiKeyService:        apiKeyService,
+		clientService:        clientService,
+		screeningService:     screeningService,
+		keys:                 keys,
+		issuer:               config.Issuer,
+		audience:             config.Audience,
+		accessTokenTTL:       config.AccessTokenTTL,
+		leeway:               config.Leeway,
+		mfaChallengeTTL:      config.MFAChallengeTTL,
+		legacyTokenHeader:    config.LegacyTokenHeader,
 	}, nil
 }
 
 // AppendRoutes adds all func handlers
 func (h *Router) AppendRoutes(rb *mux.Router) {
-	rb.HandleFunc("/api/v1/users/", h.verifyJWT(h.createUser)).Methods("POST")
-	rb.HandleFunc("/api/v1/users/{email}", h.verifyJWT(h.getUser)).Methods("GET")
+	rb.HandleFunc("/api/v1/users", h.verifyCredentials(h.authorize(user.PermissionUsersRead, h.listUsers))).Methods("GET")
+	rb.HandleFunc("/api/v1/users/", h.verifyCredentials(h.authorize(user.PermissionUsersCreate, h.createUser))).Methods("POST")
+	rb.HandleFunc("/api/v1/users/verify", h.verifyEmail).Methods("GET")
+	rb.HandleFunc("/api/v1/users/{id}", h.verifyCredentials(h.authorizeOrSelf(user.PermissionUsersRead, h.getUser))).Methods("GET")
+	rb.HandleFunc("/api/v1/users/{id}", h.verifyCredentials(h.authorizeOrSelf(user.PermissionUsersUpdate, h.updateUser))).Methods("PATCH")
+	rb.HandleFunc("/api/v1/users/{id}", h.verifyCredentials(h.authorizeOrSelf(user.PermissionUsersDelete, h.deleteUser))).Methods("DELETE")
+	rb.HandleFunc("/api/v1/users/{id}/restore", h.verifyCredentials(h.authorize(user.PermissionUsersRestore, h.restoreUser))).Methods("POST")
+	rb.HandleFunc("/api/v1/users/{id}/password", h.verifyCredentials(h.authorizeOrSelf(user.PermissionUsersUpdate, h.changePassword))).Methods("PUT")
+	rb.HandleFunc("/api/v1/users/{id}/sessions", h.verifyCredentials(h.authorizeOrSelf(user.PermissionSessionsRead, h.listSessions))).Methods("GET")
+	rb.HandleFunc("/api/v1/users/{id}/sessions", h.verifyCredentials(h.authorizeOrSelf(user.PermissionSessionsRevoke, h.revokeSessions))).Methods("DELETE")
+	rb.HandleFunc("/api/v1/users/{id}/sessions/{sessionId}", h.verifyCredentials(h.authorizeOrSelf(user.PermissionSessionsRevoke, h.deleteSession))).Methods("DELETE")
+	rb.HandleFunc("/api/v1/users/{id}/screenings", h.verifyCredentials(h.authorize(user.PermissionScreeningsRead, h.listScreenings))).Methods("GET")
+	rb.HandleFunc("/api/v1/users/{id}/unlock", h.verifyCredentials(h.authorize(user.PermissionUsersUnlock, h.unlockUser))).Methods("POST")
+	rb.HandleFunc("/api/v1/screenings", h.verifyCredentials(h.authorize(user.PermissionScreeningsRead, h.listScreeningsByEmail))).Methods("GET")
+	rb.HandleFunc("/api/v1/apikeys", h.verifyJWT(h.authorize(user.PermissionAPIKeysManage, h.createAPIKey))).Methods("POST")
+	rb.HandleFunc("/api/v1/apikeys", h.verifyJWT(h.authorize(user.PermissionAPIKeysManage, h.listAPIKeys))).Methods("GET")
+	rb.HandleFunc("/api/v1/apikeys/{id}", h.verifyJWT(h.authorize(user.PermissionAPIKeysManage, h.revokeAPIKey))).Methods("DELETE")
 	rb.HandleFunc("/api/v1/login/", h.login).Methods("POST")
+	rb.HandleFunc("/api/v1/login/mfa", h.loginMFA).Methods("POST")
+	rb.HandleFunc("/api/v1/mfa/enroll", h.verifyJWT(h.enrollMFA)).Methods("POST")
+	rb.HandleFunc("/api/v1/mfa/confirm", h.verifyJWT(h.confirmMFA)).Methods("POST")
+	rb.HandleFunc("/api/v1/logout", h.verifyJWT(h.logout)).Methods("POST")
+	rb.HandleFunc("/api/v1/token/refresh", h.refreshToken).Methods("POST")
+	rb.HandleFunc("/api/v1/password/forgot", h.forgotPassword).Methods("POST")
+	rb.HandleFunc("/api/v1/password/reset", h.resetPassword).Methods("POST")
+	rb.HandleFunc("/api/v1/oauth/clients", h.verifyJWT(h.authorize(user.PermissionClientsManage, h.createClient))).Methods("POST")
+	rb.HandleFunc("/api/v1/oauth/clients/{id}", h.verifyJWT(h.authorize(user.PermissionClientsManage, h.disableClient))).Methods("DELETE")
+	rb.HandleFunc("/oauth/token", h.oauthToken).Methods("POST")
+	rb.HandleFunc("/.well-known/jwks.json", h.getJWKS).Methods("GET")
 }
 
 func enableCors(w *http.ResponseWriter) {
 	(*w).Header().Set("Access-Control-Allow-Origin", "*")
 	(*w).Header().Set("Access-Control-Allow-Headers", "*")
-	(*w).Header().Set("Access-Control-Allow-Methods", "GET, DELETE, PUT, POST, OPTIONS")
-}
-
-func (h *Router) verifyJWT(handler func(w http.ResponseWriter, r *http.Request)) http.HandlerFunc {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Header["Token"] != nil {
-			token, err := jwt.Parse(r.Header["Token"][0], func(token *jwt.Token) (interface{}, error) {
-				_, ok := token.Method.(*jwt.SigningMethodHMAC)
-				if !ok {
-					return nil, fmt.Errorf("signing method error")
-				}
-				return h.secretKey, nil
-			})
-			if err != nil {
-				log.Println("error while jwt parse: ", err)
-				errMsg := "you're Unauthorized due to error parsing the JWT"
-				http.Error(w, errMsg, http.StatusUnauthorized)
-				return
-			}
-
-			if token.Valid {
-				handler(w, r)
-			} else {
-				errMsg := "you're Unauthorized due to invalid token"
-				http.Error(w, errMsg, http.StatusUnauthorized)
-				return
-			}
-		} else { // response for request.Header["Token"] == nil
-			errMsg := "you're Unauthorized due to No token in the header"
-			http.Error(w, errMsg, http.StatusUnauthorized)
-			return
-		}
-	})
-}
-
-func (h *Router) generateJWT(email string) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"email":  email,
-		"expire": time.Now().Add(time.Hour * time.Duration(1)).Unix(),
-	})
-
-	tokenString, err := token.SignedString(h.secretKey)
-	if err != nil {
-		return "", err
-	}
-
-	return tokenString, nil
+	(*w).Header().Set("Access-Control-Allow-Methods", "GET, DELETE, PUT, PATCH, POST, OPTIONS")
 }
 
 type createUserRequest struct {
 	FirstName string `json:"first_name" validate:"required" example:"Joaquin"`
 	LastName  string `json:"last_name" validate:"required" example:"Guzman"`
 	Email     string `json:"email" validate:"required" example:"joaquin@guzman.com"`
-	Password  string `json:"password" validate:"required" example:"joaquin123"`
+	Password  string `json:"password" validate:"required" example:"nectarine-orbit-42"`
+	Role      string `json:"role" example:"customer"`
 }
 
 type createUserResponse struct {
+	ID        string `json:"id" validate:"required" example:"0b7e3f4c-5d2a-4e8b-9c1f-6a2d8e4b7c30"`
 	FirstName string `json:"first_name" validate:"required" example:"Joaquin"`
 	LastName  string `json:"last_name" validate:"required" example:"Guzman"`
 	Email     string `json:"email" validate:"required" example:"joaquin@guzman.com"`
+	Status    string `json:"status" validate:"required" example:"pending_verification"`
+	Role      string `json:"role" validate:"required" example:"customer"`
+	Screening string `json:"screening,omitempty" example:"pending"`
+	// Code pld_review_required when the user waits for a manual PLD review
+	Code string `json:"code,omitempty" example:"pld_review_required"`
 }
 
 // createUser godoc
@@ -122,7 +244,9 @@ type createUserResponse struct {
 // @Param last_name query string false "User's last name"
 // @Param email query string false "User's email"
 // @Param password query string false "User's password"
-// @Success 200 {object} jsonapi.Response{user.User}
+// @Param role query string false "User's role, only admins can create roles other than customer"
+// @Success 201 {object} jsonapi.Response{user.User}
+// @Success 202 {object} jsonapi.Response{user.User} "User stored waiting for a manual PLD review"
 func (h *Router) createUser(w http.ResponseWriter, r *http.Request) {
 	enableCors(&w)
 	ctx := r.Context()
@@ -132,19 +256,41 @@ func (h *Router) createUser(w http.ResponseWriter, r *http.Request) {
 	reqBody, _ := ioutil.ReadAll(r.Body)
 	json.Unmarshal(reqBody, &userToCreate)
 
-	user, err := h.userService.CreateUser(ctx, user.User{
+	role := user.Role(userToCreate.Role)
+	if role != "" && role != user.RoleCustomer && !hasPermission(ctx, user.PermissionRolesAssign) {
+		http.Error(w, errForbidden.Error(), http.StatusForbidden)
+		return
+	}
+
+	createdUser, err := h.userService.CreateUser(ctx, user.User{
 		FirstName: userToCreate.FirstName,
 		LastName:  userToCreate.LastName,
 		Email:     userToCreate.Email,
 		Password:  userToCreate.Password,
+		Role:      role,
 	})
-	if err != nil {
+	if errors.Is(err, user.ErrWeakPassword) {
+		writePasswordPolicyError(w, err)
+	} else if errors.Is(err, pld.ErrUnavailable) || errors.Is(err, pld.ErrScreeningNotRecorded) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	} else if writeScreeningError(w, err) {
+		return
+	} else if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 	} else {
 		response := createUserResponse{
-			FirstName: user.FirstName,
-			LastName:  user.LastName,
-			Email:     user.Email,
+			ID:        createdUser.ID,
+			FirstName: createdUser.FirstName,
+			LastName:  createdUser.LastName,
+			Email:     createdUser.Email,
+			Status:    string(createdUser.Status),
+			Role:      string(createdUser.Role),
+			Screening: string(createdUser.Screening),
+		}
+		status := http.StatusCreated
+		if createdUser.Screening == user.ScreeningReview {
+			response.Code = screeningCodeReviewRequired
+			status = http.StatusAccepted
 		}
 
 		payload, err := json.Marshal(response)
@@ -154,24 +300,30 @@ func (h *Router) createUser(w http.ResponseWriter, r *http.Request) {
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusCreated)
+		w.WriteHeader(status)
 		w.Write(payload)
 	}
 }
 
 type loginRequest struct {
 	Email    string `json:"email" validate:"required" example:"joaquin@guzman.com"`
-	Password string `json:"password" validate:"required" example:"joaquin123"`
+	Password string `json:"password" validate:"required" example:"nectarine-orbit-42"`
 }
 
 type loginResponse struct {
-	FirstName string `json:"first_name" validate:"required" example:"Joaquin"`
-	LastName  string `json:"last_name" validate:"required" example:"Guzman"`
-	Email     string `json:"email" validate:"required" example:"joaquin@guzman.com"`
+	ID           string `json:"id" validate:"required" example:"0b7e3f4c-5d2a-4e8b-9c1f-6a2d8e4b7c30"`
+	FirstName    string `json:"first_name" validate:"required" example:"Joaquin"`
+	LastName     string `json:"last_name" validate:"required" example:"Guzman"`
+	Email        string `json:"email" validate:"required" example:"joaquin@guzman.com"`
+	AccessToken  string `json:"access_token" validate:"required" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	TokenType    string `json:"token_type" validate:"required" example:"Bearer"`
+	ExpiresIn    int64  `json:"expires_in" validate:"required" example:"3600"`
+	RefreshToken string `json:"refresh_token" validate:"required" example:"k3S0zN9rT6xQ..."`
 }
 
 // login godoc
-// @Description User login.
+// @Description User login. Users with MFA enabled get an MFA challenge token
+// @Description to exchange at /api/v1/login/mfa instead of the access token.
 // @Param email query string false "User's email"
 // @Param password query string false "User's password"
 // @Success 200 {object} jsonapi.Response{loginResponse}
@@ -186,65 +338,121 @@ func (h *Router) login(w http.ResponseWriter, r *http.Request) {
 
 	user, err := h.userService.Login(ctx, request.Email, request.Password)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeLoginError(w, err)
+	} else if user.MFA.Enabled {
+		h.writeMFAChallenge(w, user)
 	} else {
-		response := loginResponse{
-			FirstName: user.FirstName,
-			LastName:  user.LastName,
-			Email:     user.Email,
-		}
+		h.writeLoginResponse(w, r, user)
+	}
+}
 
-		payload, err := json.Marshal(response)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
-		}
+// writeLoginResponse issues access and refresh tokens for given
+// authenticated user
+func (h *Router) writeLoginResponse(w http.ResponseWriter, r *http.Request, user *user.User) {
+	session, refreshToken, err := h.authService.CreateSession(r.Context(), user.Email, r.UserAgent(), clientIP(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-		token, err := h.generateJWT(user.Email)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
-		}
+	token, err := h.generateJWT(user, session.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := loginResponse{
+		ID:           user.ID,
+		FirstName:    user.FirstName,
+		LastName:     user.LastName,
+		Email:        user.Email,
+		AccessToken:  token,
+		TokenType:    tokenType,
+		ExpiresIn:    int64(h.accessTokenTTL.Seconds()),
+		RefreshToken: refreshToken,
+	}
+
+	payload, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if h.legacyTokenHeader {
 		w.Header().Set("Token", token)
+	}
+	w.Header().Set("Cache-Control", "no-store")
 
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write(payload)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(payload)
+}
+
+// writeLoginError maps login errors to http status codes, locked accounts
+// get 423 with Retry-After, bad credentials or MFA codes 401 and unverified
+// emails or unscreened users 403
+func writeLoginError(w http.ResponseWriter, err error) {
+	var lockedErr *user.LockedError
+
+	switch {
+	case errors.As(err, &lockedErr):
+		retryAfter := int(math.Ceil(time.Until(lockedErr.Until).Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		http.Error(w, err.Error(), http.StatusLocked)
+	case errors.Is(err, user.ErrInvalidCredentials), errors.Is(err, user.ErrInvalidMFACode):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, user.ErrEmailNotVerified), errors.Is(err, user.ErrScreeningPending), errors.Is(err, user.ErrBlacklisted):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
 	}
 }
 
 type getUserResponse struct {
-	FirstName string `json:"first_name" validate:"required" example:"Joaquin"`
-	LastName  string `json:"last_name" validate:"required" example:"Guzman"`
-	Email     string `json:"email" validate:"required" example:"joaquin@guzman.com"`
+	ID        string    `json:"id" validate:"required" example:"0b7e3f4c-5d2a-4e8b-9c1f-6a2d8e4b7c30"`
+	FirstName string    `json:"first_name" validate:"required" example:"Joaquin"`
+	LastName  string    `json:"last_name" validate:"required" example:"Guzman"`
+	Email     string    `json:"email" validate:"required" example:"joaquin@guzman.com"`
+	Status    string    `json:"status" validate:"required" example:"active"`
+	Role      string    `json:"role" validate:"required" example:"customer"`
+	Screening string    `json:"screening,omitempty" example:"pending"`
+	CreatedAt time.Time `json:"created_at" validate:"required" example:"2026-01-01T00:00:00Z"`
+}
+
+// newGetUserResponse returns public representation of given user
+func newGetUserResponse(u *user.User) getUserResponse {
+	return getUserResponse{
+		ID:        u.ID,
+		FirstName: u.FirstName,
+		LastName:  u.LastName,
+		Email:     u.Email,
+		Status:    string(u.Status),
+		Role:      string(u.Role),
+		Screening: string(u.Screening),
+		CreatedAt: u.CreatedAt,
+	}
 }
 
 // getUser godoc
-// @Description retreive user data for given email.
-// @Param email query string false "User's email"
-// @Param password query string false "User's password"
+// @Description retreive user data for given id, users are looked up by email with the
+// @Description email filter of the users listing.
+// @Param id query string false "User's ID"
 // @Success 200 {object} jsonapi.Response{user.User}
 func (h *Router) getUser(w http.ResponseWriter, r *http.Request) {
 	enableCors(&w)
 	ctx := r.Context()
 
 	vars := mux.Vars(r)
-	email, ok := vars["email"]
+	id, ok := vars["id"]
 	if !ok {
-		http.Error(w, "user's email needed", http.StatusBadRequest)
+		http.Error(w, "user's id needed", http.StatusBadRequest)
 	}
 
-	user, err := h.userService.GetUser(ctx, email)
+	user, err := h.userService.GetUser(ctx, id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 	} else {
-		response := getUserResponse{
-			FirstName: user.FirstName,
-			LastName:  user.LastName,
-			Email:     user.Email,
-		}
-
-		payload, err := json.Marshal(response)
+		payload, err := json.Marshal(newGetUserResponse(user))
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
@@ -255,3 +463,189 @@ func (h *Router) getUser(w http.ResponseWriter, r *http.Request) {
 		w.Write(payload)
 	}
 }
+
+// updateUser godoc
+// @Description Partially updates a user's profile with JSON Merge Patch (RFC 7396) semantics,
+// @Description only fields present in the body change. A name change is checked against PLD again.
+// @Param id query string false "User's ID"
+// @Param first_name query string false "User's first name"
+// @Param last_name query string false "User's last name"
+// @Success 200 {object} jsonapi.Response{getUserResponse}
+func (h *Router) updateUser(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	ctx := r.Context()
+
+	id := mux.Vars(r)["id"]
+
+	reqBody, _ := ioutil.ReadAll(r.Body)
+	update, err := parseProfilePatch(reqBody)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.userService.UpdateUser(ctx, id, update)
+	if errors.Is(err, pld.ErrUnavailable) || errors.Is(err, pld.ErrScreeningNotRecorded) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if writeScreeningError(w, err) {
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	payload, err := json.Marshal(newGetUserResponse(updated))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(payload)
+}
+
+// parseProfilePatch reads a JSON merge patch of the profile fields, members
+// set to null remove the field which is refused for required fields
+func parseProfilePatch(body []byte) (user.ProfileUpdate, error) {
+	var update user.ProfileUpdate
+
+	var patch map[string]json.RawMessage
+	err := json.Unmarshal(body, &patch)
+	if err != nil || patch == nil {
+		return update, fmt.Errorf("body should be a json object")
+	}
+
+	for field, raw := range patch {
+		var target **string
+		switch field {
+		case "first_name":
+			target = &update.FirstName
+		case "last_name":
+			target = &update.LastName
+		default:
+			return update, fmt.Errorf("field %s can not be updated", field)
+		}
+
+		// null removes the member, an empty value is refused by the service
+		value := ""
+		if string(raw) != "null" {
+			err = json.Unmarshal(raw, &value)
+			if err != nil {
+				return update, fmt.Errorf("field %s should be a string", field)
+			}
+		}
+		*target = &value
+	}
+
+	return update, nil
+}
+
+// deleteUser godoc
+// @Description Soft deletes the user with given id and ends its sessions, the user
+// @Description can not log in until restored and is purged after the retention period.
+// @Param id query string false "User's ID"
+// @Success 204
+func (h *Router) deleteUser(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	ctx := r.Context()
+
+	target, ok := h.userFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.userService.DeleteUser(ctx, target.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = h.authService.DeleteSessions(ctx, target.Email)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// restoreUser godoc
+// @Description Restores the soft deleted user with given id.
+// @Param id query string false "User's ID"
+// @Success 200 {object} jsonapi.Response{getUserResponse}
+func (h *Router) restoreUser(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	ctx := r.Context()
+
+	id := mux.Vars(r)["id"]
+
+	restored, err := h.userService.RestoreUser(ctx, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	payload, err := json.Marshal(newGetUserResponse(restored))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(payload)
+}
+
+// unlockUser godoc
+// @Description Clears failed login attempts and lockout of the user with given id.
+// @Param id query string false "User's ID"
+// @Success 204
+func (h *Router) unlockUser(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	ctx := r.Context()
+
+	id := mux.Vars(r)["id"]
+
+	err := h.userService.UnlockUser(ctx, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// userFromPath returns the user identified by the id route variable, the
+// error response is written when it can not be read
+func (h *Router) userFromPath(w http.ResponseWriter, r *http.Request) (*user.User, bool) {
+	target, err := h.userService.GetUser(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	return target, true
+}
+
+// verifyEmail godoc
+// @Description Confirms email of the user that received given verification token.
+// @Param token query string true "Email verification token"
+// @Success 204
+func (h *Router) verifyEmail(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	ctx := r.Context()
+
+	token := r.URL.Query().Get("token")
+
+	_, err := h.verificationService.VerifyEmail(ctx, token)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,58 @@
+package users
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseProfilePatch(t *testing.T) {
+	testCases := map[string]struct {
+		body      string
+		firstName *string
+		lastName  *string
+		err       string
+	}{
+		"present member should be set": {
+			body:     `{"last_name": "Lipa-Ahmeti"}`,
+			lastName: stringPtr("Lipa-Ahmeti"),
+		},
+		"null member should be removed": {
+			body:      `{"first_name": null}`,
+			firstName: stringPtr(""),
+		},
+		"empty patch should not change anything": {
+			body: `{}`,
+		},
+		"immutable member should return error": {
+			body: `{"email": "other@lipa.com"}`,
+			err:  "field email can not be updated",
+		},
+		"wrong type should return error": {
+			body: `{"first_name": 7}`,
+			err:  "field first_name should be a string",
+		},
+		"non object body should return error": {
+			body: `["first_name"]`,
+			err:  "body should be a json object",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got, err := parseProfilePatch([]byte(tc.body))
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.firstName, got.FirstName)
+			assert.Equal(t, tc.lastName, got.LastName)
+		})
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,164 @@
+package users
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/alamrios/crabi-solution/internal/app/pld"
+	"github.com/alamrios/crabi-solution/internal/app/user"
+)
+
+// Stable codes of PLD screening errors, clients should branch on them
+// instead of messages
+const (
+	screeningCodeBlacklisted    = "pld_blacklisted"
+	screeningCodeReviewRequired = "pld_review_required"
+)
+
+type screeningErrorResponse struct {
+	Error string `json:"error" validate:"required" example:"user was found in pld blacklist"`
+	Code  string `json:"code" validate:"required" example:"pld_blacklisted"`
+	// UserID id the screening attempts were recorded under, for support
+	UserID string `json:"user_id,omitempty" example:"3f2b8c1e-5d4a-4e6f-9b7c-2a1d0e8f6c5b"`
+	// ReferenceID identifier of the screening at PLD provider, for support
+	ReferenceID string `json:"reference_id,omitempty" example:"scr_01HZX3"`
+}
+
+// writeScreeningError responds 403 when PLD screening found the user in a
+// blacklist and 422 when it needs a manual review, which only profile updates
+// return since new users waiting for review are stored. Returns false for
+// other errors
+func writeScreeningError(w http.ResponseWriter, err error) bool {
+	var screeningErr *user.ScreeningError
+	if !errors.As(err, &screeningErr) {
+		return false
+	}
+
+	response := screeningErrorResponse{
+		Error:       screeningErr.Error(),
+		Code:        screeningCodeBlacklisted,
+		UserID:      screeningErr.UserID,
+		ReferenceID: screeningErr.Result.ReferenceID,
+	}
+	status := http.StatusForbidden
+	if errors.Is(err, user.ErrScreeningReview) {
+		response.Code = screeningCodeReviewRequired
+		status = http.StatusUnprocessableEntity
+	}
+
+	payload, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return true
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(payload)
+
+	return true
+}
+
+type screeningRequestResponse struct {
+	FirstName string `json:"first_name" example:"Dua"`
+	LastName  string `json:"last_name" example:"Lipa"`
+	Email     string `json:"email" example:"dua@lipa.com"`
+}
+
+type screeningResponse struct {
+	UserID     string                   `json:"user_id" validate:"required" example:"3f2b8c1e-5d4a-4e6f-9b7c-2a1d0e8f6c5b"`
+	Provider   string                   `json:"provider" validate:"required" example:"crabi-pld"`
+	Request    screeningRequestResponse `json:"request" validate:"required"`
+	StatusCode int                      `json:"status_code,omitempty" example:"201"`
+	// ResponseBody raw provider response
+	ResponseBody string `json:"response_body,omitempty" example:"{\"is_in_blacklist\":false}"`
+	LatencyMS    int64  `json:"latency_ms" example:"87"`
+	// Decision clear, hit or review, empty when the attempt failed
+	Decision    string   `json:"decision,omitempty" example:"clear"`
+	Lists       []string `json:"lists,omitempty" example:"OFAC"`
+	Score       float64  `json:"score,omitempty" example:"72.5"`
+	ReferenceID string   `json:"reference_id,omitempty" example:"scr_01HZX3"`
+	// Error failure of the attempt, empty when it succeeded
+	Error     string    `json:"error,omitempty" example:"pld server returned 503 status code"`
+	CheckedAt time.Time `json:"checked_at" validate:"required" example:"2026-01-01T00:00:00Z"`
+}
+
+// listScreenings godoc
+// @Description Lists every PLD screening attempt of the user with given id, newest first.
+// @Param id query string false "User's ID"
+// @Success 200 {object} jsonapi.Response{[]screeningResponse}
+func (h *Router) listScreenings(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	ctx := r.Context()
+
+	// history outlives the user, deleted users can be reviewed too
+	id := mux.Vars(r)["id"]
+
+	screenings, err := h.screeningService.ListScreenings(ctx, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeScreenings(w, screenings)
+}
+
+// listScreeningsByEmail godoc
+// @Description Lists every PLD screening attempt of given email, newest first. Applicants rejected at registration are not stored as users, their screenings are found by email.
+// @Param email query string true "Screened email"
+// @Success 200 {object} jsonapi.Response{[]screeningResponse}
+func (h *Router) listScreeningsByEmail(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	ctx := r.Context()
+
+	screenings, err := h.screeningService.ListScreeningsByEmail(ctx, r.URL.Query().Get("email"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeScreenings(w, screenings)
+}
+
+// writeScreenings responds given screenings
+func writeScreenings(w http.ResponseWriter, screenings []pld.Screening) {
+	response := make([]screeningResponse, 0, len(screenings))
+	for _, screening := range screenings {
+		response = append(response, newScreeningResponse(screening))
+	}
+
+	payload, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(payload)
+}
+
+func newScreeningResponse(screening pld.Screening) screeningResponse {
+	return screeningResponse{
+		UserID:   screening.UserID,
+		Provider: screening.Provider,
+		Request: screeningRequestResponse{
+			FirstName: screening.Request.FirstName,
+			LastName:  screening.Request.LastName,
+			Email:     screening.Request.Email,
+		},
+		StatusCode:   screening.StatusCode,
+		ResponseBody: screening.ResponseBody,
+		LatencyMS:    screening.Latency.Milliseconds(),
+		Decision:     string(screening.Decision),
+		Lists:        screening.Lists,
+		Score:        screening.Score,
+		ReferenceID:  screening.ReferenceID,
+		Error:        screening.Error,
+		CheckedAt:    screening.CheckedAt,
+	}
+}
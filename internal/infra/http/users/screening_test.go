@@ -0,0 +1,214 @@
+package users
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/alamrios/crabi-solution/internal/app/pld"
+	"github.com/alamrios/crabi-solution/internal/app/user"
+	tmock "github.com/alamrios/crabi-solution/test/mock"
+)
+
+func TestWriteScreeningError(t *testing.T) {
+	testCases := map[string]struct {
+		err     error
+		written bool
+		status  int
+		body    string
+	}{
+		"hit should return forbidden": {
+			err: &user.ScreeningError{UserID: "user1", Result: pld.ScreeningResult{
+				Decision:    pld.DecisionHit,
+				Lists:       []string{"OFAC"},
+				ReferenceID: "scr_1",
+			}},
+			written: true,
+			status:  http.StatusForbidden,
+			body:    `{"error":"user was found in pld blacklist","code":"pld_blacklisted","user_id":"user1","reference_id":"scr_1"}`,
+		},
+		"review should return unprocessable entity": {
+			err:     fmt.Errorf("wrapped: %w", &user.ScreeningError{Result: pld.ScreeningResult{Decision: pld.DecisionReview}}),
+			written: true,
+			status:  http.StatusUnprocessableEntity,
+			body:    `{"error":"user needs a manual pld review","code":"pld_review_required"}`,
+		},
+		"other errors should not be written": {
+			err: pld.ErrUnavailable,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+
+			written := writeScreeningError(recorder, tc.err)
+			assert.Equal(t, tc.written, written)
+			if !tc.written {
+				assert.Empty(t, recorder.Body.String())
+				return
+			}
+
+			assert.Equal(t, tc.status, recorder.Code)
+			assert.JSONEq(t, tc.body, recorder.Body.String())
+		})
+	}
+}
+
+func TestListScreenings(t *testing.T) {
+	checkedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	screenings := []pld.Screening{
+		{
+			UserID:       "user1",
+			Provider:     "crabi-pld",
+			Request:      pld.Request{FirstName: "Dua", LastName: "Lipa", Email: "dua@lipa.com"},
+			StatusCode:   201,
+			ResponseBody: `{"is_in_blacklist":false}`,
+			Latency:      87 * time.Millisecond,
+			Decision:     pld.DecisionClear,
+			CheckedAt:    checkedAt,
+		},
+		{
+			UserID:     "user1",
+			Provider:   "crabi-pld",
+			Request:    pld.Request{FirstName: "Dua", LastName: "Lipa", Email: "dua@lipa.com"},
+			StatusCode: 503,
+			Latency:    12 * time.Millisecond,
+			Error:      "pld server returned 503 status code",
+			CheckedAt:  checkedAt.Add(-time.Second),
+		},
+	}
+
+	testCases := map[string]struct {
+		id     string
+		calls  []tmock.Call
+		status int
+		body   string
+	}{
+		"screenings should be listed": {
+			id: "user1",
+			calls: []tmock.Call{
+				{
+					FunctionName: "ListScreenings",
+					Params:       []interface{}{"user1"},
+					Returns:      []interface{}{screenings, nil},
+				},
+			},
+			status: http.StatusOK,
+			body: `[
+				{"user_id":"user1","provider":"crabi-pld","request":{"first_name":"Dua","last_name":"Lipa","email":"dua@lipa.com"},"status_code":201,"response_body":"{\"is_in_blacklist\":false}","latency_ms":87,"decision":"clear","checked_at":"2026-01-02T03:04:05Z"},
+				{"user_id":"user1","provider":"crabi-pld","request":{"first_name":"Dua","last_name":"Lipa","email":"dua@lipa.com"},"status_code":503,"latency_ms":12,"error":"pld server returned 503 status code","checked_at":"2026-01-02T03:04:04Z"}
+			]`,
+		},
+		"user without screenings should return empty list": {
+			id: "user2",
+			calls: []tmock.Call{
+				{
+					FunctionName: "ListScreenings",
+					Params:       []interface{}{"user2"},
+					Returns:      []interface{}{[]pld.Screening{}, nil},
+				},
+			},
+			status: http.StatusOK,
+			body:   `[]`,
+		},
+		"repository error should return bad request": {
+			id: "user1",
+			calls: []tmock.Call{
+				{
+					FunctionName: "ListScreenings",
+					Params:       []interface{}{"user1"},
+					Returns:      []interface{}{([]pld.Screening)(nil), fmt.Errorf("db error")},
+				},
+			},
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			screeningRepo := tmock.NewScreeningRepository().AddCall(t, tc.calls)
+			screeningService, err := pld.NewAuditService(screeningRepo)
+			assert.NoError(t, err)
+
+			router := newTestRouter(t)
+			router.screeningService = screeningService
+
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/users/"+tc.id+"/screenings", nil)
+			r = mux.SetURLVars(r, map[string]string{"id": tc.id})
+			recorder := httptest.NewRecorder()
+
+			router.listScreenings(recorder, r)
+			assert.Equal(t, tc.status, recorder.Code)
+			if tc.body != "" {
+				assert.JSONEq(t, tc.body, recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestListScreeningsByEmail(t *testing.T) {
+	checkedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	screenings := []pld.Screening{
+		{
+			UserID:     "user1",
+			Provider:   "crabi-pld",
+			Request:    pld.Request{FirstName: "Dua", LastName: "Lipa", Email: "dua@lipa.com"},
+			StatusCode: 201,
+			Latency:    87 * time.Millisecond,
+			Decision:   pld.DecisionHit,
+			CheckedAt:  checkedAt,
+		},
+	}
+
+	testCases := map[string]struct {
+		email  string
+		calls  []tmock.Call
+		status int
+		body   string
+	}{
+		"screenings of rejected applicant should be listed": {
+			email: "dua@lipa.com",
+			calls: []tmock.Call{
+				{
+					FunctionName: "ListScreeningsByEmail",
+					Params:       []interface{}{"dua@lipa.com"},
+					Returns:      []interface{}{screenings, nil},
+				},
+			},
+			status: http.StatusOK,
+			body: `[
+				{"user_id":"user1","provider":"crabi-pld","request":{"first_name":"Dua","last_name":"Lipa","email":"dua@lipa.com"},"status_code":201,"latency_ms":87,"decision":"hit","checked_at":"2026-01-02T03:04:05Z"}
+			]`,
+		},
+		"missing email should return bad request": {
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			screeningRepo := tmock.NewScreeningRepository().AddCall(t, tc.calls)
+			screeningService, err := pld.NewAuditService(screeningRepo)
+			assert.NoError(t, err)
+
+			router := newTestRouter(t)
+			router.screeningService = screeningService
+
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/screenings?email="+url.QueryEscape(tc.email), nil)
+			recorder := httptest.NewRecorder()
+
+			router.listScreeningsByEmail(recorder, r)
+			assert.Equal(t, tc.status, recorder.Code)
+			if tc.body != "" {
+				assert.JSONEq(t, tc.body, recorder.Body.String())
+			}
+		})
+	}
+}
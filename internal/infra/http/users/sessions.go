@@ -0,0 +1,109 @@
+package users
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/alamrios/crabi-solution/internal/app/auth"
+)
+
+type sessionResponse struct {
+	ID         string    `json:"id" validate:"required" example:"9c1f4e2a7b3d5f60a8e2c4b6d8f0a1c3"`
+	UserAgent  string    `json:"user_agent" example:"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0)"`
+	IP         string    `json:"ip" example:"203.0.113.7"`
+	CreatedAt  time.Time `json:"created_at" validate:"required" example:"2026-01-01T00:00:00Z"`
+	LastSeenAt time.Time `json:"last_seen_at" validate:"required" example:"2026-01-01T01:00:00Z"`
+	ExpiresAt  time.Time `json:"expires_at" validate:"required" example:"2026-01-31T00:00:00Z"`
+	// Current marks the session of the token used in the request
+	Current bool `json:"current"`
+}
+
+// listSessions godoc
+// @Description Lists active login sessions of the user with given id.
+// @Param id query string false "User's ID"
+// @Success 200 {object} jsonapi.Response{[]sessionResponse}
+func (h *Router) listSessions(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	ctx := r.Context()
+
+	target, ok := h.userFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	sessions, err := h.authService.ListSessions(ctx, target.Email)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var currentID string
+	if claims, ok := ClaimsFromContext(ctx); ok {
+		currentID = claims.SessionID
+	}
+
+	response := make([]sessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, sessionResponse{
+			ID:         session.ID,
+			UserAgent:  session.UserAgent,
+			IP:         session.IP,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.ID == currentID,
+		})
+	}
+
+	payload, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(payload)
+}
+
+// deleteSession godoc
+// @Description Ends a login session of the user with given id, its access and refresh tokens stop working.
+// @Param id query string false "User's ID"
+// @Param sessionId query string false "Session ID"
+// @Success 204
+func (h *Router) deleteSession(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	ctx := r.Context()
+
+	target, ok := h.userFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.authService.DeleteSession(ctx, target.Email, mux.Vars(r)["sessionId"])
+	if errors.Is(err, auth.ErrSessionNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// clientIP returns the address of the peer that sent the request
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
@@ -0,0 +1,150 @@
+package users
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/alamrios/crabi-solution/internal/app/auth"
+	"github.com/alamrios/crabi-solution/internal/app/user"
+	tmock "github.com/alamrios/crabi-solution/test/mock"
+)
+
+func TestVerifyJWTSession(t *testing.T) {
+	user1 := &user.User{
+		ID:     "user1",
+		Email:  "dua@lipa.com",
+		Status: user.StatusActive,
+		Role:   user.RoleCustomer,
+	}
+
+	testCases := map[string]struct {
+		sessionID string
+		session   *auth.Session
+		// emailSubject issues the token with the email as subject, as
+		// before user ids existed
+		emailSubject bool
+		// client issues a client credentials token, they carry no session
+		client   bool
+		expected int
+	}{
+		"active session should be accepted": {
+			sessionID: "session1",
+			session: &auth.Session{
+				ID:         "session1",
+				Email:      "dua@lipa.com",
+				LastSeenAt: time.Now(),
+				ExpiresAt:  time.Now().Add(time.Hour),
+			},
+			expected: http.StatusOK,
+		},
+		"deleted session should be rejected": {
+			sessionID: "session1",
+			session:   nil,
+			expected:  http.StatusUnauthorized,
+		},
+		"session of another user should be rejected": {
+			sessionID: "session1",
+			session: &auth.Session{
+				ID:    "session1",
+				Email: "joaquin@guzman.com",
+			},
+			expected: http.StatusUnauthorized,
+		},
+		"user token without session should be rejected": {
+			expected: http.StatusUnauthorized,
+		},
+		"client token without session should be accepted": {
+			client:   true,
+			expected: http.StatusOK,
+		},
+		"token with email subject should be rejected": {
+			emailSubject: true,
+			expected:     http.StatusUnauthorized,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			userRepo := tmock.NewUserRepository().AddCall(t, []tmock.Call{
+				{
+					FunctionName: "GetUserByID",
+					Params:       []interface{}{"user1"},
+					Returns:      []interface{}{user1, nil},
+				},
+				{
+					FunctionName: "GetUserByID",
+					Params:       []interface{}{"dua@lipa.com"},
+					Returns:      []interface{}{(*user.User)(nil), nil},
+				},
+			})
+			userService, err := user.NewService(
+				tmock.NewPLDService(),
+				userRepo,
+				tmock.NewPasswordHasher(),
+				user.LockoutPolicy{},
+				user.PasswordPolicy{},
+				tmock.NewEmailVerifier(),
+				tmock.NewSessionRevoker(),
+			)
+			assert.NoError(t, err)
+
+			revokedTokenRepo := tmock.NewRevokedTokenRepository().AddCall(t, []tmock.Call{
+				{
+					FunctionName: "IsTokenRevoked",
+					Params:       []interface{}{mock.AnythingOfType("string")},
+					Returns:      []interface{}{false, nil},
+				},
+			})
+			sessionRepo := tmock.NewSessionRepository().AddCall(t, []tmock.Call{
+				{
+					FunctionName: "GetSession",
+					Params:       []interface{}{"session1"},
+					Returns:      []interface{}{tc.session, nil},
+				},
+			})
+			authService, err := auth.NewService(tmock.NewRefreshTokenRepository(), revokedTokenRepo, sessionRepo, time.Hour)
+			assert.NoError(t, err)
+
+			client1 := &auth.Client{ID: "client1", Scopes: []string{"users:read"}}
+			clientRepo := tmock.NewClientRepository().AddCall(t, []tmock.Call{
+				{
+					FunctionName: "GetClient",
+					Params:       []interface{}{"client1"},
+					Returns:      []interface{}{client1, nil},
+				},
+			})
+			clientService, err := auth.NewClientService(clientRepo)
+			assert.NoError(t, err)
+
+			router := newTestRouter(t)
+			router.userService = userService
+			router.authService = authService
+			router.clientService = clientService
+
+			token, err := router.generateJWT(user1, tc.sessionID)
+			if tc.emailSubject {
+				claims := Claims{Email: user1.Email, Role: string(user1.Role)}
+				claims.Subject = user1.Email
+				token, err = router.generateToken(claims, time.Hour)
+			}
+			if tc.client {
+				token, err = router.generateClientToken(client1, "users:read")
+			}
+			assert.NoError(t, err)
+
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/users/user1/sessions", nil)
+			r.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+
+			router.verifyJWT(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})(w, r)
+			assert.Equal(t, tc.expected, w.Code)
+		})
+	}
+}
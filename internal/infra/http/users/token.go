@@ -0,0 +1,154 @@
+package users
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/alamrios/crabi-solution/internal/app/auth"
+)
+
+type refreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required" example:"k3S0zN9rT6xQ..."`
+}
+
+// tokenType OAuth2 token type of issued access tokens
+const tokenType = "Bearer"
+
+type refreshTokenResponse struct {
+	AccessToken  string `json:"access_token" validate:"required" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	TokenType    string `json:"token_type" validate:"required" example:"Bearer"`
+	ExpiresIn    int64  `json:"expires_in" validate:"required" example:"3600"`
+	RefreshToken string `json:"refresh_token" validate:"required" example:"p8Vb1cL4mW2e..."`
+}
+
+// refreshToken godoc
+// @Description Exchanges a refresh token for a new access token and a rotated refresh token.
+// @Param refresh_token query string false "Refresh token returned by login or a previous refresh"
+// @Success 200 {object} jsonapi.Response{refreshTokenResponse}
+func (h *Router) refreshToken(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	ctx := r.Context()
+
+	var request refreshTokenRequest
+	reqBody, _ := ioutil.ReadAll(r.Body)
+	json.Unmarshal(reqBody, &request)
+
+	session, refreshToken, err := h.authService.RefreshSession(ctx, request.RefreshToken, r.UserAgent(), clientIP(r))
+	if errors.Is(err, auth.ErrInvalidRefreshToken) || errors.Is(err, auth.ErrRefreshTokenReused) {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.userService.GetUserByEmail(ctx, session.Email)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	token, err := h.generateJWT(user, session.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	payload, err := json.Marshal(refreshTokenResponse{
+		AccessToken:  token,
+		TokenType:    tokenType,
+		ExpiresIn:    int64(h.accessTokenTTL.Seconds()),
+		RefreshToken: refreshToken,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if h.legacyTokenHeader {
+		w.Header().Set("Token", token)
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(payload)
+}
+
+type logoutRequest struct {
+	RefreshToken string `json:"refresh_token" example:"k3S0zN9rT6xQ..."`
+}
+
+// logout godoc
+// @Description Revokes the access token used in the request and ends its session, along with
+// @Description the refresh token of the same login if sent.
+// @Param refresh_token query string false "Refresh token to revoke along with the access token"
+// @Success 204
+func (h *Router) logout(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	ctx := r.Context()
+
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		http.Error(w, "token claims not found", http.StatusUnauthorized)
+		return
+	}
+
+	var request logoutRequest
+	reqBody, _ := ioutil.ReadAll(r.Body)
+	json.Unmarshal(reqBody, &request)
+
+	err := h.authService.RevokeToken(ctx, claims.Id, claims.Email, h.revokedUntil(claims))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if claims.SessionID != "" {
+		err = h.authService.DeleteSession(ctx, claims.Email, claims.SessionID)
+		if err != nil && !errors.Is(err, auth.ErrSessionNotFound) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if request.RefreshToken != "" {
+		err = h.authService.RevokeRefreshToken(ctx, request.RefreshToken)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// revokeSessions godoc
+// @Description Revokes every access and refresh token issued to the user with given id and ends its sessions.
+// @Param id query string false "User's ID"
+// @Success 204
+func (h *Router) revokeSessions(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	ctx := r.Context()
+
+	target, ok := h.userFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.userService.RevokeSessions(ctx, target.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = h.authService.DeleteSessions(ctx, target.Email)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,130 @@
+package users
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/alamrios/crabi-solution/internal/app/auth"
+	"github.com/alamrios/crabi-solution/internal/app/user"
+	tmock "github.com/alamrios/crabi-solution/test/mock"
+)
+
+// expiringRevokedTokens forgets revoked tokens once they expire, as the
+// mongo ttl index does
+type expiringRevokedTokens struct {
+	mu     sync.Mutex
+	tokens map[string]time.Time
+}
+
+func (r *expiringRevokedTokens) SaveRevokedToken(_ context.Context, token auth.RevokedToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tokens[token.JTI] = token.ExpiresAt
+	return nil
+}
+
+func (r *expiringRevokedTokens) InsertRevokedToken(ctx context.Context, token auth.RevokedToken) (bool, error) {
+	revoked, _ := r.IsTokenRevoked(ctx, token.JTI)
+	if revoked {
+		return false, nil
+	}
+
+	return true, r.SaveRevokedToken(ctx, token)
+}
+
+func (r *expiringRevokedTokens) IsTokenRevoked(_ context.Context, jti string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	expiresAt, ok := r.tokens[jti]
+	return ok && time.Now().Before(expiresAt), nil
+}
+
+func TestLogoutRevocationLeeway(t *testing.T) {
+	user1 := &user.User{
+		ID:     "user1",
+		Email:  "dua@lipa.com",
+		Status: user.StatusActive,
+		Role:   user.RoleCustomer,
+	}
+	session1 := &auth.Session{
+		ID:         "session1",
+		Email:      "dua@lipa.com",
+		LastSeenAt: time.Now(),
+		ExpiresAt:  time.Now().Add(time.Hour),
+	}
+
+	userRepo := tmock.NewUserRepository().AddCall(t, []tmock.Call{
+		{
+			FunctionName: "GetUserByID",
+			Params:       []interface{}{"user1"},
+			Returns:      []interface{}{user1, nil},
+		},
+	})
+	userService, err := user.NewService(
+		tmock.NewPLDService(),
+		userRepo,
+		tmock.NewPasswordHasher(),
+		user.LockoutPolicy{},
+		user.PasswordPolicy{},
+		tmock.NewEmailVerifier(),
+		tmock.NewSessionRevoker(),
+	)
+	assert.NoError(t, err)
+
+	sessionRepo := tmock.NewSessionRepository().AddCall(t, []tmock.Call{
+		{
+			FunctionName: "GetSession",
+			Params:       []interface{}{"session1"},
+			Returns:      []interface{}{session1, nil},
+		},
+		{
+			FunctionName: "DeleteSession",
+			Params:       []interface{}{"session1"},
+			Returns:      []interface{}{nil},
+		},
+	})
+	refreshTokenRepo := tmock.NewRefreshTokenRepository().AddCall(t, []tmock.Call{
+		{
+			FunctionName: "RevokeRefreshTokenFamily",
+			Params:       []interface{}{"session1", mock.AnythingOfType("time.Time")},
+			Returns:      []interface{}{nil},
+		},
+	})
+	revokedTokenRepo := &expiringRevokedTokens{tokens: map[string]time.Time{}}
+	authService, err := auth.NewService(refreshTokenRepo, revokedTokenRepo, sessionRepo, time.Hour)
+	assert.NoError(t, err)
+
+	router := newTestRouter(t)
+	router.userService = userService
+	router.authService = authService
+
+	// expired a second ago, still accepted within the leeway
+	router.accessTokenTTL = -time.Second
+	token, err := router.generateJWT(user1, "session1")
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/logout", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+
+	router.verifyJWT(router.logout)(w, r)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	r = httptest.NewRequest(http.MethodGet, "/api/v1/users/user1", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	w = httptest.NewRecorder()
+
+	router.verifyJWT(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
@@ -0,0 +1,67 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+)
+
+// LogNotifier writes account messages to the service log instead of
+// delivering them, meant for local development only
+type LogNotifier struct {
+	passwordResetURL     string
+	emailVerificationURL string
+}
+
+// NewLogNotifier returns an instance of log notifier
+func NewLogNotifier(passwordResetURL, emailVerificationURL string) (*LogNotifier, error) {
+	if passwordResetURL == "" {
+		return nil, fmt.Errorf("password reset url is empty")
+	}
+
+	if emailVerificationURL == "" {
+		return nil, fmt.Errorf("email verification url is empty")
+	}
+
+	return &LogNotifier{
+		passwordResetURL:     passwordResetURL,
+		emailVerificationURL: emailVerificationURL,
+	}, nil
+}
+
+// SendPasswordReset logs password reset link for given email
+func (n *LogNotifier) SendPasswordReset(_ context.Context, email, token string) error {
+	link, err := withToken(n.passwordResetURL, token)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("password reset requested for %s: %s", email, link)
+	return nil
+}
+
+// SendEmailVerification logs email verification link for given email
+func (n *LogNotifier) SendEmailVerification(_ context.Context, email, token string) error {
+	link, err := withToken(n.emailVerificationURL, token)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("email verification requested for %s: %s", email, link)
+	return nil
+}
+
+// withToken returns given url with token query param
+func withToken(rawURL, token string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	query := u.Query()
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
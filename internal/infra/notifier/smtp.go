@@ -0,0 +1,103 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+
+	"github.com/alamrios/crabi-solution/config"
+)
+
+// sendMailFunc sends a message through an SMTP server, smtp.SendMail
+// signature
+type sendMailFunc func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error
+
+// SMTPNotifier delivers account messages by email through an SMTP server
+type SMTPNotifier struct {
+	addr                 string
+	auth                 smtp.Auth
+	from                 string
+	passwordResetURL     string
+	emailVerificationURL string
+	sendMail             sendMailFunc
+}
+
+// NewSMTPNotifier returns an instance of SMTP notifier
+func NewSMTPNotifier(cfg *config.Notifier) (*SMTPNotifier, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("notifier config is nil")
+	}
+
+	if cfg.SMTP.Host == "" {
+		return nil, fmt.Errorf("smtp host is empty")
+	}
+
+	if cfg.SMTP.From == "" {
+		return nil, fmt.Errorf("smtp sender is empty")
+	}
+
+	if cfg.PasswordResetURL == "" {
+		return nil, fmt.Errorf("password reset url is empty")
+	}
+
+	if cfg.EmailVerificationURL == "" {
+		return nil, fmt.Errorf("email verification url is empty")
+	}
+
+	var auth smtp.Auth
+	if cfg.SMTP.User != "" {
+		// PlainAuth refuses to send credentials over unencrypted connections
+		// to hosts other than localhost
+		auth = smtp.PlainAuth("", cfg.SMTP.User, cfg.SMTP.Password, cfg.SMTP.Host)
+	}
+
+	return &SMTPNotifier{
+		addr:                 net.JoinHostPort(cfg.SMTP.Host, cfg.SMTP.Port),
+		auth:                 auth,
+		from:                 cfg.SMTP.From,
+		passwordResetURL:     cfg.PasswordResetURL,
+		emailVerificationURL: cfg.EmailVerificationURL,
+		sendMail:             smtp.SendMail,
+	}, nil
+}
+
+// SendPasswordReset emails password reset link to given email
+func (n *SMTPNotifier) SendPasswordReset(_ context.Context, email, token string) error {
+	link, err := withToken(n.passwordResetURL, token)
+	if err != nil {
+		return err
+	}
+
+	return n.send(email, "Restablece tu contraseña",
+		"Para restablecer tu contraseña abre el siguiente enlace:\r\n\r\n"+link+"\r\n\r\n"+
+			"Si no solicitaste el cambio puedes ignorar este mensaje.\r\n")
+}
+
+// SendEmailVerification emails email verification link to given email
+func (n *SMTPNotifier) SendEmailVerification(_ context.Context, email, token string) error {
+	link, err := withToken(n.emailVerificationURL, token)
+	if err != nil {
+		return err
+	}
+
+	return n.send(email, "Verifica tu email",
+		"Para verificar tu email abre el siguiente enlace:\r\n\r\n"+link+"\r\n")
+}
+
+// send emails given plain text message to given address
+func (n *SMTPNotifier) send(to, subject, body string) error {
+	if strings.ContainsAny(to, "\r\n") {
+		return fmt.Errorf("invalid recipient address")
+	}
+
+	msg := "From: " + n.from + "\r\n" +
+		"To: " + to + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" + body
+
+	return n.sendMail(n.addr, n.auth, n.from, []string{to}, []byte(msg))
+}
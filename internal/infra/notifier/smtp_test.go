@@ -0,0 +1,120 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"net/smtp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/alamrios/crabi-solution/config"
+)
+
+func TestNewSMTPNotifier(t *testing.T) {
+	cfg := config.Notifier{
+		PasswordResetURL:     "https://crabi.mx/reset-password",
+		EmailVerificationURL: "https://crabi.mx/api/v1/users/verify",
+		SMTP: config.SMTP{
+			Host: "smtp.crabi.mx",
+			Port: "587",
+			From: "no-reply@crabi.mx",
+		},
+	}
+
+	testCases := map[string]struct {
+		cfg func(cfg config.Notifier) *config.Notifier
+		err string
+	}{
+		"success": {
+			cfg: func(cfg config.Notifier) *config.Notifier { return &cfg },
+		},
+		"nil config should return error": {
+			cfg: func(cfg config.Notifier) *config.Notifier { return nil },
+			err: "notifier config is nil",
+		},
+		"empty host should return error": {
+			cfg: func(cfg config.Notifier) *config.Notifier {
+				cfg.SMTP.Host = ""
+				return &cfg
+			},
+			err: "smtp host is empty",
+		},
+		"empty sender should return error": {
+			cfg: func(cfg config.Notifier) *config.Notifier {
+				cfg.SMTP.From = ""
+				return &cfg
+			},
+			err: "smtp sender is empty",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got, err := NewSMTPNotifier(tc.cfg(cfg))
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				assert.Nil(t, got)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, got)
+			}
+		})
+	}
+}
+
+func TestSMTPNotifierSend(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Notifier{
+		PasswordResetURL:     "https://crabi.mx/reset-password",
+		EmailVerificationURL: "https://crabi.mx/api/v1/users/verify",
+		SMTP: config.SMTP{
+			Host: "smtp.crabi.mx",
+			Port: "587",
+			From: "no-reply@crabi.mx",
+		},
+	}
+
+	var sent struct {
+		addr string
+		from string
+		to   []string
+		msg  string
+	}
+	notifier, err := NewSMTPNotifier(cfg)
+	assert.NoError(t, err)
+	notifier.sendMail = func(addr string, _ smtp.Auth, from string, to []string, msg []byte) error {
+		sent.addr, sent.from, sent.to, sent.msg = addr, from, to, string(msg)
+		return nil
+	}
+
+	t.Run("password reset should email the link", func(t *testing.T) {
+		err := notifier.SendPasswordReset(ctx, "dua@lipa.com", "token1")
+		assert.NoError(t, err)
+		assert.Equal(t, "smtp.crabi.mx:587", sent.addr)
+		assert.Equal(t, "no-reply@crabi.mx", sent.from)
+		assert.Equal(t, []string{"dua@lipa.com"}, sent.to)
+		assert.True(t, strings.HasPrefix(sent.msg, "From: no-reply@crabi.mx\r\nTo: dua@lipa.com\r\n"))
+		assert.Contains(t, sent.msg, "https://crabi.mx/reset-password?token=token1")
+	})
+
+	t.Run("email verification should email the link", func(t *testing.T) {
+		err := notifier.SendEmailVerification(ctx, "dua@lipa.com", "token2")
+		assert.NoError(t, err)
+		assert.Contains(t, sent.msg, "https://crabi.mx/api/v1/users/verify?token=token2")
+	})
+
+	t.Run("recipient with line breaks should return error", func(t *testing.T) {
+		err := notifier.SendPasswordReset(ctx, "dua@lipa.com\r\nBcc: joaquin@guzman.com", "token1")
+		assert.EqualError(t, err, "invalid recipient address")
+	})
+
+	t.Run("delivery error should propagate", func(t *testing.T) {
+		notifier.sendMail = func(string, smtp.Auth, string, []string, []byte) error {
+			return fmt.Errorf("connection refused")
+		}
+		err := notifier.SendEmailVerification(ctx, "dua@lipa.com", "token2")
+		assert.EqualError(t, err, "connection refused")
+	})
+}
@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/alamrios/crabi-solution/internal/app/auth"
+)
+
+// APIKeyCollection name of mongo collection
+const APIKeyCollection = "api_keys"
+
+// APIKeyRepository struct for API keys mongo repository
+type APIKeyRepository struct {
+	mongoDB *mongo.Database
+}
+
+// NewAPIKeyRepository returns an instance of API keys mongo repository
+func NewAPIKeyRepository(mongoDB *mongo.Database) (*APIKeyRepository, error) {
+	if mongoDB == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	return &APIKeyRepository{
+		mongoDB: mongoDB,
+	}, nil
+}
+
+// EnsureIndexes creates owner lookup index, expired keys are kept for audit
+func (r *APIKeyRepository) EnsureIndexes(ctx context.Context) error {
+	collection := r.mongoDB.Collection(APIKeyCollection)
+
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "owner", Value: 1}},
+	})
+	return err
+}
+
+// SaveAPIKey method
+func (r *APIKeyRepository) SaveAPIKey(ctx context.Context, apiKey auth.APIKey) error {
+	collection := r.mongoDB.Collection(APIKeyCollection)
+
+	_, err := collection.InsertOne(ctx, apiKey)
+	return err
+}
+
+// GetAPIKey returns API key in mongo collection with given id
+func (r *APIKeyRepository) GetAPIKey(ctx context.Context, id string) (*auth.APIKey, error) {
+	collection := r.mongoDB.Collection(APIKeyCollection)
+
+	query := collection.FindOne(
+		ctx,
+		bson.M{
+			"_id": id,
+		},
+	)
+
+	if query.Err() == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+
+	var apiKey auth.APIKey
+	err := query.Decode(&apiKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &apiKey, nil
+}
+
+// ListAPIKeys returns every API key in mongo collection, newest first
+func (r *APIKeyRepository) ListAPIKeys(ctx context.Context) ([]auth.APIKey, error) {
+	collection := r.mongoDB.Collection(APIKeyCollection)
+
+	cursor, err := collection.Find(
+		ctx,
+		bson.M{},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKeys := []auth.APIKey{}
+	err = cursor.All(ctx, &apiKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	return apiKeys, nil
+}
+
+// RevokeAPIKey sets revocation date of API key with given id
+func (r *APIKeyRepository) RevokeAPIKey(ctx context.Context, id string, revokedAt time.Time) error {
+	collection := r.mongoDB.Collection(APIKeyCollection)
+
+	_, err := collection.UpdateOne(
+		ctx,
+		bson.M{
+			"_id": id,
+		},
+		bson.M{
+			"$set": bson.M{
+				"revoked_at": revokedAt,
+			},
+		},
+	)
+	return err
+}
+
+// UpdateAPIKeyLastUsed sets last use date of API key with given id
+func (r *APIKeyRepository) UpdateAPIKeyLastUsed(ctx context.Context, id string, usedAt time.Time) error {
+	collection := r.mongoDB.Collection(APIKeyCollection)
+
+	_, err := collection.UpdateOne(
+		ctx,
+		bson.M{
+			"_id": id,
+		},
+		bson.M{
+			"$set": bson.M{
+				"last_used_at": usedAt,
+			},
+		},
+	)
+	return err
+}
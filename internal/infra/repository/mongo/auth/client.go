@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/alamrios/crabi-solution/internal/app/auth"
+)
+
+// ClientCollection name of mongo collection
+const ClientCollection = "oauth_clients"
+
+// ClientRepository struct for OAuth2 clients mongo repository
+type ClientRepository struct {
+	mongoDB *mongo.Database
+}
+
+// NewClientRepository returns an instance of OAuth2 clients mongo repository
+func NewClientRepository(mongoDB *mongo.Database) (*ClientRepository, error) {
+	if mongoDB == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	return &ClientRepository{
+		mongoDB: mongoDB,
+	}, nil
+}
+
+// SaveClient method
+func (r *ClientRepository) SaveClient(ctx context.Context, client auth.Client) error {
+	collection := r.mongoDB.Collection(ClientCollection)
+
+	_, err := collection.InsertOne(ctx, client)
+	return err
+}
+
+// GetClient returns client in mongo collection with given id
+func (r *ClientRepository) GetClient(ctx context.Context, id string) (*auth.Client, error) {
+	collection := r.mongoDB.Collection(ClientCollection)
+
+	query := collection.FindOne(
+		ctx,
+		bson.M{
+			"_id": id,
+		},
+	)
+
+	if query.Err() == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+
+	var client auth.Client
+	err := query.Decode(&client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &client, nil
+}
+
+// DisableClient sets disabled date of client with given id
+func (r *ClientRepository) DisableClient(ctx context.Context, id string, disabledAt time.Time) error {
+	collection := r.mongoDB.Collection(ClientCollection)
+
+	_, err := collection.UpdateOne(
+		ctx,
+		bson.M{
+			"_id": id,
+		},
+		bson.M{
+			"$set": bson.M{
+				"disabled_at": disabledAt,
+			},
+		},
+	)
+	return err
+}
@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/alamrios/crabi-solution/internal/app/auth"
+)
+
+// RefreshTokenCollection name of mongo collection
+const RefreshTokenCollection = "refresh_tokens"
+
+// RefreshTokenRepository struct for refresh tokens mongo repository
+type RefreshTokenRepository struct {
+	mongoDB *mongo.Database
+}
+
+// NewRefreshTokenRepository returns an instance of refresh tokens mongo repository
+func NewRefreshTokenRepository(mongoDB *mongo.Database) (*RefreshTokenRepository, error) {
+	if mongoDB == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	return &RefreshTokenRepository{
+		mongoDB: mongoDB,
+	}, nil
+}
+
+// EnsureIndexes creates family and email lookup indexes and expiration TTL index
+func (r *RefreshTokenRepository) EnsureIndexes(ctx context.Context) error {
+	collection := r.mongoDB.Collection(RefreshTokenCollection)
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "family_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "email", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	return err
+}
+
+// SaveRefreshToken method
+func (r *RefreshTokenRepository) SaveRefreshToken(ctx context.Context, token auth.RefreshToken) error {
+	collection := r.mongoDB.Collection(RefreshTokenCollection)
+
+	_, err := collection.InsertOne(ctx, token)
+	return err
+}
+
+// GetRefreshToken returns refresh token in mongo collection with given hash
+func (r *RefreshTokenRepository) GetRefreshToken(ctx context.Context, hash string) (*auth.RefreshToken, error) {
+	collection := r.mongoDB.Collection(RefreshTokenCollection)
+
+	query := collection.FindOne(
+		ctx,
+		bson.M{
+			"_id": hash,
+		},
+	)
+
+	if query.Err() == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+
+	var token auth.RefreshToken
+	err := query.Decode(&token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// MarkRefreshTokenUsed sets used date only if token was not used before
+func (r *RefreshTokenRepository) MarkRefreshTokenUsed(ctx context.Context, hash string, usedAt time.Time) (bool, error) {
+	collection := r.mongoDB.Collection(RefreshTokenCollection)
+
+	result, err := collection.UpdateOne(
+		ctx,
+		bson.M{
+			"_id":     hash,
+			"used_at": nil,
+		},
+		bson.M{
+			"$set": bson.M{
+				"used_at": usedAt,
+			},
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+// RevokeRefreshTokenFamily sets revoked date on every token of given family
+func (r *RefreshTokenRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID string, revokedAt time.Time) error {
+	collection := r.mongoDB.Collection(RefreshTokenCollection)
+
+	_, err := collection.UpdateMany(
+		ctx,
+		bson.M{
+			"family_id":  familyID,
+			"revoked_at": nil,
+		},
+		bson.M{
+			"$set": bson.M{
+				"revoked_at": revokedAt,
+			},
+		},
+	)
+	return err
+}
+
+// RevokeRefreshTokensByEmail sets revoked date on every token of given email
+func (r *RefreshTokenRepository) RevokeRefreshTokensByEmail(ctx context.Context, email string, revokedAt time.Time) error {
+	collection := r.mongoDB.Collection(RefreshTokenCollection)
+
+	_, err := collection.UpdateMany(
+		ctx,
+		bson.M{
+			"email":      email,
+			"revoked_at": nil,
+		},
+		bson.M{
+			"$set": bson.M{
+				"revoked_at": revokedAt,
+			},
+		},
+	)
+	return err
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/alamrios/crabi-solution/internal/app/auth"
+)
+
+// RevokedTokenCollection name of mongo collection
+const RevokedTokenCollection = "revoked_tokens"
+
+// RevokedTokenRepository struct for revoked tokens mongo repository
+type RevokedTokenRepository struct {
+	mongoDB *mongo.Database
+}
+
+// NewRevokedTokenRepository returns an instance of revoked tokens mongo repository
+func NewRevokedTokenRepository(mongoDB *mongo.Database) (*RevokedTokenRepository, error) {
+	if mongoDB == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	return &RevokedTokenRepository{
+		mongoDB: mongoDB,
+	}, nil
+}
+
+// EnsureIndexes creates TTL index so entries vanish once the token expires
+func (r *RevokedTokenRepository) EnsureIndexes(ctx context.Context) error {
+	collection := r.mongoDB.Collection(RevokedTokenCollection)
+
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+// SaveRevokedToken method, revoking the same token twice is not an error
+func (r *RevokedTokenRepository) SaveRevokedToken(ctx context.Context, token auth.RevokedToken) error {
+	collection := r.mongoDB.Collection(RevokedTokenCollection)
+
+	_, err := collection.ReplaceOne(
+		ctx,
+		bson.M{
+			"_id": token.JTI,
+		},
+		token,
+		options.Replace().SetUpsert(true),
+	)
+	return err
+}
+
+// InsertRevokedToken saves given token, returns false when a token with the
+// same jti was already revoked
+func (r *RevokedTokenRepository) InsertRevokedToken(ctx context.Context, token auth.RevokedToken) (bool, error) {
+	collection := r.mongoDB.Collection(RevokedTokenCollection)
+
+	_, err := collection.InsertOne(ctx, token)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// IsTokenRevoked reports whether a token with given jti is in mongo collection
+func (r *RevokedTokenRepository) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	collection := r.mongoDB.Collection(RevokedTokenCollection)
+
+	count, err := collection.CountDocuments(
+		ctx,
+		bson.M{
+			"_id": jti,
+		},
+		options.Count().SetLimit(1),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/alamrios/crabi-solution/internal/app/auth"
+)
+
+// SessionCollection name of mongo collection
+const SessionCollection = "sessions"
+
+// SessionRepository struct for login sessions mongo repository
+type SessionRepository struct {
+	mongoDB *mongo.Database
+}
+
+// NewSessionRepository returns an instance of login sessions mongo repository
+func NewSessionRepository(mongoDB *mongo.Database) (*SessionRepository, error) {
+	if mongoDB == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	return &SessionRepository{
+		mongoDB: mongoDB,
+	}, nil
+}
+
+// EnsureIndexes creates email lookup index and expiration TTL index
+func (r *SessionRepository) EnsureIndexes(ctx context.Context) error {
+	collection := r.mongoDB.Collection(SessionCollection)
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "email", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	return err
+}
+
+// SaveSession method
+func (r *SessionRepository) SaveSession(ctx context.Context, session auth.Session) error {
+	collection := r.mongoDB.Collection(SessionCollection)
+
+	_, err := collection.InsertOne(ctx, session)
+	return err
+}
+
+// GetSession returns unexpired session in mongo collection with given id
+func (r *SessionRepository) GetSession(ctx context.Context, id string) (*auth.Session, error) {
+	collection := r.mongoDB.Collection(SessionCollection)
+
+	query := collection.FindOne(
+		ctx,
+		bson.M{
+			"_id":        id,
+			"expires_at": bson.M{"$gt": time.Now().UTC()},
+		},
+	)
+
+	if query.Err() == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+
+	var session auth.Session
+	err := query.Decode(&session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// ListSessions returns unexpired sessions of given email, most recently seen first
+func (r *SessionRepository) ListSessions(ctx context.Context, email string) ([]auth.Session, error) {
+	collection := r.mongoDB.Collection(SessionCollection)
+
+	cursor, err := collection.Find(
+		ctx,
+		bson.M{
+			"email":      email,
+			"expires_at": bson.M{"$gt": time.Now().UTC()},
+		},
+		options.Find().SetSort(bson.D{{Key: "last_seen_at", Value: -1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := []auth.Session{}
+	err = cursor.All(ctx, &sessions)
+	if err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// UpdateSessionLastSeen sets last activity date of session with given id
+func (r *SessionRepository) UpdateSessionLastSeen(ctx context.Context, id string, lastSeenAt time.Time) error {
+	collection := r.mongoDB.Collection(SessionCollection)
+
+	_, err := collection.UpdateOne(
+		ctx,
+		bson.M{
+			"_id": id,
+		},
+		bson.M{
+			"$set": bson.M{
+				"last_seen_at": lastSeenAt,
+			},
+		},
+	)
+	return err
+}
+
+// ExtendSession sets last activity and expiration dates of session with given id
+func (r *SessionRepository) ExtendSession(ctx context.Context, id string, lastSeenAt, expiresAt time.Time) error {
+	collection := r.mongoDB.Collection(SessionCollection)
+
+	_, err := collection.UpdateOne(
+		ctx,
+		bson.M{
+			"_id": id,
+		},
+		bson.M{
+			"$set": bson.M{
+				"last_seen_at": lastSeenAt,
+				"expires_at":   expiresAt,
+			},
+		},
+	)
+	return err
+}
+
+// DeleteSession removes session with given id
+func (r *SessionRepository) DeleteSession(ctx context.Context, id string) error {
+	collection := r.mongoDB.Collection(SessionCollection)
+
+	_, err := collection.DeleteOne(
+		ctx,
+		bson.M{
+			"_id": id,
+		},
+	)
+	return err
+}
+
+// DeleteSessionsByEmail removes every session of given email
+func (r *SessionRepository) DeleteSessionsByEmail(ctx context.Context, email string) error {
+	collection := r.mongoDB.Collection(SessionCollection)
+
+	_, err := collection.DeleteMany(
+		ctx,
+		bson.M{
+			"email": email,
+		},
+	)
+	return err
+}
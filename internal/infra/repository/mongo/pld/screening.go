@@ -0,0 +1,90 @@
+package pld
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/alamrios/crabi-solution/internal/app/pld"
+)
+
+// ScreeningCollection name of mongo collection
+const ScreeningCollection = "pld_screenings"
+
+// ScreeningRepository struct for PLD screenings mongo repository
+type ScreeningRepository struct {
+	mongoDB *mongo.Database
+}
+
+// NewScreeningRepository returns an instance of PLD screenings mongo repository
+func NewScreeningRepository(mongoDB *mongo.Database) (*ScreeningRepository, error) {
+	if mongoDB == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	return &ScreeningRepository{
+		mongoDB: mongoDB,
+	}, nil
+}
+
+// EnsureIndexes creates user and email history lookup indexes
+func (r *ScreeningRepository) EnsureIndexes(ctx context.Context) error {
+	collection := r.mongoDB.Collection(ScreeningCollection)
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "checked_at", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "request.email", Value: 1}, {Key: "checked_at", Value: -1}},
+		},
+	})
+	return err
+}
+
+// SaveScreening method
+func (r *ScreeningRepository) SaveScreening(ctx context.Context, screening pld.Screening) error {
+	collection := r.mongoDB.Collection(ScreeningCollection)
+
+	_, err := collection.InsertOne(ctx, screening)
+	return err
+}
+
+// ListScreenings returns screenings of user with given id, newest first
+func (r *ScreeningRepository) ListScreenings(ctx context.Context, userID string) ([]pld.Screening, error) {
+	return r.listScreenings(ctx, bson.M{
+		"user_id": userID,
+	})
+}
+
+// ListScreeningsByEmail returns screenings of given email, newest first
+func (r *ScreeningRepository) ListScreeningsByEmail(ctx context.Context, email string) ([]pld.Screening, error) {
+	return r.listScreenings(ctx, bson.M{
+		"request.email": email,
+	})
+}
+
+// listScreenings returns screenings matching given filter, newest first
+func (r *ScreeningRepository) listScreenings(ctx context.Context, filter bson.M) ([]pld.Screening, error) {
+	collection := r.mongoDB.Collection(ScreeningCollection)
+
+	cursor, err := collection.Find(
+		ctx,
+		filter,
+		options.Find().SetSort(bson.D{{Key: "checked_at", Value: -1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	screenings := []pld.Screening{}
+	err = cursor.All(ctx, &screenings)
+	if err != nil {
+		return nil, err
+	}
+
+	return screenings, nil
+}
@@ -0,0 +1,116 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/alamrios/crabi-solution/internal/app/user"
+)
+
+// EmailVerificationCollection name of mongo collection
+const EmailVerificationCollection = "email_verification_tokens"
+
+// EmailVerificationRepository struct for email verification tokens mongo repository
+type EmailVerificationRepository struct {
+	mongoDB *mongo.Database
+}
+
+// NewEmailVerificationRepository returns an instance of email verification tokens mongo repository
+func NewEmailVerificationRepository(mongoDB *mongo.Database) (*EmailVerificationRepository, error) {
+	if mongoDB == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	return &EmailVerificationRepository{
+		mongoDB: mongoDB,
+	}, nil
+}
+
+// EnsureIndexes creates email lookup index and expiration TTL index
+func (r *EmailVerificationRepository) EnsureIndexes(ctx context.Context) error {
+	collection := r.mongoDB.Collection(EmailVerificationCollection)
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "email", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	return err
+}
+
+// SaveEmailVerificationToken method
+func (r *EmailVerificationRepository) SaveEmailVerificationToken(ctx context.Context, token user.EmailVerificationToken) error {
+	collection := r.mongoDB.Collection(EmailVerificationCollection)
+
+	_, err := collection.InsertOne(ctx, token)
+	return err
+}
+
+// GetEmailVerificationToken returns verification token in mongo collection with given hash
+func (r *EmailVerificationRepository) GetEmailVerificationToken(ctx context.Context, hash string) (*user.EmailVerificationToken, error) {
+	collection := r.mongoDB.Collection(EmailVerificationCollection)
+
+	query := collection.FindOne(
+		ctx,
+		bson.M{
+			"_id": hash,
+		},
+	)
+
+	if query.Err() == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+
+	var token user.EmailVerificationToken
+	err := query.Decode(&token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// MarkEmailVerificationTokenUsed sets used date only if token was not used before
+func (r *EmailVerificationRepository) MarkEmailVerificationTokenUsed(ctx context.Context, hash string, usedAt time.Time) (bool, error) {
+	collection := r.mongoDB.Collection(EmailVerificationCollection)
+
+	result, err := collection.UpdateOne(
+		ctx,
+		bson.M{
+			"_id":     hash,
+			"used_at": nil,
+		},
+		bson.M{
+			"$set": bson.M{
+				"used_at": usedAt,
+			},
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+// DeleteEmailVerificationTokens removes every verification token of given email
+func (r *EmailVerificationRepository) DeleteEmailVerificationTokens(ctx context.Context, email string) error {
+	collection := r.mongoDB.Collection(EmailVerificationCollection)
+
+	_, err := collection.DeleteMany(
+		ctx,
+		bson.M{
+			"email": email,
+		},
+	)
+	return err
+}
@@ -0,0 +1,192 @@
+package user
+
+import (
+	"context"
+	"regexp"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/alamrios/crabi-solution/internal/app/user"
+)
+
+// ListUsers returns up to limit users matching given filter in given order,
+// starting after given cursor when not nil. Pages are read by key ranges on
+// the sort field and email so every page uses the same index.
+func (r *Repository) ListUsers(ctx context.Context, filter user.Filter, sort user.Sort, after *user.ListCursor, limit int) ([]user.User, error) {
+	collection := r.mongoDB.Collection(ResourceCollection)
+
+	query := listFilter(filter)
+	if after != nil {
+		query = bson.M{
+			"$and": bson.A{query, afterCursor(sort, after)},
+		}
+	}
+
+	direction := 1
+	if sort.Descending {
+		direction = -1
+	}
+
+	cursor, err := collection.Find(
+		ctx,
+		query,
+		options.Find().
+			SetSort(bson.D{
+				{Key: string(sort.Field), Value: direction},
+				{Key: "email", Value: direction},
+			}).
+			SetLimit(int64(limit)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	users := []user.User{}
+	err = cursor.All(ctx, &users)
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// CountUsers returns the number of users matching given filter
+func (r *Repository) CountUsers(ctx context.Context, filter user.Filter) (int64, error) {
+	collection := r.mongoDB.Collection(ResourceCollection)
+
+	return collection.CountDocuments(ctx, listFilter(filter))
+}
+
+// MigrateCreatedAt sets creation date of users stored before it was
+// recorded from the timestamp of their object id, returns the number of
+// migrated users. Users already migrated by MigrateIDs got it then.
+func (r *Repository) MigrateCreatedAt(ctx context.Context) (int64, error) {
+	collection := r.mongoDB.Collection(ResourceCollection)
+
+	result, err := collection.UpdateMany(
+		ctx,
+		bson.M{
+			"created_at": bson.M{
+				"$exists": false,
+			},
+			"_id": bson.M{
+				"$type": "objectId",
+			},
+		},
+		mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{
+				"created_at": bson.M{"$toDate": "$_id"},
+			}}},
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// MigrateEmailDomain sets lower cased email domain of users stored before it
+// was recorded, returns the number of migrated users
+func (r *Repository) MigrateEmailDomain(ctx context.Context) (int64, error) {
+	collection := r.mongoDB.Collection(ResourceCollection)
+
+	result, err := collection.UpdateMany(
+		ctx,
+		bson.M{
+			"email_domain": bson.M{
+				"$exists": false,
+			},
+		},
+		mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{
+				"email_domain": bson.M{
+					"$toLower": bson.M{
+						"$arrayElemAt": bson.A{
+							bson.M{"$split": bson.A{"$email", "@"}},
+							-1,
+						},
+					},
+				},
+			}}},
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// listFilter returns query of users matching given filter, deleted users
+// never match
+func listFilter(filter user.Filter) bson.M {
+	query := bson.M{
+		"deleted_at": nil,
+	}
+
+	if filter.Email != "" {
+		query["email"] = filter.Email
+	}
+
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+
+	if filter.CreatedFrom != nil || filter.CreatedTo != nil {
+		createdAt := bson.M{}
+		if filter.CreatedFrom != nil {
+			createdAt["$gte"] = *filter.CreatedFrom
+		}
+		if filter.CreatedTo != nil {
+			createdAt["$lt"] = *filter.CreatedTo
+		}
+		query["created_at"] = createdAt
+	}
+
+	if filter.EmailDomain != "" {
+		query["email_domain"] = filter.EmailDomain
+	}
+
+	if filter.NamePrefix != "" {
+		// anchored case sensitive patterns are answered with index bounds
+		prefix := bson.M{"$regex": "^" + regexp.QuoteMeta(filter.NamePrefix)}
+		query["$or"] = bson.A{
+			bson.M{"first_name": prefix},
+			bson.M{"last_name": prefix},
+		}
+	}
+
+	return query
+}
+
+// afterCursor returns query of users sorted after given cursor
+func afterCursor(sort user.Sort, after *user.ListCursor) bson.M {
+	operator := "$gt"
+	if sort.Descending {
+		operator = "$lt"
+	}
+
+	var value interface{}
+	switch sort.Field {
+	case user.SortCreatedAt:
+		value = after.CreatedAt
+	case user.SortLastName:
+		value = after.LastName
+	default:
+		return bson.M{
+			"email": bson.M{operator: after.Email},
+		}
+	}
+
+	field := string(sort.Field)
+
+	return bson.M{
+		"$or": bson.A{
+			bson.M{field: bson.M{operator: value}},
+			bson.M{field: value, "email": bson.M{operator: after.Email}},
+		},
+	}
+}
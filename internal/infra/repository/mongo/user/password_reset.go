@@ -0,0 +1,135 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/alamrios/crabi-solution/internal/app/user"
+)
+
+// PasswordResetCollection name of mongo collection
+const PasswordResetCollection = "password_reset_tokens"
+
+// PasswordResetRepository struct for password reset tokens mongo repository
+type PasswordResetRepository struct {
+	mongoDB *mongo.Database
+}
+
+// NewPasswordResetRepository returns an instance of password reset tokens mongo repository
+func NewPasswordResetRepository(mongoDB *mongo.Database) (*PasswordResetRepository, error) {
+	if mongoDB == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	return &PasswordResetRepository{
+		mongoDB: mongoDB,
+	}, nil
+}
+
+// EnsureIndexes creates email lookup index and expiration TTL index
+func (r *PasswordResetRepository) EnsureIndexes(ctx context.Context) error {
+	collection := r.mongoDB.Collection(PasswordResetCollection)
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "email", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	return err
+}
+
+// SavePasswordResetToken method
+func (r *PasswordResetRepository) SavePasswordResetToken(ctx context.Context, token user.PasswordResetToken) error {
+	collection := r.mongoDB.Collection(PasswordResetCollection)
+
+	_, err := collection.InsertOne(ctx, token)
+	return err
+}
+
+// GetPasswordResetToken returns reset token in mongo collection with given hash
+func (r *PasswordResetRepository) GetPasswordResetToken(ctx context.Context, hash string) (*user.PasswordResetToken, error) {
+	collection := r.mongoDB.Collection(PasswordResetCollection)
+
+	query := collection.FindOne(
+		ctx,
+		bson.M{
+			"_id": hash,
+		},
+	)
+
+	if query.Err() == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+
+	var token user.PasswordResetToken
+	err := query.Decode(&token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// MarkPasswordResetTokenUsed sets used date only if token was not used before
+func (r *PasswordResetRepository) MarkPasswordResetTokenUsed(ctx context.Context, hash string, usedAt time.Time) (bool, error) {
+	collection := r.mongoDB.Collection(PasswordResetCollection)
+
+	result, err := collection.UpdateOne(
+		ctx,
+		bson.M{
+			"_id":     hash,
+			"used_at": nil,
+		},
+		bson.M{
+			"$set": bson.M{
+				"used_at": usedAt,
+			},
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+// UnmarkPasswordResetTokenUsed removes used date only if it is the given one
+func (r *PasswordResetRepository) UnmarkPasswordResetTokenUsed(ctx context.Context, hash string, usedAt time.Time) error {
+	collection := r.mongoDB.Collection(PasswordResetCollection)
+
+	_, err := collection.UpdateOne(
+		ctx,
+		bson.M{
+			"_id":     hash,
+			"used_at": usedAt,
+		},
+		bson.M{
+			"$unset": bson.M{
+				"used_at": "",
+			},
+		},
+	)
+	return err
+}
+
+// DeletePasswordResetTokens removes every reset token of given email
+func (r *PasswordResetRepository) DeletePasswordResetTokens(ctx context.Context, email string) error {
+	collection := r.mongoDB.Collection(PasswordResetCollection)
+
+	_, err := collection.DeleteMany(
+		ctx,
+		bson.M{
+			"email": email,
+		},
+	)
+	return err
+}
@@ -3,9 +3,12 @@ package user
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"github.com/alamrios/crabi-solution/internal/app/user"
 )
@@ -29,22 +32,600 @@ func New(mongoDB *mongo.Database) (*Repository, error) {
 	}, nil
 }
 
+// legacyIndexes names of former indexes replaced by indexes with the same
+// keys: the non unique email index and the sparse screening index, which
+// every user was indexed by since created_at is always set
+var legacyIndexes = []string{"email_1", "screening_1_created_at_1"}
+
+// EnsureIndexes creates unique email index, deleted date index used to purge
+// deleted users, screening index over users with a screening status used to
+// screen pending users and the
+// indexes backing user listing filters and sorts. Deleted users keep their
+// email until purged, so the email index covers them too. It should run
+// after MigrateIDs, which briefly stores two copies of each migrated user.
+func (r *Repository) EnsureIndexes(ctx context.Context) error {
+	collection := r.mongoDB.Collection(ResourceCollection)
+
+	err := r.dropLegacyIndexes(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "deleted_at", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+		{
+			Keys: bson.D{{Key: "screening", Value: 1}, {Key: "created_at", Value: 1}},
+			Options: options.Index().
+				SetName("screening_partial").
+				SetPartialFilterExpression(bson.M{"screening": bson.M{"$exists": true}}),
+		},
+		{
+			Keys:    bson.D{{Key: "email", Value: 1}},
+			Options: options.Index().SetName("email_unique").SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "created_at", Value: 1}, {Key: "email", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "last_name", Value: 1}, {Key: "email", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "first_name", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "email_domain", Value: 1}, {Key: "created_at", Value: 1}},
+		},
+	})
+	return err
+}
+
+// dropLegacyIndexes removes the legacy indexes that still exist, indexes
+// with the same keys can not be created while they exist
+func (r *Repository) dropLegacyIndexes(ctx context.Context) error {
+	collection := r.mongoDB.Collection(ResourceCollection)
+
+	cursor, err := collection.Indexes().List(ctx)
+	if err != nil {
+		return err
+	}
+
+	var indexes []bson.M
+	err = cursor.All(ctx, &indexes)
+	if err != nil {
+		return err
+	}
+
+	for _, index := range indexes {
+		for _, name := range legacyIndexes {
+			if index["name"] != name {
+				continue
+			}
+
+			_, err = collection.Indexes().DropOne(ctx, name)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
 // SaveUser method
-func (r *Repository) SaveUser(ctx context.Context, user user.User) error {
+func (r *Repository) SaveUser(ctx context.Context, newUser user.User) error {
 	collection := r.mongoDB.Collection(ResourceCollection)
 
-	_, err := collection.InsertOne(ctx, user)
+	_, err := collection.InsertOne(ctx, newUser)
+	if mongo.IsDuplicateKeyError(err) {
+		return user.ErrDuplicateEmail
+	}
 	return err
 }
 
-// GetUserByEmail returns user in mongo collection with given email
+// GetUserByID returns user in mongo collection with given id, deleted
+// users are not returned
+func (r *Repository) GetUserByID(ctx context.Context, id string) (*user.User, error) {
+	collection := r.mongoDB.Collection(ResourceCollection)
+
+	query := collection.FindOne(
+		ctx,
+		bson.M{
+			"_id":        id,
+			"deleted_at": nil,
+		},
+	)
+
+	if query.Err() == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+
+	var user user.User
+	err := query.Decode(&user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// GetUserByEmail returns user in mongo collection with given email,
+// deleted users are not returned
 func (r *Repository) GetUserByEmail(ctx context.Context, email string) (*user.User, error) {
 	collection := r.mongoDB.Collection(ResourceCollection)
 
 	query := collection.FindOne(
 		ctx,
 		bson.M{
-			"email": email,
+			"email":      email,
+			"deleted_at": nil,
+		},
+	)
+
+	if query.Err() == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+
+	var user user.User
+	err := query.Decode(&user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// UpdateUser replaces profile fields of user with the same email
+func (r *Repository) UpdateUser(ctx context.Context, user user.User) error {
+	collection := r.mongoDB.Collection(ResourceCollection)
+
+	_, err := collection.UpdateOne(
+		ctx,
+		bson.M{
+			"email":      user.Email,
+			"deleted_at": nil,
+		},
+		bson.M{
+			"$set": bson.M{
+				"first_name": user.FirstName,
+				"last_name":  user.LastName,
+			},
+		},
+	)
+	return err
+}
+
+// UpdatePassword replaces password hash of user with given email
+func (r *Repository) UpdatePassword(ctx context.Context, email, password string) error {
+	collection := r.mongoDB.Collection(ResourceCollection)
+
+	_, err := collection.UpdateOne(
+		ctx,
+		bson.M{
+			"email":      email,
+			"deleted_at": nil,
+		},
+		bson.M{
+			"$set": bson.M{
+				"password": password,
+			},
+		},
+	)
+	return err
+}
+
+// UpdatePasswordHistory replaces previous password hashes of user with given email
+func (r *Repository) UpdatePasswordHistory(ctx context.Context, email string, history []string) error {
+	collection := r.mongoDB.Collection(ResourceCollection)
+
+	_, err := collection.UpdateOne(
+		ctx,
+		bson.M{
+			"email":      email,
+			"deleted_at": nil,
+		},
+		bson.M{
+			"$set": bson.M{
+				"password_history": history,
+			},
+		},
+	)
+	return err
+}
+
+// UpdateTokensValidAfter sets date before which user's tokens are rejected
+func (r *Repository) UpdateTokensValidAfter(ctx context.Context, email string, validAfter time.Time) error {
+	collection := r.mongoDB.Collection(ResourceCollection)
+
+	_, err := collection.UpdateOne(
+		ctx,
+		bson.M{
+			"email":      email,
+			"deleted_at": nil,
+		},
+		bson.M{
+			"$set": bson.M{
+				"tokens_valid_after": validAfter,
+			},
+		},
+	)
+	return err
+}
+
+// UpdateLoginAttempts replaces failed login tracking of user with given email
+func (r *Repository) UpdateLoginAttempts(ctx context.Context, email string, attempts user.LoginAttempts) error {
+	collection := r.mongoDB.Collection(ResourceCollection)
+
+	_, err := collection.UpdateOne(
+		ctx,
+		bson.M{
+			"email":      email,
+			"deleted_at": nil,
+		},
+		bson.M{
+			"$set": bson.M{
+				"login_attempts": attempts,
+			},
+		},
+	)
+	return err
+}
+
+// IncrementLoginFailures atomically counts a failed login of user with given
+// email at given date, a new window is started when the current one began
+// before windowStart. Returns updated attempts, nil when the user does not
+// exist
+func (r *Repository) IncrementLoginFailures(ctx context.Context, email string, failedAt, windowStart time.Time) (*user.LoginAttempts, error) {
+	collection := r.mongoDB.Collection(ResourceCollection)
+	opts := options.FindOneAndUpdate().
+		SetReturnDocument(options.After).
+		SetProjection(bson.M{"login_attempts": 1})
+
+	// a concurrent failure may start a new window between both updates, in
+	// which case counting in the current window is tried again
+	for i := 0; i < 2; i++ {
+		query := collection.FindOneAndUpdate(
+			ctx,
+			bson.M{
+				"email":                       email,
+				"deleted_at":                  nil,
+				"login_attempts.window_start": bson.M{"$gte": windowStart},
+			},
+			bson.M{
+				"$inc": bson.M{"login_attempts.failed": 1},
+				"$set": bson.M{"login_attempts.last_failed": failedAt},
+			},
+			opts,
+		)
+		attempts, err := decodeLoginAttempts(query)
+		if err != nil || attempts != nil {
+			return attempts, err
+		}
+
+		query = collection.FindOneAndUpdate(
+			ctx,
+			bson.M{
+				"email":      email,
+				"deleted_at": nil,
+				"$or": []bson.M{
+					{"login_attempts.window_start": nil},
+					{"login_attempts.window_start": bson.M{"$lt": windowStart}},
+				},
+			},
+			bson.M{
+				"$set": bson.M{
+					"login_attempts.failed":       1,
+					"login_attempts.window_start": failedAt,
+					"login_attempts.last_failed":  failedAt,
+				},
+				"$unset": bson.M{"login_attempts.locked_until": ""},
+			},
+			opts,
+		)
+		attempts, err = decodeLoginAttempts(query)
+		if err != nil || attempts != nil {
+			return attempts, err
+		}
+	}
+
+	return nil, nil
+}
+
+// LockLogin locks login of user with given email until given date and
+// starts a new attempts window, only while at least maxAttempts failures are
+// counted so concurrent lockings apply once
+func (r *Repository) LockLogin(ctx context.Context, email string, maxAttempts int, lockedUntil time.Time) error {
+	collection := r.mongoDB.Collection(ResourceCollection)
+
+	_, err := collection.UpdateOne(
+		ctx,
+		bson.M{
+			"email":                 email,
+			"deleted_at":            nil,
+			"login_attempts.failed": bson.M{"$gte": maxAttempts},
+		},
+		bson.M{
+			"$set": bson.M{
+				"login_attempts.failed":       0,
+				"login_attempts.locked_until": lockedUntil,
+			},
+			"$unset": bson.M{"login_attempts.window_start": ""},
+		},
+	)
+	return err
+}
+
+// decodeLoginAttempts returns login attempts of user found by given query,
+// nil when nothing matched
+func decodeLoginAttempts(query *mongo.SingleResult) (*user.LoginAttempts, error) {
+	if query.Err() == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+
+	var found user.User
+	err := query.Decode(&found)
+	if err != nil {
+		return nil, err
+	}
+
+	return &found.LoginAttempts, nil
+}
+
+// UpdateStatus sets account status of user with given email
+func (r *Repository) UpdateStatus(ctx context.Context, email string, status user.Status) error {
+	collection := r.mongoDB.Collection(ResourceCollection)
+
+	_, err := collection.UpdateOne(
+		ctx,
+		bson.M{
+			"email":      email,
+			"deleted_at": nil,
+		},
+		bson.M{
+			"$set": bson.M{
+				"status": status,
+			},
+		},
+	)
+	return err
+}
+
+// UpdateScreening sets PLD screening status of user with given email, empty
+// status removes it
+func (r *Repository) UpdateScreening(ctx context.Context, email string, screening user.ScreeningStatus) error {
+	collection := r.mongoDB.Collection(ResourceCollection)
+
+	update := bson.M{
+		"$set": bson.M{
+			"screening": screening,
+		},
+	}
+	if screening == "" {
+		update = bson.M{
+			"$unset": bson.M{
+				"screening": "",
+			},
+		}
+	}
+
+	_, err := collection.UpdateOne(
+		ctx,
+		bson.M{
+			"email":      email,
+			"deleted_at": nil,
+		},
+		update,
+	)
+	return err
+}
+
+// GetUsersByScreening returns up to limit users with given screening status,
+// oldest first, deleted users are not returned
+func (r *Repository) GetUsersByScreening(ctx context.Context, screening user.ScreeningStatus, limit int) ([]user.User, error) {
+	collection := r.mongoDB.Collection(ResourceCollection)
+
+	cursor, err := collection.Find(
+		ctx,
+		bson.M{
+			"screening":  screening,
+			"deleted_at": nil,
+		},
+		options.Find().
+			SetSort(bson.D{{Key: "created_at", Value: 1}}).
+			SetLimit(int64(limit)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	users := []user.User{}
+	err = cursor.All(ctx, &users)
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// MigrateStatus marks users stored before account status existed as
+// active, returns the number of migrated users
+func (r *Repository) MigrateStatus(ctx context.Context) (int64, error) {
+	collection := r.mongoDB.Collection(ResourceCollection)
+
+	result, err := collection.UpdateMany(
+		ctx,
+		bson.M{
+			"status": bson.M{
+				"$exists": false,
+			},
+		},
+		bson.M{
+			"$set": bson.M{
+				"status": user.StatusActive,
+			},
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// UpdateMFA replaces two-factor authentication settings of user with given email
+func (r *Repository) UpdateMFA(ctx context.Context, email string, mfa user.MFA) error {
+	collection := r.mongoDB.Collection(ResourceCollection)
+
+	_, err := collection.UpdateOne(
+		ctx,
+		bson.M{
+			"email":      email,
+			"deleted_at": nil,
+		},
+		bson.M{
+			"$set": bson.M{
+				"mfa": mfa,
+			},
+		},
+	)
+	return err
+}
+
+// UseMFAStep records given TOTP time step as used by user with given email,
+// returns false when it or a later step was already used
+func (r *Repository) UseMFAStep(ctx context.Context, email string, step int64) (bool, error) {
+	collection := r.mongoDB.Collection(ResourceCollection)
+
+	result, err := collection.UpdateOne(
+		ctx,
+		bson.M{
+			"email":       email,
+			"deleted_at":  nil,
+			"mfa.enabled": true,
+			"$or": []bson.M{
+				{"mfa.last_used_step": nil},
+				{"mfa.last_used_step": bson.M{"$lt": step}},
+			},
+		},
+		bson.M{
+			"$set": bson.M{
+				"mfa.last_used_step": step,
+			},
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+// UseMFARecoveryCode removes recovery code with given hash of user with given
+// email, returns false when it was already used
+func (r *Repository) UseMFARecoveryCode(ctx context.Context, email, hash string) (bool, error) {
+	collection := r.mongoDB.Collection(ResourceCollection)
+
+	result, err := collection.UpdateOne(
+		ctx,
+		bson.M{
+			"email":              email,
+			"deleted_at":         nil,
+			"mfa.enabled":        true,
+			"mfa.recovery_codes": hash,
+		},
+		bson.M{
+			"$pull": bson.M{
+				"mfa.recovery_codes": hash,
+			},
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+// UpdateRole sets role of user with given email
+func (r *Repository) UpdateRole(ctx context.Context, email string, role user.Role) error {
+	collection := r.mongoDB.Collection(ResourceCollection)
+
+	_, err := collection.UpdateOne(
+		ctx,
+		bson.M{
+			"email":      email,
+			"deleted_at": nil,
+		},
+		bson.M{
+			"$set": bson.M{
+				"role": role,
+			},
+		},
+	)
+	return err
+}
+
+// MigrateRole gives customer role to users stored before roles existed,
+// returns the number of migrated users
+func (r *Repository) MigrateRole(ctx context.Context) (int64, error) {
+	collection := r.mongoDB.Collection(ResourceCollection)
+
+	result, err := collection.UpdateMany(
+		ctx,
+		bson.M{
+			"role": bson.M{
+				"$exists": false,
+			},
+		},
+		bson.M{
+			"$set": bson.M{
+				"role": user.RoleCustomer,
+			},
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// DeleteUser flags user with given email as deleted on given date
+func (r *Repository) DeleteUser(ctx context.Context, email string, deletedAt time.Time) error {
+	collection := r.mongoDB.Collection(ResourceCollection)
+
+	_, err := collection.UpdateOne(
+		ctx,
+		bson.M{
+			"email":      email,
+			"deleted_at": nil,
+		},
+		bson.M{
+			"$set": bson.M{
+				"deleted_at": deletedAt,
+			},
+		},
+	)
+	return err
+}
+
+// GetDeletedUserByID returns soft deleted user in mongo collection with
+// given id
+func (r *Repository) GetDeletedUserByID(ctx context.Context, id string) (*user.User, error) {
+	collection := r.mongoDB.Collection(ResourceCollection)
+
+	query := collection.FindOne(
+		ctx,
+		bson.M{
+			"_id": id,
+			"deleted_at": bson.M{
+				"$ne": nil,
+			},
 		},
 	)
 
@@ -61,14 +642,18 @@ func (r *Repository) GetUserByEmail(ctx context.Context, email string) (*user.Us
 	return &user, nil
 }
 
-func (r *Repository) GetUserByEmailAndPassword(ctx context.Context, email, password string) (*user.User, error) {
+// GetDeletedUserByEmail returns soft deleted user in mongo collection with
+// given email
+func (r *Repository) GetDeletedUserByEmail(ctx context.Context, email string) (*user.User, error) {
 	collection := r.mongoDB.Collection(ResourceCollection)
 
 	query := collection.FindOne(
 		ctx,
 		bson.M{
-			"email":    email,
-			"password": password,
+			"email": email,
+			"deleted_at": bson.M{
+				"$ne": nil,
+			},
 		},
 	)
 
@@ -84,3 +669,114 @@ func (r *Repository) GetUserByEmailAndPassword(ctx context.Context, email, passw
 
 	return &user, nil
 }
+
+// RestoreUser clears the deleted flag of user with given id
+func (r *Repository) RestoreUser(ctx context.Context, id string) error {
+	collection := r.mongoDB.Collection(ResourceCollection)
+
+	_, err := collection.UpdateOne(
+		ctx,
+		bson.M{
+			"_id": id,
+			"deleted_at": bson.M{
+				"$ne": nil,
+			},
+		},
+		bson.M{
+			"$unset": bson.M{
+				"deleted_at": "",
+			},
+		},
+	)
+	return err
+}
+
+// PurgeUsers permanently removes users deleted before given date, returns
+// the number of removed users
+func (r *Repository) PurgeUsers(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	collection := r.mongoDB.Collection(ResourceCollection)
+
+	result, err := collection.DeleteMany(
+		ctx,
+		bson.M{
+			"deleted_at": bson.M{
+				"$lt": deletedBefore,
+			},
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
+
+// MigrateIDs replaces the object id of users stored before user ids existed
+// with their legacy user id, returns the number of migrated users. Each
+// user is copied under its new id before the old document is removed, the
+// copy is skipped when a previous interrupted run already made it. It
+// requires a single instance running it before serving requests and before
+// EnsureIndexes creates the unique email index, and it should run after the
+// other migrations since they may read the object id.
+func (r *Repository) MigrateIDs(ctx context.Context) (int64, error) {
+	collection := r.mongoDB.Collection(ResourceCollection)
+
+	cursor, err := collection.Find(
+		ctx,
+		bson.M{
+			"_id": bson.M{
+				"$type": "objectId",
+			},
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var migrated int64
+	for cursor.Next(ctx) {
+		var document bson.M
+		err = cursor.Decode(&document)
+		if err != nil {
+			return migrated, err
+		}
+
+		objectID, ok := migrateID(document)
+		if !ok {
+			continue
+		}
+
+		_, err = collection.InsertOne(ctx, document)
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			return migrated, err
+		}
+
+		_, err = collection.DeleteOne(ctx, bson.M{"_id": objectID})
+		if err != nil {
+			return migrated, err
+		}
+
+		migrated++
+	}
+
+	return migrated, cursor.Err()
+}
+
+// migrateID replaces the object id of given user document with its legacy
+// user id, returns the replaced object id, false when the document has none.
+// The creation date, only kept by the object id until then, is set when
+// missing.
+func migrateID(document bson.M) (primitive.ObjectID, bool) {
+	objectID, ok := document["_id"].(primitive.ObjectID)
+	if !ok {
+		return objectID, false
+	}
+
+	document["_id"] = user.LegacyID(objectID[:])
+	if _, ok := document["created_at"]; !ok {
+		document["created_at"] = objectID.Timestamp().UTC()
+	}
+
+	return objectID, true
+}
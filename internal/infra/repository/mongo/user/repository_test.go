@@ -0,0 +1,58 @@
+package user
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/alamrios/crabi-solution/internal/app/user"
+)
+
+func TestMigrateID(t *testing.T) {
+	createdAt := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	objectID := primitive.NewObjectIDFromTimestamp(createdAt)
+	keptCreatedAt := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := map[string]struct {
+		document bson.M
+		migrated bool
+		expected bson.M
+	}{
+		"user without creation date should get the object id timestamp": {
+			document: bson.M{"_id": objectID, "email": "dua@lipa.com"},
+			migrated: true,
+			expected: bson.M{
+				"_id":        user.LegacyID(objectID[:]),
+				"email":      "dua@lipa.com",
+				"created_at": createdAt,
+			},
+		},
+		"user with creation date should keep it": {
+			document: bson.M{"_id": objectID, "email": "dua@lipa.com", "created_at": keptCreatedAt},
+			migrated: true,
+			expected: bson.M{
+				"_id":        user.LegacyID(objectID[:]),
+				"email":      "dua@lipa.com",
+				"created_at": keptCreatedAt,
+			},
+		},
+		"migrated user should be skipped": {
+			document: bson.M{"_id": "3f2b8c1e-5d4a-4e6f-9b7c-2a1d0e8f6c5b", "email": "dua@lipa.com"},
+			expected: bson.M{"_id": "3f2b8c1e-5d4a-4e6f-9b7c-2a1d0e8f6c5b", "email": "dua@lipa.com"},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got, migrated := migrateID(tc.document)
+			assert.Equal(t, tc.migrated, migrated)
+			if migrated {
+				assert.Equal(t, objectID, got)
+			}
+			assert.Equal(t, tc.expected, tc.document)
+		})
+	}
+}
@@ -0,0 +1,63 @@
+// Source: internal/app/auth/repository.go
+package mock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/alamrios/crabi-solution/internal/app/auth"
+)
+
+// APIKeyRepository is a mock of APIKeyRepository interface
+type APIKeyRepository struct {
+	mock.Mock
+}
+
+// NewAPIKeyRepository creates new api key mock repository
+func NewAPIKeyRepository() *APIKeyRepository {
+	return &APIKeyRepository{}
+}
+
+// AddCall adds new call to the mock
+func (m *APIKeyRepository) AddCall(t *testing.T, calls []Call) *APIKeyRepository {
+	t.Helper()
+
+	for _, call := range calls {
+		call.on(&m.Mock)
+	}
+
+	return m
+}
+
+// SaveAPIKey method mock
+func (m *APIKeyRepository) SaveAPIKey(_ context.Context, apiKey auth.APIKey) error {
+	args := m.Called(apiKey)
+	return args.Error(0)
+}
+
+// GetAPIKey method mock
+func (m *APIKeyRepository) GetAPIKey(_ context.Context, id string) (*auth.APIKey, error) {
+	args := m.Called(id)
+	return args.Get(0).(*auth.APIKey), args.Error(1)
+}
+
+// ListAPIKeys method mock
+func (m *APIKeyRepository) ListAPIKeys(_ context.Context) ([]auth.APIKey, error) {
+	args := m.Called()
+	return args.Get(0).([]auth.APIKey), args.Error(1)
+}
+
+// RevokeAPIKey method mock
+func (m *APIKeyRepository) RevokeAPIKey(_ context.Context, id string, revokedAt time.Time) error {
+	args := m.Called(id, revokedAt)
+	return args.Error(0)
+}
+
+// UpdateAPIKeyLastUsed method mock
+func (m *APIKeyRepository) UpdateAPIKeyLastUsed(_ context.Context, id string, usedAt time.Time) error {
+	args := m.Called(id, usedAt)
+	return args.Error(0)
+}
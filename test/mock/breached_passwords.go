@@ -0,0 +1,35 @@
+// Source: internal/app/user/password_policy.go
+package mock
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// BreachedPasswords is a mock of BreachedPasswords interface
+type BreachedPasswords struct {
+	mock.Mock
+}
+
+// NewBreachedPasswords creates new breached passwords mock
+func NewBreachedPasswords() *BreachedPasswords {
+	return &BreachedPasswords{}
+}
+
+// AddCall adds new call to the mock
+func (m *BreachedPasswords) AddCall(t *testing.T, calls []Call) *BreachedPasswords {
+	t.Helper()
+
+	for _, call := range calls {
+		call.on(&m.Mock)
+	}
+
+	return m
+}
+
+// Contains method mock
+func (m *BreachedPasswords) Contains(password string) (bool, error) {
+	args := m.Called(password)
+	return args.Bool(0), args.Error(1)
+}
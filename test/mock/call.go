@@ -1,8 +1,22 @@
 package mock
 
+import (
+	"github.com/stretchr/testify/mock"
+)
+
 // Call struct for mock call
 type Call struct {
 	FunctionName string
 	Params       []interface{}
 	Returns      []interface{}
+	// Times number of calls answered with Returns, every call when zero
+	Times int
+}
+
+// on sets up given mock to expect call
+func (c Call) on(m *mock.Mock) {
+	expectation := m.On(c.FunctionName, c.Params...).Return(c.Returns...)
+	if c.Times > 0 {
+		expectation.Times(c.Times)
+	}
 }
@@ -0,0 +1,51 @@
+// Source: internal/app/auth/repository.go
+package mock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/alamrios/crabi-solution/internal/app/auth"
+)
+
+// ClientRepository is a mock of ClientRepository interface
+type ClientRepository struct {
+	mock.Mock
+}
+
+// NewClientRepository creates new oauth client mock repository
+func NewClientRepository() *ClientRepository {
+	return &ClientRepository{}
+}
+
+// AddCall adds new call to the mock
+func (m *ClientRepository) AddCall(t *testing.T, calls []Call) *ClientRepository {
+	t.Helper()
+
+	for _, call := range calls {
+		call.on(&m.Mock)
+	}
+
+	return m
+}
+
+// SaveClient method mock
+func (m *ClientRepository) SaveClient(_ context.Context, client auth.Client) error {
+	args := m.Called(client)
+	return args.Error(0)
+}
+
+// GetClient method mock
+func (m *ClientRepository) GetClient(_ context.Context, id string) (*auth.Client, error) {
+	args := m.Called(id)
+	return args.Get(0).(*auth.Client), args.Error(1)
+}
+
+// DisableClient method mock
+func (m *ClientRepository) DisableClient(_ context.Context, id string, disabledAt time.Time) error {
+	args := m.Called(id, disabledAt)
+	return args.Error(0)
+}
@@ -0,0 +1,57 @@
+// Source: internal/app/user/repository.go
+package mock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/alamrios/crabi-solution/internal/app/user"
+)
+
+// EmailVerificationRepository is a mock of EmailVerificationRepository interface
+type EmailVerificationRepository struct {
+	mock.Mock
+}
+
+// NewEmailVerificationRepository creates new email verification mock repository
+func NewEmailVerificationRepository() *EmailVerificationRepository {
+	return &EmailVerificationRepository{}
+}
+
+// AddCall adds new call to the mock
+func (m *EmailVerificationRepository) AddCall(t *testing.T, calls []Call) *EmailVerificationRepository {
+	t.Helper()
+
+	for _, call := range calls {
+		call.on(&m.Mock)
+	}
+
+	return m
+}
+
+// SaveEmailVerificationToken method mock
+func (m *EmailVerificationRepository) SaveEmailVerificationToken(_ context.Context, token user.EmailVerificationToken) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+// GetEmailVerificationToken method mock
+func (m *EmailVerificationRepository) GetEmailVerificationToken(_ context.Context, hash string) (*user.EmailVerificationToken, error) {
+	args := m.Called(hash)
+	return args.Get(0).(*user.EmailVerificationToken), args.Error(1)
+}
+
+// MarkEmailVerificationTokenUsed method mock
+func (m *EmailVerificationRepository) MarkEmailVerificationTokenUsed(_ context.Context, hash string, usedAt time.Time) (bool, error) {
+	args := m.Called(hash, usedAt)
+	return args.Bool(0), args.Error(1)
+}
+
+// DeleteEmailVerificationTokens method mock
+func (m *EmailVerificationRepository) DeleteEmailVerificationTokens(_ context.Context, email string) error {
+	args := m.Called(email)
+	return args.Error(0)
+}
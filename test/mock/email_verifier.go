@@ -0,0 +1,36 @@
+// Source: internal/app/user/email_verification.go
+package mock
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// EmailVerifier is a mock of EmailVerifier interface
+type EmailVerifier struct {
+	mock.Mock
+}
+
+// NewEmailVerifier creates new email verifier mock
+func NewEmailVerifier() *EmailVerifier {
+	return &EmailVerifier{}
+}
+
+// AddCall adds new call to the mock
+func (m *EmailVerifier) AddCall(t *testing.T, calls []Call) *EmailVerifier {
+	t.Helper()
+
+	for _, call := range calls {
+		call.on(&m.Mock)
+	}
+
+	return m
+}
+
+// SendVerification method mock
+func (m *EmailVerifier) SendVerification(_ context.Context, email string) error {
+	args := m.Called(email)
+	return args.Error(0)
+}
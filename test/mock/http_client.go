@@ -1,6 +1,7 @@
 package mock
 
 import (
+	"context"
 	"net/http"
 	"testing"
 
@@ -22,14 +23,14 @@ func (m *HttpClient) AddCall(t *testing.T, calls []Call) *HttpClient {
 	t.Helper()
 
 	for _, call := range calls {
-		m.On(call.FunctionName, call.Params...).Return(call.Returns...)
+		call.on(&m.Mock)
 	}
 
 	return m
 }
 
 // Post method mock
-func (m *HttpClient) Post(URL string, _ []byte, contentType string) (*http.Response, error) {
+func (m *HttpClient) Post(_ context.Context, URL string, _ []byte, contentType string) (*http.Response, error) {
 	args := m.Called(URL, contentType)
 	return args.Get(0).(*http.Response), args.Error(1)
 }
@@ -0,0 +1,42 @@
+// Source: internal/app/user/notifier.go
+package mock
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// Notifier is a mock of Notifier interface
+type Notifier struct {
+	mock.Mock
+}
+
+// NewNotifier creates new notifier mock
+func NewNotifier() *Notifier {
+	return &Notifier{}
+}
+
+// AddCall adds new call to the mock
+func (m *Notifier) AddCall(t *testing.T, calls []Call) *Notifier {
+	t.Helper()
+
+	for _, call := range calls {
+		call.on(&m.Mock)
+	}
+
+	return m
+}
+
+// SendPasswordReset method mock
+func (m *Notifier) SendPasswordReset(_ context.Context, email, token string) error {
+	args := m.Called(email, token)
+	return args.Error(0)
+}
+
+// SendEmailVerification method mock
+func (m *Notifier) SendEmailVerification(_ context.Context, email, token string) error {
+	args := m.Called(email, token)
+	return args.Error(0)
+}
@@ -0,0 +1,53 @@
+// Source: internal/app/user/hasher.go
+package mock
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// PasswordHasher is a mock of PasswordHasher interface
+type PasswordHasher struct {
+	mock.Mock
+}
+
+// NewPasswordHasher creates new password hasher mock
+func NewPasswordHasher() *PasswordHasher {
+	return &PasswordHasher{}
+}
+
+// AddCall adds new call to the mock
+func (m *PasswordHasher) AddCall(t *testing.T, calls []Call) *PasswordHasher {
+	t.Helper()
+
+	for _, call := range calls {
+		call.on(&m.Mock)
+	}
+
+	return m
+}
+
+// Hash method mock
+func (m *PasswordHasher) Hash(password string) (string, error) {
+	args := m.Called(password)
+	return args.String(0), args.Error(1)
+}
+
+// Verify method mock
+func (m *PasswordHasher) Verify(password, encoded string) (bool, error) {
+	args := m.Called(password, encoded)
+	return args.Bool(0), args.Error(1)
+}
+
+// Supports method mock
+func (m *PasswordHasher) Supports(encoded string) bool {
+	args := m.Called(encoded)
+	return args.Bool(0)
+}
+
+// NeedsRehash method mock
+func (m *PasswordHasher) NeedsRehash(encoded string) bool {
+	args := m.Called(encoded)
+	return args.Bool(0)
+}
@@ -0,0 +1,63 @@
+// Source: internal/app/user/repository.go
+package mock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/alamrios/crabi-solution/internal/app/user"
+)
+
+// PasswordResetRepository is a mock of PasswordResetRepository interface
+type PasswordResetRepository struct {
+	mock.Mock
+}
+
+// NewPasswordResetRepository creates new password reset mock repository
+func NewPasswordResetRepository() *PasswordResetRepository {
+	return &PasswordResetRepository{}
+}
+
+// AddCall adds new call to the mock
+func (m *PasswordResetRepository) AddCall(t *testing.T, calls []Call) *PasswordResetRepository {
+	t.Helper()
+
+	for _, call := range calls {
+		call.on(&m.Mock)
+	}
+
+	return m
+}
+
+// SavePasswordResetToken method mock
+func (m *PasswordResetRepository) SavePasswordResetToken(_ context.Context, token user.PasswordResetToken) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+// GetPasswordResetToken method mock
+func (m *PasswordResetRepository) GetPasswordResetToken(_ context.Context, hash string) (*user.PasswordResetToken, error) {
+	args := m.Called(hash)
+	return args.Get(0).(*user.PasswordResetToken), args.Error(1)
+}
+
+// MarkPasswordResetTokenUsed method mock
+func (m *PasswordResetRepository) MarkPasswordResetTokenUsed(_ context.Context, hash string, usedAt time.Time) (bool, error) {
+	args := m.Called(hash, usedAt)
+	return args.Bool(0), args.Error(1)
+}
+
+// UnmarkPasswordResetTokenUsed method mock
+func (m *PasswordResetRepository) UnmarkPasswordResetTokenUsed(_ context.Context, hash string, usedAt time.Time) error {
+	args := m.Called(hash, usedAt)
+	return args.Error(0)
+}
+
+// DeletePasswordResetTokens method mock
+func (m *PasswordResetRepository) DeletePasswordResetTokens(_ context.Context, email string) error {
+	args := m.Called(email)
+	return args.Error(0)
+}
@@ -25,14 +25,14 @@ func (m *PLDService) AddCall(t *testing.T, calls []Call) *PLDService {
 	t.Helper()
 
 	for _, call := range calls {
-		m.On(call.FunctionName, call.Params...).Return(call.Returns...)
+		call.on(&m.Mock)
 	}
 
 	return m
 }
 
 // CheckBlacklist method mock
-func (m *PLDService) CheckBlacklist(_ context.Context, request pld.Request) error {
+func (m *PLDService) CheckBlacklist(_ context.Context, request pld.Request) (*pld.ScreeningResult, error) {
 	args := m.Called(request)
-	return args.Error(0)
+	return args.Get(0).(*pld.ScreeningResult), args.Error(1)
 }
@@ -0,0 +1,63 @@
+// Source: internal/app/auth/repository.go
+package mock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/alamrios/crabi-solution/internal/app/auth"
+)
+
+// RefreshTokenRepository is a mock of RefreshTokenRepository interface
+type RefreshTokenRepository struct {
+	mock.Mock
+}
+
+// NewRefreshTokenRepository creates new refresh token mock repository
+func NewRefreshTokenRepository() *RefreshTokenRepository {
+	return &RefreshTokenRepository{}
+}
+
+// AddCall adds new call to the mock
+func (m *RefreshTokenRepository) AddCall(t *testing.T, calls []Call) *RefreshTokenRepository {
+	t.Helper()
+
+	for _, call := range calls {
+		call.on(&m.Mock)
+	}
+
+	return m
+}
+
+// SaveRefreshToken method mock
+func (m *RefreshTokenRepository) SaveRefreshToken(_ context.Context, token auth.RefreshToken) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+// GetRefreshToken method mock
+func (m *RefreshTokenRepository) GetRefreshToken(_ context.Context, hash string) (*auth.RefreshToken, error) {
+	args := m.Called(hash)
+	return args.Get(0).(*auth.RefreshToken), args.Error(1)
+}
+
+// MarkRefreshTokenUsed method mock
+func (m *RefreshTokenRepository) MarkRefreshTokenUsed(_ context.Context, hash string, usedAt time.Time) (bool, error) {
+	args := m.Called(hash, usedAt)
+	return args.Bool(0), args.Error(1)
+}
+
+// RevokeRefreshTokenFamily method mock
+func (m *RefreshTokenRepository) RevokeRefreshTokenFamily(_ context.Context, familyID string, revokedAt time.Time) error {
+	args := m.Called(familyID, revokedAt)
+	return args.Error(0)
+}
+
+// RevokeRefreshTokensByEmail method mock
+func (m *RefreshTokenRepository) RevokeRefreshTokensByEmail(_ context.Context, email string, revokedAt time.Time) error {
+	args := m.Called(email, revokedAt)
+	return args.Error(0)
+}
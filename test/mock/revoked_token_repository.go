@@ -0,0 +1,50 @@
+// Source: internal/app/auth/repository.go
+package mock
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/alamrios/crabi-solution/internal/app/auth"
+)
+
+// RevokedTokenRepository is a mock of RevokedTokenRepository interface
+type RevokedTokenRepository struct {
+	mock.Mock
+}
+
+// NewRevokedTokenRepository creates new revoked token mock repository
+func NewRevokedTokenRepository() *RevokedTokenRepository {
+	return &RevokedTokenRepository{}
+}
+
+// AddCall adds new call to the mock
+func (m *RevokedTokenRepository) AddCall(t *testing.T, calls []Call) *RevokedTokenRepository {
+	t.Helper()
+
+	for _, call := range calls {
+		call.on(&m.Mock)
+	}
+
+	return m
+}
+
+// SaveRevokedToken method mock
+func (m *RevokedTokenRepository) SaveRevokedToken(_ context.Context, token auth.RevokedToken) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+// InsertRevokedToken method mock
+func (m *RevokedTokenRepository) InsertRevokedToken(_ context.Context, token auth.RevokedToken) (bool, error) {
+	args := m.Called(token)
+	return args.Bool(0), args.Error(1)
+}
+
+// IsTokenRevoked method mock
+func (m *RevokedTokenRepository) IsTokenRevoked(_ context.Context, jti string) (bool, error) {
+	args := m.Called(jti)
+	return args.Bool(0), args.Error(1)
+}
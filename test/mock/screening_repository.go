@@ -0,0 +1,50 @@
+// Source: internal/app/pld/screening.go
+package mock
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/alamrios/crabi-solution/internal/app/pld"
+)
+
+// ScreeningRepository is a mock of ScreeningRepository interface
+type ScreeningRepository struct {
+	mock.Mock
+}
+
+// NewScreeningRepository creates new screening mock repository
+func NewScreeningRepository() *ScreeningRepository {
+	return &ScreeningRepository{}
+}
+
+// AddCall adds new call to the mock
+func (m *ScreeningRepository) AddCall(t *testing.T, calls []Call) *ScreeningRepository {
+	t.Helper()
+
+	for _, call := range calls {
+		call.on(&m.Mock)
+	}
+
+	return m
+}
+
+// SaveScreening method mock
+func (m *ScreeningRepository) SaveScreening(_ context.Context, screening pld.Screening) error {
+	args := m.Called(screening)
+	return args.Error(0)
+}
+
+// ListScreenings method mock
+func (m *ScreeningRepository) ListScreenings(_ context.Context, userID string) ([]pld.Screening, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]pld.Screening), args.Error(1)
+}
+
+// ListScreeningsByEmail method mock
+func (m *ScreeningRepository) ListScreeningsByEmail(_ context.Context, email string) ([]pld.Screening, error) {
+	args := m.Called(email)
+	return args.Get(0).([]pld.Screening), args.Error(1)
+}
@@ -0,0 +1,75 @@
+// Source: internal/app/auth/repository.go
+package mock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/alamrios/crabi-solution/internal/app/auth"
+)
+
+// SessionRepository is a mock of SessionRepository interface
+type SessionRepository struct {
+	mock.Mock
+}
+
+// NewSessionRepository creates new session mock repository
+func NewSessionRepository() *SessionRepository {
+	return &SessionRepository{}
+}
+
+// AddCall adds new call to the mock
+func (m *SessionRepository) AddCall(t *testing.T, calls []Call) *SessionRepository {
+	t.Helper()
+
+	for _, call := range calls {
+		call.on(&m.Mock)
+	}
+
+	return m
+}
+
+// SaveSession method mock
+func (m *SessionRepository) SaveSession(_ context.Context, session auth.Session) error {
+	args := m.Called(session)
+	return args.Error(0)
+}
+
+// GetSession method mock
+func (m *SessionRepository) GetSession(_ context.Context, id string) (*auth.Session, error) {
+	args := m.Called(id)
+	return args.Get(0).(*auth.Session), args.Error(1)
+}
+
+// ListSessions method mock
+func (m *SessionRepository) ListSessions(_ context.Context, email string) ([]auth.Session, error) {
+	args := m.Called(email)
+	return args.Get(0).([]auth.Session), args.Error(1)
+}
+
+// UpdateSessionLastSeen method mock
+func (m *SessionRepository) UpdateSessionLastSeen(_ context.Context, id string, lastSeenAt time.Time) error {
+	args := m.Called(id, lastSeenAt)
+	return args.Error(0)
+}
+
+// ExtendSession method mock
+func (m *SessionRepository) ExtendSession(_ context.Context, id string, lastSeenAt, expiresAt time.Time) error {
+	args := m.Called(id, lastSeenAt, expiresAt)
+	return args.Error(0)
+}
+
+// DeleteSession method mock
+func (m *SessionRepository) DeleteSession(_ context.Context, id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+// DeleteSessionsByEmail method mock
+func (m *SessionRepository) DeleteSessionsByEmail(_ context.Context, email string) error {
+	args := m.Called(email)
+	return args.Error(0)
+}
@@ -0,0 +1,36 @@
+// Source: internal/app/user/session.go
+package mock
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// SessionRevoker is a mock of SessionRevoker interface
+type SessionRevoker struct {
+	mock.Mock
+}
+
+// NewSessionRevoker creates new session revoker mock
+func NewSessionRevoker() *SessionRevoker {
+	return &SessionRevoker{}
+}
+
+// AddCall adds new call to the mock
+func (m *SessionRevoker) AddCall(t *testing.T, calls []Call) *SessionRevoker {
+	t.Helper()
+
+	for _, call := range calls {
+		call.on(&m.Mock)
+	}
+
+	return m
+}
+
+// DeleteSessions method mock
+func (m *SessionRevoker) DeleteSessions(_ context.Context, email string) error {
+	args := m.Called(email)
+	return args.Error(0)
+}
@@ -4,6 +4,7 @@ package mock
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 
@@ -25,7 +26,7 @@ func (m *UserRepository) AddCall(t *testing.T, calls []Call) *UserRepository {
 	t.Helper()
 
 	for _, call := range calls {
-		m.On(call.FunctionName, call.Params...).Return(call.Returns...)
+		call.on(&m.Mock)
 	}
 
 	return m
@@ -37,13 +38,140 @@ func (m *UserRepository) SaveUser(_ context.Context, user user.User) error {
 	return args.Error(0)
 }
 
+// GetUserByID method mock
+func (m *UserRepository) GetUserByID(_ context.Context, id string) (*user.User, error) {
+	args := m.Called(id)
+	return args.Get(0).(*user.User), args.Error(1)
+}
+
 // GetUserByEmail method mock
 func (m *UserRepository) GetUserByEmail(_ context.Context, email string) (*user.User, error) {
 	args := m.Called(email)
 	return args.Get(0).(*user.User), args.Error(1)
 }
 
-func (m *UserRepository) GetUserByEmailAndPassword(_ context.Context, email, password string) (*user.User, error) {
+// UpdateUser method mock
+func (m *UserRepository) UpdateUser(_ context.Context, user user.User) error {
+	args := m.Called(user)
+	return args.Error(0)
+}
+
+// UpdatePassword method mock
+func (m *UserRepository) UpdatePassword(_ context.Context, email, password string) error {
 	args := m.Called(email, password)
+	return args.Error(0)
+}
+
+// UpdatePasswordHistory method mock
+func (m *UserRepository) UpdatePasswordHistory(_ context.Context, email string, history []string) error {
+	args := m.Called(email, history)
+	return args.Error(0)
+}
+
+// UpdateTokensValidAfter method mock
+func (m *UserRepository) UpdateTokensValidAfter(_ context.Context, email string, validAfter time.Time) error {
+	args := m.Called(email, validAfter)
+	return args.Error(0)
+}
+
+// UpdateLoginAttempts method mock
+func (m *UserRepository) UpdateLoginAttempts(_ context.Context, email string, attempts user.LoginAttempts) error {
+	args := m.Called(email, attempts)
+	return args.Error(0)
+}
+
+// IncrementLoginFailures method mock
+func (m *UserRepository) IncrementLoginFailures(_ context.Context, email string, failedAt, windowStart time.Time) (*user.LoginAttempts, error) {
+	args := m.Called(email, failedAt, windowStart)
+	return args.Get(0).(*user.LoginAttempts), args.Error(1)
+}
+
+// LockLogin method mock
+func (m *UserRepository) LockLogin(_ context.Context, email string, maxAttempts int, lockedUntil time.Time) error {
+	args := m.Called(email, maxAttempts, lockedUntil)
+	return args.Error(0)
+}
+
+// UpdateStatus method mock
+func (m *UserRepository) UpdateStatus(_ context.Context, email string, status user.Status) error {
+	args := m.Called(email, status)
+	return args.Error(0)
+}
+
+// UpdateScreening method mock
+func (m *UserRepository) UpdateScreening(_ context.Context, email string, screening user.ScreeningStatus) error {
+	args := m.Called(email, screening)
+	return args.Error(0)
+}
+
+// GetUsersByScreening method mock
+func (m *UserRepository) GetUsersByScreening(_ context.Context, screening user.ScreeningStatus, limit int) ([]user.User, error) {
+	args := m.Called(screening, limit)
+	return args.Get(0).([]user.User), args.Error(1)
+}
+
+// UpdateMFA method mock
+func (m *UserRepository) UpdateMFA(_ context.Context, email string, mfa user.MFA) error {
+	args := m.Called(email, mfa)
+	return args.Error(0)
+}
+
+// UseMFAStep method mock
+func (m *UserRepository) UseMFAStep(_ context.Context, email string, step int64) (bool, error) {
+	args := m.Called(email, step)
+	return args.Bool(0), args.Error(1)
+}
+
+// UseMFARecoveryCode method mock
+func (m *UserRepository) UseMFARecoveryCode(_ context.Context, email, hash string) (bool, error) {
+	args := m.Called(email, hash)
+	return args.Bool(0), args.Error(1)
+}
+
+// UpdateRole method mock
+func (m *UserRepository) UpdateRole(_ context.Context, email string, role user.Role) error {
+	args := m.Called(email, role)
+	return args.Error(0)
+}
+
+// DeleteUser method mock
+func (m *UserRepository) DeleteUser(_ context.Context, email string, deletedAt time.Time) error {
+	args := m.Called(email, deletedAt)
+	return args.Error(0)
+}
+
+// GetDeletedUserByID method mock
+func (m *UserRepository) GetDeletedUserByID(_ context.Context, id string) (*user.User, error) {
+	args := m.Called(id)
 	return args.Get(0).(*user.User), args.Error(1)
 }
+
+// GetDeletedUserByEmail method mock
+func (m *UserRepository) GetDeletedUserByEmail(_ context.Context, email string) (*user.User, error) {
+	args := m.Called(email)
+	return args.Get(0).(*user.User), args.Error(1)
+}
+
+// RestoreUser method mock
+func (m *UserRepository) RestoreUser(_ context.Context, id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+// PurgeUsers method mock
+func (m *UserRepository) PurgeUsers(_ context.Context, deletedBefore time.Time) (int64, error) {
+	args := m.Called(deletedBefore)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// ListUsers method mock
+func (m *UserRepository) ListUsers(_ context.Context, filter user.Filter, sort user.Sort, after *user.ListCursor, limit int) ([]user.User, error) {
+	args := m.Called(filter, sort, after, limit)
+	return args.Get(0).([]user.User), args.Error(1)
+}
+
+// CountUsers method mock
+func (m *UserRepository) CountUsers(_ context.Context, filter user.Filter) (int64, error) {
+	args := m.Called(filter)
+	return args.Get(0).(int64), args.Error(1)
+}